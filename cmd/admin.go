@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/repos"
+)
+
+const adminUsage = `usage: forum admin <command>
+
+commands:
+  backup [-dir DIR] [-keep N]  copy the database (safe while the server runs),
+                               verify the copy and delete old ones
+  verify FILE                  check a backup and print its schema version
+  restore FILE                 replace the database with a backup;
+                               stop the server first`
+
+// runAdmin executes `forum admin ...` maintenance commands
+func runAdmin(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	if cfg.DBDialect != string(repos.DialectSQLite) {
+		return fmt.Errorf("forum admin works on SQLite databases only; use pg_dump and pg_restore for %s", cfg.DBDialect)
+	}
+
+	switch args[0] {
+	case "backup":
+		fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+		dir := fs.String("dir", cfg.BackupDir, "directory for backups")
+		keep := fs.Int("keep", cfg.BackupKeep, "number of backups to keep (0 keeps all)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if _, err := os.Stat(cfg.DatabasePath); err != nil {
+			return err
+		}
+		pools, err := database.InitDB(cfg.DatabasePath, database.Options{
+			BusyTimeout:   cfg.DBBusyTimeout,
+			MaxWriteConns: 1,
+			MaxReadConns:  1,
+		})
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+
+		path, err := database.Backup(context.Background(), pools.Write, *dir, *keep)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+
+	case "verify":
+		if len(args) != 2 {
+			return errors.New("usage: forum admin verify FILE")
+		}
+		version, err := database.VerifyBackup(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: ok, schema version %d\n", args[1], version)
+		return nil
+
+	case "restore":
+		if len(args) != 2 {
+			return errors.New("usage: forum admin restore FILE")
+		}
+		previous, err := database.Restore(args[1], cfg.DatabasePath)
+		if err != nil {
+			return err
+		}
+		if previous == "" {
+			fmt.Printf("restored %s from %s\n", cfg.DatabasePath, args[1])
+		} else {
+			fmt.Printf("restored %s from %s; the previous file is %s\n", cfg.DatabasePath, args[1], previous)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], adminUsage)
+	}
+}
+
+// scheduleBackups backs the database up every cfg.BackupInterval
+func scheduleBackups(ctx context.Context, db *sql.DB, cfg *config.Config) {
+	ticker := time.NewTicker(cfg.BackupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if path, err := database.Backup(ctx, db, cfg.BackupDir, cfg.BackupKeep); err != nil {
+			log.Printf("scheduled backup: %v", err)
+		} else {
+			log.Printf("scheduled backup written to %s", path)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+func (c *cli) categories(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: forumctl categories list|create|archive|unarchive|delete")
+	}
+	switch args[0] {
+	case "list":
+		return c.listCategories(ctx, args[1:])
+	case "create":
+		return c.createCategory(ctx, args[1:])
+	case "archive":
+		return c.archiveCategory(ctx, args[1:], true)
+	case "unarchive":
+		return c.archiveCategory(ctx, args[1:], false)
+	case "delete":
+		return c.deleteCategory(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown categories command %q", args[0])
+	}
+}
+
+func (c *cli) listCategories(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("categories list", flag.ContinueOnError)
+	archived := fs.Bool("archived", false, "include archived categories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tree, err := c.store.Categories.ListCategories(ctx, *archived)
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		tree = []models.Category{}
+	}
+
+	// в таблице подкатегории идут под родителем с отступом
+	var rows [][]string
+	var walk func(cats []models.Category, depth int)
+	walk = func(cats []models.Category, depth int) {
+		for _, cat := range cats {
+			rows = append(rows, []string{
+				strconv.Itoa(cat.ID), strings.Repeat("  ", depth) + cat.Name, cat.Slug,
+				strconv.Itoa(cat.SortOrder), yesNo(cat.Archived),
+			})
+			walk(cat.Children, depth+1)
+		}
+	}
+	walk(tree, 0)
+	return c.out.table(tree, []string{"ID", "NAME", "SLUG", "ORDER", "ARCHIVED"}, rows)
+}
+
+func (c *cli) createCategory(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("categories create", flag.ContinueOnError)
+	name := fs.String("name", "", "category name")
+	slug := fs.String("slug", "", "URL slug (derived from the name when omitted)")
+	description := fs.String("description", "", "description")
+	parent := fs.Int("parent", 0, "ID of the parent category")
+	order := fs.Int("order", 0, "sort order")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cat := &models.Category{
+		Name:        strings.TrimSpace(*name),
+		Slug:        *slug,
+		Description: strings.TrimSpace(*description),
+		SortOrder:   *order,
+	}
+	if *parent > 0 {
+		cat.ParentID = parent
+	}
+	if cat.Slug == "" {
+		cat.Slug = utils.Slugify(cat.Name)
+	}
+	if ok, msg := utils.ValidateCategoryData(cat.Name, cat.Slug, cat.Description); !ok {
+		return errors.New(msg)
+	}
+
+	id, err := c.store.Categories.CreateCategory(ctx, cat)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("parent category %d not found", *parent)
+	} else if err != nil {
+		return err
+	}
+	created, err := c.store.Categories.GetCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.out.done(created, fmt.Sprintf("created category %d (%s)", created.ID, created.Slug))
+}
+
+// archiveCategory closes a category for new posts or reopens it
+func (c *cli) archiveCategory(ctx context.Context, args []string, archive bool) error {
+	id, err := categoryIDArg(args)
+	if err != nil {
+		return err
+	}
+	cat, err := c.store.Categories.GetCategory(ctx, id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category %d not found", id)
+	} else if err != nil {
+		return err
+	}
+
+	cat.Archived = archive
+	if err := c.store.Categories.UpdateCategory(ctx, cat); err != nil {
+		return err
+	}
+	state := "archived"
+	if !archive {
+		state = "unarchived"
+	}
+	return c.out.done(cat, fmt.Sprintf("category %d (%s) %s", cat.ID, cat.Slug, state))
+}
+
+// deleteCategory removes an unused category, like DELETE /api/admin/categories/{id}
+func (c *cli) deleteCategory(ctx context.Context, args []string) error {
+	id, err := categoryIDArg(args)
+	if err != nil {
+		return err
+	}
+	children, posts, err := c.store.Categories.CountCategoryUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if children > 0 || posts > 0 {
+		return fmt.Errorf("category has %d subcategories and %d posts; archive it instead", children, posts)
+	}
+	if err := c.store.Categories.DeleteCategory(ctx, id); err == sql.ErrNoRows {
+		return fmt.Errorf("category %d not found", id)
+	} else if err != nil {
+		return err
+	}
+	return c.out.done(map[string]interface{}{"id": id, "deleted": true}, fmt.Sprintf("category %d deleted", id))
+}
+
+func categoryIDArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("expected a category ID")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid category ID %q", args[0])
+	}
+	return id, nil
+}
@@ -0,0 +1,122 @@
+// Command forumctl administers the forum database from the shell: users,
+// categories, sessions, statistics and demo data, printed as a table or as
+// JSON. It opens the same database as the server (DB_DIALECT with
+// DATABASE_PATH or DATABASE_URL, or -db) and is safe to run while the server
+// is up. Run it without arguments for the command list.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/repos"
+)
+
+const usage = `usage: forumctl [-db PATH|URL] [-format table|json] <command>
+
+commands:
+  users list
+  users create -email E -username U -password P [-admin]
+  users ban USER
+  users unban USER
+  users reset-password -password P USER
+  categories list [-archived]
+  categories create -name N [-slug S] [-description D] [-parent ID]
+  categories archive ID
+  categories unarchive ID
+  categories delete ID
+  sessions purge
+  stats
+  seed [-seed N] [-users N] [-posts N] [-comments N] [-messages N] [-password P]
+
+USER is a user ID, a username or an e-mail address. seed works on SQLite only.`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "forumctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	cfg := config.Load()
+
+	fs := flag.NewFlagSet("forumctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), usage) }
+	dsn := fs.String("db", cfg.DatabaseDSN(), "database file, or connection URL with DB_DIALECT=postgres")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return errors.New(usage)
+	}
+
+	dialect, err := repos.ParseDialect(cfg.DBDialect)
+	if err != nil {
+		return err
+	}
+	if dialect == repos.DialectSQLite {
+		if _, err := os.Stat(*dsn); err != nil {
+			return err
+		}
+	}
+	pools, err := repos.Open(dialect, *dsn, database.Options{
+		BusyTimeout:   cfg.DBBusyTimeout,
+		MaxWriteConns: 2,
+		MaxReadConns:  2,
+	})
+	if err != nil {
+		return err
+	}
+	defer pools.Close()
+	// новые колонки (например, banned_at) могут появиться раньше, чем перезапустится сервер
+	store, err := repos.New(pools, dialect)
+	if err != nil {
+		return err
+	}
+	database.SetQueryTimeout(cfg.DBQueryTimeout)
+
+	c := &cli{db: pools.Write, dialect: dialect, store: store, cfg: cfg, out: &printer{w: stdout, json: *format == "json"}}
+	return c.dispatch(context.Background(), fs.Args())
+}
+
+// cli holds what every command needs
+type cli struct {
+	db      *sql.DB // only for seed, which writes SQLite directly
+	dialect repos.Dialect
+	store   *repos.Repos
+	cfg     *config.Config
+	out     *printer
+}
+
+func (c *cli) dispatch(ctx context.Context, args []string) error {
+	group, rest := args[0], args[1:]
+	switch group {
+	case "users":
+		return c.users(ctx, rest)
+	case "categories":
+		return c.categories(ctx, rest)
+	case "sessions":
+		if len(rest) != 1 || rest[0] != "purge" {
+			return errors.New("usage: forumctl sessions purge")
+		}
+		return c.purgeSessions(ctx)
+	case "stats":
+		return c.stats(ctx)
+	case "seed":
+		return c.seedData(ctx, rest)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", group, usage)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+)
+
+func TestForumctl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forum.db")
+	pools, err := database.InitDB(path, database.Options{BusyTimeout: time.Second, MaxWriteConns: 1, MaxReadConns: 1})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	pools.Close()
+
+	forumctl := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := run(append([]string{"-db", path}, args...), &out); err != nil {
+			t.Fatalf("forumctl %s: %v", strings.Join(args, " "), err)
+		}
+		return out.String()
+	}
+
+	forumctl("users", "create", "-email", "root@example.com", "-username", "root", "-password", "correct horse", "-admin")
+	forumctl("users", "ban", "root@example.com")
+
+	var users []models.AccountSummary
+	if err := json.Unmarshal([]byte(forumctl("-format", "json", "users", "list")), &users); err != nil {
+		t.Fatalf("decode users: %v", err)
+	}
+	if len(users) != 1 || !users[0].IsAdmin || !users[0].EmailVerified || users[0].BannedAt == nil {
+		t.Fatalf("users = %+v, want one verified, banned admin", users)
+	}
+
+	if out := forumctl("users", "list"); !strings.HasPrefix(out, "ID") || !strings.Contains(out, "root@example.com") {
+		t.Errorf("table output:\n%s", out)
+	}
+
+	forumctl("categories", "create", "-name", "Remote Work")
+	var stats models.ForumStats
+	if err := json.Unmarshal([]byte(forumctl("-format", "json", "stats")), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.Users != 1 || stats.BannedUsers != 1 || stats.Categories != 7 {
+		t.Errorf("stats = %+v, want 1 user, 1 banned, 7 categories", stats)
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-db", path, "users", "create", "-email", "x@example.com", "-username", "root", "-password", "correct horse"}, &out); err == nil {
+		t.Error("creating a duplicate username succeeded")
+	}
+}
+
+func TestSeedUsesConfiguredReactions(t *testing.T) {
+	t.Setenv("REACTIONS", "up=⬆️,party=🎉")
+	path := filepath.Join(t.TempDir(), "forum.db")
+	pools, err := database.InitDB(path, database.Options{BusyTimeout: time.Second, MaxWriteConns: 1, MaxReadConns: 1})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer pools.Close()
+
+	var out bytes.Buffer
+	if err := run([]string{"-db", path, "seed", "-users", "10", "-posts", "20", "-comments", "40", "-messages", "0"}, &out); err != nil {
+		t.Fatalf("forumctl seed: %v", err)
+	}
+
+	rows, err := pools.Read.Query("SELECT reaction FROM post_likes UNION SELECT reaction FROM comment_likes ORDER BY reaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if strings.Join(names, ",") != "party,up" {
+		t.Errorf("seeded reactions = %v, want only party and up", names)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printer writes command results as aligned columns or as indented JSON
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// table prints rows under header, or v itself in JSON mode
+func (p *printer) table(v interface{}, header []string, rows [][]string) error {
+	if p.json {
+		return p.encode(v)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// done reports the outcome of an action: msg as a line of text, or v in JSON mode
+func (p *printer) done(v interface{}, msg string) error {
+	if p.json {
+		return p.encode(v)
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+func (p *printer) encode(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// formatTime prints t in UTC, or "-" for a missing or zero time
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04")
+}
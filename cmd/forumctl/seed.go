@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/seed"
+)
+
+// seedData fills the database with generated demo content, see package seed
+func (c *cli) seedData(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	opts := seed.Options{Reactions: c.cfg.ReactionNames()}
+	fs.Int64Var(&opts.Seed, "seed", 1, "random seed; the same seed gives the same data")
+	fs.IntVar(&opts.Users, "users", 50, "number of users")
+	fs.IntVar(&opts.Posts, "posts", 200, "number of posts")
+	fs.IntVar(&opts.Comments, "comments", 1000, "number of comments")
+	fs.IntVar(&opts.Messages, "messages", 2000, "number of private messages")
+	fs.StringVar(&opts.Password, "password", "password123", "password of every generated user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if c.dialect != repos.DialectSQLite {
+		return fmt.Errorf("seed works on SQLite databases only, not %s", c.dialect)
+	}
+
+	res, err := seed.Generate(ctx, c.db, opts)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"users", strconv.Itoa(res.Users)},
+		{"posts", strconv.Itoa(res.Posts)},
+		{"comments", strconv.Itoa(res.Comments)},
+		{"reactions", strconv.Itoa(res.Reactions)},
+		{"messages", strconv.Itoa(res.Messages)},
+	}
+	if err := c.out.table(res, []string{"CREATED", "COUNT"}, rows); err != nil {
+		return err
+	}
+	if !c.out.json {
+		fmt.Fprintf(c.out.w, "users sign in with the password %q\n", opts.Password)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"real-time-forum/internal/middleware"
+)
+
+// purgeSessions deletes expired sessions; the server only drops them when
+// someone presents one
+func (c *cli) purgeSessions(ctx context.Context) error {
+	purged, err := middleware.CleanupExpiredSessions(ctx, c.store)
+	if err != nil {
+		return err
+	}
+	return c.out.done(map[string]int64{"purged": purged}, fmt.Sprintf("purged %d expired sessions", purged))
+}
+
+func (c *cli) stats(ctx context.Context) error {
+	s, err := c.store.Admin.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"users", strconv.Itoa(s.Users)},
+		{"admins", strconv.Itoa(s.Admins)},
+		{"banned users", strconv.Itoa(s.BannedUsers)},
+		{"posts", strconv.Itoa(s.Posts)},
+		{"comments", strconv.Itoa(s.Comments)},
+		{"messages", strconv.Itoa(s.Messages)},
+		{"categories", strconv.Itoa(s.Categories)},
+		{"tags", strconv.Itoa(s.Tags)},
+		{"active sessions", strconv.Itoa(s.ActiveSessions)},
+		{"online users", strconv.Itoa(s.OnlineUsers)},
+	}
+	return c.out.table(s, []string{"METRIC", "COUNT"}, rows)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+func (c *cli) users(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: forumctl users list|create|ban|unban|reset-password")
+	}
+	switch args[0] {
+	case "list":
+		return c.listUsers(ctx)
+	case "create":
+		return c.createUser(ctx, args[1:])
+	case "ban":
+		return c.banUser(ctx, args[1:], true)
+	case "unban":
+		return c.banUser(ctx, args[1:], false)
+	case "reset-password":
+		return c.resetPassword(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown users command %q", args[0])
+	}
+}
+
+func (c *cli) listUsers(ctx context.Context) error {
+	users, err := c.store.Admin.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if users == nil {
+		users = []models.AccountSummary{}
+	}
+
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{
+			strconv.Itoa(u.ID), u.Username, u.Email,
+			yesNo(u.IsAdmin), yesNo(u.EmailVerified),
+			formatTime(u.BannedAt), formatTime(u.DeletionRequestedAt), formatTime(&u.CreatedAt),
+		})
+	}
+	return c.out.table(users, []string{"ID", "USERNAME", "EMAIL", "ADMIN", "VERIFIED", "BANNED", "DELETION", "CREATED"}, rows)
+}
+
+// createUser registers an account the way /api/register does, but with a
+// verified e-mail: the operator vouches for the address
+func (c *cli) createUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	email := fs.String("email", "", "e-mail address")
+	username := fs.String("username", "", "username")
+	password := fs.String("password", "", "password (read from stdin when omitted)")
+	admin := fs.Bool("admin", false, "grant admin rights")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !utils.IsValidEmail(*email) {
+		return errors.New("invalid e-mail")
+	}
+	if !utils.IsValidUsername(*username) {
+		return errors.New("invalid username")
+	}
+	if ok, err := c.store.Users.EmailExists(ctx, *email); err != nil {
+		return err
+	} else if ok {
+		return errors.New("e-mail already registered")
+	}
+	if ok, err := c.store.Users.UsernameExists(ctx, *username); err != nil {
+		return err
+	} else if ok {
+		return errors.New("username already taken")
+	}
+	hash, err := newPasswordHash(*password)
+	if err != nil {
+		return err
+	}
+
+	user := &models.User{Email: *email, Username: *username, PasswordHash: hash}
+	if user.ID, err = c.store.Users.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := c.store.Users.MarkEmailVerified(ctx, user.ID); err != nil {
+		return err
+	}
+	if *admin {
+		if err := c.store.Admin.SetAdmin(ctx, user.ID, true); err != nil {
+			return err
+		}
+	}
+
+	return c.out.done(
+		map[string]interface{}{"id": user.ID, "username": user.Username, "email": user.Email, "is_admin": *admin},
+		fmt.Sprintf("created user %d (%s)", user.ID, user.Username),
+	)
+}
+
+// banUser bans or unbans an account. A ban signs the user out everywhere;
+// WebSocket connections already open stay up until they reconnect.
+func (c *cli) banUser(ctx context.Context, args []string, ban bool) error {
+	if len(args) != 1 {
+		return errors.New("usage: forumctl users ban|unban USER")
+	}
+	user, err := c.resolveUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	if ban {
+		changed, err = c.store.Admin.Ban(ctx, user.ID, time.Now())
+	} else {
+		changed, err = c.store.Admin.Unban(ctx, user.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("user %d (%s) banned", user.ID, user.Username)
+	switch {
+	case ban && !changed:
+		msg = fmt.Sprintf("user %d (%s) was already banned", user.ID, user.Username)
+	case !ban && changed:
+		msg = fmt.Sprintf("user %d (%s) unbanned", user.ID, user.Username)
+	case !ban:
+		msg = fmt.Sprintf("user %d (%s) was not banned", user.ID, user.Username)
+	}
+	return c.out.done(map[string]interface{}{"id": user.ID, "banned": ban, "changed": changed}, msg)
+}
+
+// resetPassword sets a new password, signs the user out and lifts the login lockout
+func (c *cli) resetPassword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password (read from stdin when omitted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: forumctl users reset-password -password P USER")
+	}
+	user, err := c.resolveUser(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	hash, err := newPasswordHash(*password)
+	if err != nil {
+		return err
+	}
+
+	if err := c.store.Users.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+	sessions, err := c.store.Sessions.DeleteUserSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := c.store.LoginAttempts.ClearLoginAttempts(ctx, middleware.AccountKey(user.ID)); err != nil {
+		return err
+	}
+
+	return c.out.done(
+		map[string]interface{}{"id": user.ID, "sessions_revoked": sessions},
+		fmt.Sprintf("password of user %d (%s) reset, %d sessions revoked", user.ID, user.Username, sessions),
+	)
+}
+
+// resolveUser finds a user by ID, username or e-mail
+func (c *cli) resolveUser(ctx context.Context, ref string) (*models.User, error) {
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		if id, err = c.store.Users.IDByLogin(ctx, ref); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("user %q not found", ref)
+			}
+			return nil, err
+		}
+	}
+	user, err := c.store.Users.GetByID(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user %q not found", ref)
+	}
+	return user, err
+}
+
+// newPasswordHash checks and hashes password; an empty one is read from
+// stdin so it does not end up in the shell history
+func newPasswordHash(password string) (string, error) {
+	if password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", errors.New("no password given")
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if err := utils.ValidatePasswordStrength(password); err != nil {
+		return "", err
+	}
+	return utils.HashPassword(password)
+}
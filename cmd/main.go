@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,23 +11,58 @@ import (
 	"syscall"
 	"time"
 
+	"real-time-forum/internal/config"
 	"real-time-forum/internal/database"
 	"real-time-forum/internal/handlers"
 	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/repos"
 )
 
 func main() {
-	db, err := database.InitDB()
+	cfg := config.Load()
+
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	dialect, err := repos.ParseDialect(cfg.DBDialect)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-
-	if err := database.RunMigrations(db); err != nil {
+	pools, err := repos.Open(dialect, cfg.DatabaseDSN(), database.Options{
+		BusyTimeout:   cfg.DBBusyTimeout,
+		MaxWriteConns: cfg.DBMaxWriteConns,
+		MaxReadConns:  cfg.DBMaxReadConns,
+	})
+	if err != nil {
 		log.Fatal(err)
 	}
+	defer pools.Close()
 
-	handler := handlers.NewHandler(db)
+	store, err := repos.New(pools, dialect)
+	if err != nil {
+		log.Fatal(err)
+	}
+	database.SetQueryTimeout(cfg.DBQueryTimeout)
+
+	// baseCtx is the parent of every request context: cancelling it aborts
+	// the queries still running when shutdown gives up waiting
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	handler := handlers.NewHandler(store, cfg)
+	go purgeDeletedAccounts(baseCtx, store, cfg)
+	if cfg.BackupInterval > 0 {
+		if dialect == repos.DialectSQLite {
+			go scheduleBackups(baseCtx, pools.Write, cfg)
+		} else {
+			log.Printf("BACKUP_INTERVAL ignored: scheduled backups are SQLite-only, back PostgreSQL up with pg_dump")
+		}
+	}
+	limiter := middleware.NewRateLimiter(store)
 	mux := http.NewServeMux()
 
 	// ================= STATIC FILES =================
@@ -42,34 +78,73 @@ func main() {
 	mux.HandleFunc("/ws", handler.ServeWS)
 
 	// API endpoint for loading private messages
-	mux.HandleFunc("/api/messages", middleware.RequireAuth(handler.MessagesHandler, db))
+	mux.HandleFunc("/api/messages", middleware.RequireAuth(handler.MessagesHandler, store))
 	// API endpoint for chat roster
-	mux.HandleFunc("/api/users", middleware.RequireAuth(handler.UsersHandler, db))
+	mux.HandleFunc("/api/users", middleware.RequireAuth(handler.UsersHandler, store))
+	// Public profile: /api/users/{id}/profile
+	mux.HandleFunc("/api/users/", handler.UserProfile)
 
 	// ================= API =================
 
 	// --- Auth ---
-	mux.HandleFunc("/api/register", handler.Register)
+	mux.HandleFunc("/api/register", limiter.Wrap("register", cfg.RateLimits["register"], handler.Register))
 	mux.HandleFunc("/api/login", handler.Login)
+	mux.HandleFunc("/api/login/2fa", handler.LoginSecondFactor)
 	mux.HandleFunc("/api/logout", handler.Logout)
 	mux.HandleFunc("/api/me", handler.Me)
+	mux.HandleFunc("/api/me/privacy", handler.Privacy)
+	mux.HandleFunc("/api/me/export", handler.ExportData)
+	mux.HandleFunc("/api/auth/oidc", handler.OIDCStatus)
+	mux.HandleFunc("/api/auth/oidc/login", handler.OIDCLogin)
+	mux.HandleFunc("/api/auth/oidc/callback", handler.OIDCCallback)
+	mux.HandleFunc("/api/verify", handler.VerifyEmail)
+	mux.HandleFunc("/api/verify/resend", handler.ResendVerification)
+
+	// --- Two-factor auth ---
+	mux.HandleFunc("/api/2fa", handler.TwoFactorStatus)
+	mux.HandleFunc("/api/2fa/setup", handler.TwoFactorSetup)
+	mux.HandleFunc("/api/2fa/enable", handler.TwoFactorEnable)
+	mux.HandleFunc("/api/2fa/disable", handler.TwoFactorDisable)
+
+	// --- API tokens (Authorization: Bearer) for scripts and bots ---
+	mux.HandleFunc("/api/tokens", handler.APITokens)
+	mux.HandleFunc("/api/tokens/", handler.RevokeAPIToken)
+
+	// --- Blocked users ---
+	mux.HandleFunc("/api/blocks", handler.Blocks)
+	mux.HandleFunc("/api/blocks/", handler.Unblock)
+
+	// --- Password ---
+	mux.HandleFunc("/api/password/change", handler.ChangePassword)
+	mux.HandleFunc("/api/password/forgot", limiter.Wrap("password_forgot", cfg.RateLimits["password_forgot"], handler.ForgotPassword))
+	mux.HandleFunc("/api/password/reset", handler.ResetPassword)
 
 	// --- Posts ---
 	mux.HandleFunc("/api/posts", handler.GetPosts)
 	mux.HandleFunc("/api/posts/", handler.GetPost)
-	mux.HandleFunc("/api/posts/create", handler.CreatePost)
+	mux.HandleFunc("/api/posts/create", limiter.Wrap("posts_create", cfg.RateLimits["posts_create"], handler.CreatePost))
 
 	// --- Comments (GET + POST) ---
-	mux.HandleFunc("/api/comments", handler.Comments)
-
-	// --- Reactions ---
-	mux.HandleFunc("/api/posts/like", handler.LikePost)
-	mux.HandleFunc("/api/posts/dislike", handler.DislikePost)
-	mux.HandleFunc("/api/comments/like", handler.LikeComment)
-	mux.HandleFunc("/api/comments/dislike", handler.DislikeComment)
+	mux.HandleFunc("/api/comments", limiter.Wrap("comments", cfg.RateLimits["comments"], handler.Comments))
+
+	// --- Reactions (one shared quota for all toggles) ---
+	reactions := cfg.RateLimits["reactions"]
+	mux.HandleFunc("/api/posts/like", limiter.Wrap("reactions", reactions, handler.LikePost))
+	mux.HandleFunc("/api/posts/dislike", limiter.Wrap("reactions", reactions, handler.DislikePost))
+	mux.HandleFunc("/api/comments/like", limiter.Wrap("reactions", reactions, handler.LikeComment))
+	mux.HandleFunc("/api/comments/dislike", limiter.Wrap("reactions", reactions, handler.DislikeComment))
+	mux.HandleFunc("/api/posts/react", limiter.Wrap("reactions", reactions, handler.ReactPost))
+	mux.HandleFunc("/api/comments/react", limiter.Wrap("reactions", reactions, handler.ReactComment))
+	mux.HandleFunc("/api/reactions", handler.Reactions)
 
 	// --- Categories ---
 	mux.HandleFunc("/api/categories", handler.GetCategories)
+	mux.HandleFunc("/api/tags", handler.Tags)
+
+	// --- Admin ---
+	mux.HandleFunc("/api/admin/unlock", middleware.RequireAdmin(handler.AdminUnlock, store))
+	mux.HandleFunc("/api/admin/categories", middleware.RequireAdmin(handler.AdminCategories, store))
+	mux.HandleFunc("/api/admin/categories/", middleware.RequireAdmin(handler.AdminCategory, store))
 
 	// ================= SPA ENTRY =================
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -86,7 +161,10 @@ func main() {
 	// ================= SERVER =================
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: middleware.CSRFProtect(mux),
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	go func() {
@@ -104,6 +182,29 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	server.Shutdown(ctx)
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	cancelBase()
 	log.Println("Server stopped")
 }
+
+// purgeDeletedAccounts removes accounts whose deletion grace period is over,
+// once at startup and then every cfg.AccountPurgeInterval
+func purgeDeletedAccounts(ctx context.Context, store *repos.Repos, cfg *config.Config) {
+	ticker := time.NewTicker(cfg.AccountPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := store.Accounts.PurgeDeleted(ctx, time.Now().Add(-cfg.AccountDeletionGrace)); err != nil {
+			log.Printf("purge deleted accounts: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted accounts", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
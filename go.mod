@@ -4,6 +4,7 @@ go 1.22
 
 require (
 	github.com/gorilla/websocket v1.5.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.32
 	golang.org/x/crypto v0.28.0
 )
@@ -1,19 +1,123 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reaction is one entry of the reaction set: Name is stored in the database
+// and used in the API, Emoji is what the UI shows
+type Reaction struct {
+	Name  string `json:"name"`
+	Emoji string `json:"emoji"`
+}
+
+// DefaultReactions is the reaction set used when REACTIONS is not set
+var DefaultReactions = []Reaction{
+	{"like", "👍"},
+	{"dislike", "👎"},
+	{"love", "❤️"},
+	{"laugh", "😂"},
+	{"wow", "😮"},
+	{"sad", "😢"},
+}
+
+// RateLimit is a token bucket quota: Requests tokens, fully refilled every Per
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
 
 type Config struct {
 	// Server settings
 	ServerPort string
 	ServerHost string
 
-	// Database
-	DatabasePath string
+	// Database: DBDialect is "sqlite3" (the file at DatabasePath) or
+	// "postgres" (DatabaseURL, a lib/pq connection string).
+	// Every query is cancelled after DBQueryTimeout (0 disables the limit).
+	// Writers wait up to DBBusyTimeout for the SQLite write lock. Writes and
+	// transactions share DBMaxWriteConns connections, listings use DBMaxReadConns;
+	// PostgreSQL gets one pool of both sizes together
+	DBDialect       string
+	DatabasePath    string
+	DatabaseURL     string
+	DBQueryTimeout  time.Duration
+	DBBusyTimeout   time.Duration
+	DBMaxWriteConns int
+	DBMaxReadConns  int
+
+	// Backups: `forum admin backup` and the scheduled job (every BackupInterval,
+	// 0 disables it) write into BackupDir and keep the BackupKeep newest copies
+	BackupDir      string
+	BackupKeep     int
+	BackupInterval time.Duration
 
 	// Authentication
 	SessionSecret string
 	SessionMaxAge int
 
+	// Public URL used to build links in e-mails
+	BaseURL string
+
+	// Outgoing mail: "log" prints messages, "file" writes .eml files to MailDir
+	MailerType string
+	MailDir    string
+
+	PasswordResetTTL time.Duration
+
+	// E-mail verification: unverified users may log in, but cannot post or
+	// send messages while RequireVerifiedEmail is on
+	RequireVerifiedEmail bool
+	EmailVerifyTTL       time.Duration
+	VerifyResendInterval time.Duration
+
+	// Lifetime of the challenge between the password step and the 2FA code
+	TwoFactorChallengeTTL time.Duration
+
+	// Single sign-on through an OpenID Connect provider; enabled when
+	// OIDCIssuer and OIDCClientID are set. OIDCProviderName is stored with
+	// linked identities, OIDCRedirectURL defaults to BaseURL + callback path
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	OIDCProviderName string
+	OIDCStateTTL     time.Duration
+
+	// Personal API tokens: lifetime when the request does not pick one, and the cap
+	APITokenDefaultTTL time.Duration
+	APITokenMaxTTL     time.Duration
+
+	// Deleted accounts are purged this long after the request; signing in
+	// before that cancels the deletion. The purge job runs every AccountPurgeInterval
+	AccountDeletionGrace time.Duration
+	AccountPurgeInterval time.Duration
+
+	// Extra origins allowed to open WebSocket connections (same-origin is always allowed)
+	AllowedOrigins []string
+
+	// Login throttling: failures allowed per account / per IP before
+	// exponential lockout, and its bounds
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginLockoutBase   time.Duration
+	LoginLockoutMax    time.Duration
+	LoginFailureWindow time.Duration
+
+	// Per-route quotas (keyed by route name, counted per user or per IP for guests)
+	// and the per-connection quota for inbound WebSocket frames
+	RateLimits     map[string]RateLimit
+	WSMessageLimit RateLimit
+
+	// Reactions offered on posts and comments, in display order. "like" and
+	// "dislike" keep feeding the likes/dislikes counters and the liked-posts
+	// filter, so dropping them from the set hides those too
+	Reactions []Reaction
+
 	// App settings
 	SiteName     string
 	PostsPerPage int
@@ -26,18 +130,74 @@ type Config struct {
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		// Server
 		ServerPort: getEnv("SERVER_PORT", ":8080"),
 		ServerHost: getEnv("SERVER_HOST", "localhost"),
 
 		// Database
-		DatabasePath: getEnv("DATABASE_PATH", "./data/forum.db"),
+		DBDialect:       getEnv("DB_DIALECT", "sqlite3"),
+		DatabasePath:    getEnv("DATABASE_PATH", "./data/forum.db"),
+		DatabaseURL:     getEnv("DATABASE_URL", ""),
+		DBQueryTimeout:  getEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second),
+		DBBusyTimeout:   getEnvDuration("DB_BUSY_TIMEOUT", 5*time.Second),
+		DBMaxWriteConns: getEnvInt("DB_MAX_WRITE_CONNS", 4),
+		DBMaxReadConns:  getEnvInt("DB_MAX_READ_CONNS", 16),
+
+		BackupDir:      getEnv("BACKUP_DIR", "./data/backups"),
+		BackupKeep:     getEnvInt("BACKUP_KEEP", 7),
+		BackupInterval: getEnvDuration("BACKUP_INTERVAL", 0),
 
 		// Authentication
 		SessionSecret: getEnv("SESSION_SECRET", "your-secret-key-change-in-production"),
 		SessionMaxAge: 3600, // 1 hour
 
+		BaseURL: strings.TrimSuffix(getEnv("BASE_URL", "http://localhost:8080"), "/"),
+
+		MailerType: getEnv("MAILER", "log"),
+		MailDir:    getEnv("MAIL_DIR", "./data/mail"),
+
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		RequireVerifiedEmail: getEnv("REQUIRE_VERIFIED_EMAIL", "true") == "true",
+		EmailVerifyTTL:       getEnvDuration("EMAIL_VERIFY_TTL", 48*time.Hour),
+		VerifyResendInterval: getEnvDuration("VERIFY_RESEND_INTERVAL", time.Minute),
+
+		TwoFactorChallengeTTL: getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       getEnvList("OIDC_SCOPES"),
+		OIDCProviderName: getEnv("OIDC_PROVIDER_NAME", "oidc"),
+		OIDCStateTTL:     getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+
+		APITokenDefaultTTL: getEnvDuration("API_TOKEN_DEFAULT_TTL", 90*24*time.Hour),
+		APITokenMaxTTL:     getEnvDuration("API_TOKEN_MAX_TTL", 365*24*time.Hour),
+
+		AccountDeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour),
+		AccountPurgeInterval: getEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+
+		AllowedOrigins: getEnvList("ALLOWED_ORIGINS"),
+
+		LoginMaxAttempts:   getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginIPMaxAttempts: getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginLockoutBase:   getEnvDuration("LOGIN_LOCKOUT_BASE", 30*time.Second),
+		LoginLockoutMax:    getEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		LoginFailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour),
+
+		RateLimits: map[string]RateLimit{
+			"register":        getEnvRateLimit("RATE_LIMIT_REGISTER", RateLimit{5, time.Hour}),
+			"password_forgot": getEnvRateLimit("RATE_LIMIT_PASSWORD_FORGOT", RateLimit{3, 15 * time.Minute}),
+			"posts_create":    getEnvRateLimit("RATE_LIMIT_POSTS_CREATE", RateLimit{5, time.Minute}),
+			"comments":        getEnvRateLimit("RATE_LIMIT_COMMENTS", RateLimit{20, time.Minute}),
+			"reactions":       getEnvRateLimit("RATE_LIMIT_REACTIONS", RateLimit{60, time.Minute}),
+		},
+		WSMessageLimit: getEnvRateLimit("RATE_LIMIT_WS_MESSAGES", RateLimit{5, time.Second}),
+
+		Reactions: getEnvReactions("REACTIONS", DefaultReactions),
+
 		// App
 		SiteName:     getEnv("SITE_NAME", "Forum"),
 		PostsPerPage: 10,
@@ -48,6 +208,36 @@ func Load() *Config {
 		StaticPath:    "./static/",
 		UploadsPath:   "./uploads/",
 	}
+
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = cfg.BaseURL + "/api/auth/oidc/callback"
+	}
+	if len(cfg.OIDCScopes) == 0 {
+		cfg.OIDCScopes = []string{"openid", "email", "profile"}
+	}
+	return cfg
+}
+
+// DatabaseDSN is what the database of DBDialect is opened with
+func (c *Config) DatabaseDSN() string {
+	if c.DBDialect == "postgres" {
+		return c.DatabaseURL
+	}
+	return c.DatabasePath
+}
+
+// OIDCEnabled reports whether single sign-on is configured
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != "" && c.OIDCClientID != ""
+}
+
+// ReactionNames returns the names of the configured reactions
+func (c *Config) ReactionNames() []string {
+	names := make([]string, len(c.Reactions))
+	for i, r := range c.Reactions {
+		names[i] = r.Name
+	}
+	return names
 }
 
 func getEnv(key, defaultValue string) string {
@@ -56,3 +246,79 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma-separated list, skipping empty items
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration parses values like "30s" or "15m"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvRateLimit parses quotas written as "<requests>/<duration>", e.g. "5/1m"
+func getEnvRateLimit(key string, defaultValue RateLimit) RateLimit {
+	parts := strings.SplitN(os.Getenv(key), "/", 2)
+	if len(parts) != 2 {
+		return defaultValue
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return defaultValue
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || per <= 0 {
+		return defaultValue
+	}
+	return RateLimit{Requests: requests, Per: per}
+}
+
+// getEnvReactions parses a reaction set written as "name=emoji,...", e.g.
+// "like=👍,love=❤️". Names are lowercase letters and underscores; invalid or
+// repeated entries are skipped, and an empty result falls back to the default
+func getEnvReactions(key string, defaultValue []Reaction) []Reaction {
+	var out []Reaction
+	seen := map[string]bool{}
+	for _, item := range getEnvList(key) {
+		name, emoji, ok := strings.Cut(item, "=")
+		name, emoji = strings.TrimSpace(name), strings.TrimSpace(emoji)
+		if !ok || emoji == "" || !validReactionName(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, Reaction{Name: name, Emoji: emoji})
+	}
+	if len(out) == 0 {
+		return defaultValue
+	}
+	return out
+}
+
+func validReactionName(name string) bool {
+	if name == "" || len(name) > 20 {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Account deletion is two-step: RequestAccountDeletion signs the user out
+// everywhere and marks the account, PurgeDeletedAccounts removes it once the
+// grace period is over. Signing in again before that cancels the deletion.
+//
+// Removing the users row does the rest through foreign keys: posts and
+// comments stay but lose their author (ON DELETE SET NULL), everything else
+// (likes, messages, sessions, presence, tokens, identities, blocks) cascades.
+
+// RequestAccountDeletion marks the account for deletion at now and revokes its sessions and API tokens
+func RequestAccountDeletion(ctx context.Context, db *sql.DB, userID int, now time.Time) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET deletion_requested_at = ? WHERE id = ?", now.UTC(), userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	for _, q := range []string{
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM api_tokens WHERE user_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CancelAccountDeletion clears a pending deletion and reports whether there was one
+func CancelAccountDeletion(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx,
+		"UPDATE users SET deletion_requested_at = NULL WHERE id = ? AND deletion_requested_at IS NOT NULL",
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetDeletionRequestedAt returns when the user asked to delete the account, or nil
+func GetDeletionRequestedAt(ctx context.Context, db *sql.DB, userID int) (*time.Time, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var at sql.NullTime
+	if err := db.QueryRowContext(ctx, "SELECT deletion_requested_at FROM users WHERE id = ?", userID).Scan(&at); err != nil {
+		return nil, err
+	}
+	if !at.Valid {
+		return nil, nil
+	}
+	return &at.Time, nil
+}
+
+// PurgeDeletedAccounts deletes every account whose deletion was requested
+// at or before cutoff and returns how many were removed
+func PurgeDeletedAccounts(ctx context.Context, db *sql.DB, cutoff time.Time) (int, error) {
+	// the timeout covers the lookup; every DeleteUser below gets its own
+	qctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(qctx, "SELECT id, deletion_requested_at FROM users WHERE deletion_requested_at IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+
+	var due []int
+	for rows.Next() {
+		var (
+			id int
+			at time.Time
+		)
+		if err := rows.Scan(&id, &at); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !at.After(cutoff) {
+			due = append(due, id)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range due {
+		if err := DeleteUser(ctx, db, id); err != nil {
+			return purged, err
+		}
+		log.Printf("account %d deleted", id)
+		purged++
+	}
+	return purged, nil
+}
+
+var errForeignKeysOff = errors.New("foreign keys are disabled on this connection")
+
+// DeleteUser removes a user for good; see the note at the top of the file.
+// It refuses to run without foreign key enforcement, which would leave the
+// user's data behind.
+func DeleteUser(ctx context.Context, db *sql.DB, userID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var fkEnabled bool
+	if err := tx.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fkEnabled); err != nil {
+		return err
+	}
+	if !fkEnabled {
+		return errForeignKeysOff
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openFileDB opens a database file with foreign keys enforced, as InitDB does
+func openFileDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "forum.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSchemaUpgradeAddsDeleteActions(t *testing.T) {
+	db := openFileDB(t)
+
+	// the tables as they were created before schema version 1
+	legacy := []string{
+		createUsersTable,
+		`CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, title TEXT NOT NULL, content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (user_id) REFERENCES users (id))`,
+		`CREATE TABLE comments (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id INTEGER NOT NULL, user_id INTEGER NOT NULL, content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (post_id) REFERENCES posts (id), FOREIGN KEY (user_id) REFERENCES users (id))`,
+		`CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, from_user INTEGER NOT NULL, to_user INTEGER NOT NULL, content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (from_user) REFERENCES users (id), FOREIGN KEY (to_user) REFERENCES users (id))`,
+		"INSERT INTO users (id, email, username, password_hash) VALUES (1, 'a@example.com', 'alice', 'x'), (2, 'b@example.com', 'bob', 'x')",
+		"INSERT INTO posts (id, user_id, title, content) VALUES (10, 1, 'hello', 'world')",
+		"INSERT INTO comments (post_id, user_id, content) VALUES (10, 2, 'hi')",
+		"INSERT INTO messages (from_user, to_user, content) VALUES (1, 2, 'psst')",
+	}
+	for _, q := range legacy {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+	}
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	if v, _ := SchemaVersion(db); v != len(schemaUpgrades) {
+		t.Fatalf("schema version = %d, want %d", v, len(schemaUpgrades))
+	}
+
+	var title string
+	if err := db.QueryRow("SELECT title FROM posts WHERE id = 10").Scan(&title); err != nil || title != "hello" {
+		t.Fatalf("post lost in the rebuild: %q, %v", title, err)
+	}
+
+	if err := DeleteUser(context.Background(), db, 1); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	var author sql.NullInt64
+	db.QueryRow("SELECT user_id FROM posts WHERE id = 10").Scan(&author)
+	if author.Valid {
+		t.Errorf("post still belongs to user %d", author.Int64)
+	}
+	var messages int
+	db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messages)
+	if messages != 0 {
+		t.Errorf("%d messages survived their sender", messages)
+	}
+
+	// running the migrations again is a no-op
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+}
+
+func TestSchemaUpgradeNamesReactions(t *testing.T) {
+	db := openFileDB(t)
+
+	// a version 1 database: reactions are a like/dislike flag
+	for _, q := range []string{
+		createUsersTable, createPostsTable, createCommentsTable, createPostLikesTableV1, createCommentLikesTableV1,
+		"PRAGMA user_version = 1",
+		"INSERT INTO users (id, email, username, password_hash) VALUES (1, 'a@example.com', 'alice', 'x'), (2, 'b@example.com', 'bob', 'x')",
+		"INSERT INTO posts (id, user_id, title, content) VALUES (10, 1, 'hello', 'world')",
+		"INSERT INTO comments (id, post_id, user_id, content) VALUES (20, 10, 1, 'first')",
+		"INSERT INTO post_likes (post_id, user_id, is_like) VALUES (10, 1, 1), (10, 2, 0)",
+		"INSERT INTO comment_likes (comment_id, user_id, is_like) VALUES (20, 2, 0)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+	}
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	ctx := context.Background()
+	post, err := GetPostByID(ctx, db, 10, 2)
+	if err != nil || post.Likes != 1 || post.Dislikes != 1 || len(post.Reactions) != 2 || post.MyReaction != "dislike" {
+		t.Fatalf("post after upgrade = %+v, %v", post, err)
+	}
+	comment, err := GetCommentByID(ctx, db, 20)
+	if err != nil || comment.Reactions["dislike"] != 1 {
+		t.Fatalf("comment after upgrade = %+v, %v", comment, err)
+	}
+	// the rebuilt table still holds one reaction per user
+	if _, err := db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (10, 1, 'love')"); err == nil {
+		t.Error("second reaction of the same user accepted")
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	for _, q := range []string{
+		"INSERT INTO users (id, email, username, password_hash) VALUES (1, 'a@example.com', 'alice', 'x'), (2, 'b@example.com', 'bob', 'x')",
+		"INSERT INTO posts (id, user_id, title, content) VALUES (10, 1, 'hello', 'world')",
+		"INSERT INTO comments (id, post_id, user_id, content) VALUES (20, 10, 1, 'first')",
+		"INSERT INTO post_likes (post_id, user_id, reaction) VALUES (10, 1, 'like'), (10, 2, 'like')",
+		"INSERT INTO sessions (id, user_id, expires_at) VALUES ('s1', 1, '2999-01-01 00:00:00')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	requested := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := RequestAccountDeletion(context.Background(), db, 1, requested); err != nil {
+		t.Fatalf("RequestAccountDeletion: %v", err)
+	}
+	var sessions int
+	db.QueryRow("SELECT COUNT(*) FROM sessions WHERE user_id = 1").Scan(&sessions)
+	if sessions != 0 {
+		t.Fatal("sessions survived the deletion request")
+	}
+
+	// still within the grace period
+	if n, err := PurgeDeletedAccounts(context.Background(), db, requested.Add(-time.Second)); err != nil || n != 0 {
+		t.Fatalf("early purge removed %d accounts, %v", n, err)
+	}
+
+	if n, err := PurgeDeletedAccounts(context.Background(), db, requested); err != nil || n != 1 {
+		t.Fatalf("purge = %d, %v; want 1", n, err)
+	}
+
+	posts, err := GetAllPosts(context.Background(), db, 0)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("GetAllPosts = %v, %v", posts, err)
+	}
+	if posts[0].UserID != 0 || posts[0].Username != "[deleted]" || posts[0].Likes != 1 {
+		t.Errorf("anonymized post = %+v, want author [deleted] with bob's like only", posts[0])
+	}
+	comments, err := GetCommentsByPostID(context.Background(), db, 10, 0)
+	if err != nil || len(comments) != 1 || comments[0].Username != "[deleted]" {
+		t.Errorf("comments = %+v, %v", comments, err)
+	}
+}
+
+func TestCancelAccountDeletion(t *testing.T) {
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	db.Exec("INSERT INTO users (id, email, username, password_hash) VALUES (1, 'a@example.com', 'alice', 'x')")
+
+	if cancelled, _ := CancelAccountDeletion(context.Background(), db, 1); cancelled {
+		t.Fatal("cancelled a deletion that was never requested")
+	}
+	RequestAccountDeletion(context.Background(), db, 1, time.Now())
+	if cancelled, err := CancelAccountDeletion(context.Background(), db, 1); err != nil || !cancelled {
+		t.Fatalf("CancelAccountDeletion = %v, %v", cancelled, err)
+	}
+	if n, _ := PurgeDeletedAccounts(context.Background(), db, time.Now().Add(time.Hour)); n != 0 {
+		t.Fatal("purged an account whose deletion was cancelled")
+	}
+}
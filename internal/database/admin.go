@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"real-time-forum/internal/models"
+)
+
+// ListUsers returns every account with its moderation state, oldest first
+func ListUsers(ctx context.Context, db *sql.DB) ([]models.AccountSummary, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, username, email, is_admin, email_verified_at IS NOT NULL,
+		       banned_at, deletion_requested_at, created_at
+		FROM users
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.AccountSummary
+	for rows.Next() {
+		var (
+			u                models.AccountSummary
+			banned, deletion sql.NullTime
+			createdAt        sql.NullTime
+		)
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.IsAdmin, &u.EmailVerified, &banned, &deletion, &createdAt); err != nil {
+			return nil, err
+		}
+		if banned.Valid {
+			u.BannedAt = &banned.Time
+		}
+		if deletion.Valid {
+			u.DeletionRequestedAt = &deletion.Time
+		}
+		u.CreatedAt = createdAt.Time
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// BanUser marks the account as banned at now and revokes its sessions and
+// API tokens; it reports false when the user was already banned
+func BanUser(ctx context.Context, db *sql.DB, userID int, now time.Time) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var banned bool
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		var at sql.NullTime
+		if err := tx.QueryRowContext(ctx, "SELECT banned_at FROM users WHERE id = ?", userID).Scan(&at); err != nil {
+			return err
+		}
+		if !at.Valid {
+			if _, err := tx.ExecContext(ctx, "UPDATE users SET banned_at = ? WHERE id = ?", now.UTC(), userID); err != nil {
+				return err
+			}
+			banned = true
+		}
+		for _, q := range []string{
+			"DELETE FROM sessions WHERE user_id = ?",
+			"DELETE FROM api_tokens WHERE user_id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return banned, err
+}
+
+// UnbanUser lifts a ban and reports whether there was one
+func UnbanUser(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "UPDATE users SET banned_at = NULL WHERE id = ? AND banned_at IS NOT NULL", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// IsUserBanned reports whether the account is banned; unknown users are not
+func IsUserBanned(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var banned bool
+	err := db.QueryRowContext(ctx, "SELECT banned_at IS NOT NULL FROM users WHERE id = ?", userID).Scan(&banned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return banned, err
+}
+
+// SetUserAdmin sets or clears the admin flag of a user
+func SetUserAdmin(ctx context.Context, db *sql.DB, userID int, admin bool) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "UPDATE users SET is_admin = ? WHERE id = ?", admin, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetForumStats counts users, content and activity
+func GetForumStats(ctx context.Context, db *sql.DB) (*models.ForumStats, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var s models.ForumStats
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE is_admin = 1),
+			(SELECT COUNT(*) FROM users WHERE banned_at IS NOT NULL),
+			(SELECT COUNT(*) FROM posts),
+			(SELECT COUNT(*) FROM comments),
+			(SELECT COUNT(*) FROM messages),
+			(SELECT COUNT(*) FROM categories),
+			(SELECT COUNT(*) FROM tags),
+			(SELECT COUNT(*) FROM sessions WHERE datetime(expires_at) > datetime('now')),
+			(SELECT COUNT(*) FROM presence WHERE status = 'online')`,
+	).Scan(&s.Users, &s.Admins, &s.BannedUsers, &s.Posts, &s.Comments, &s.Messages,
+		&s.Categories, &s.Tags, &s.ActiveSessions, &s.OnlineUsers)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
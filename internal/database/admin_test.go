@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestBanUser(t *testing.T) {
+	ctx := context.Background()
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	res, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('a@example.com', 'alice', 'x')")
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	id64, _ := res.LastInsertId()
+	alice := int(id64)
+	db.Exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('s1', ?, datetime('now', '+1 hour'))", alice)
+	db.Exec("INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at) VALUES (?, 'bot', 'h', 'read', datetime('now', '+1 day'))", alice)
+
+	if banned, err := BanUser(ctx, db, alice, time.Now()); err != nil || !banned {
+		t.Fatalf("BanUser = %v, %v; want true", banned, err)
+	}
+	if banned, err := BanUser(ctx, db, alice, time.Now()); err != nil || banned {
+		t.Fatalf("second BanUser = %v, %v; want false", banned, err)
+	}
+	if ok, _ := IsUserBanned(ctx, db, alice); !ok {
+		t.Fatal("IsUserBanned = false after the ban")
+	}
+	var sessions, tokens int
+	db.QueryRow("SELECT (SELECT COUNT(*) FROM sessions), (SELECT COUNT(*) FROM api_tokens)").Scan(&sessions, &tokens)
+	if sessions != 0 || tokens != 0 {
+		t.Errorf("after the ban: %d sessions, %d tokens; want none", sessions, tokens)
+	}
+
+	users, err := ListUsers(ctx, db)
+	if err != nil || len(users) != 1 || users[0].BannedAt == nil {
+		t.Fatalf("ListUsers = %+v, %v; want alice with BannedAt", users, err)
+	}
+	stats, err := GetForumStats(ctx, db)
+	if err != nil || stats.Users != 1 || stats.BannedUsers != 1 {
+		t.Fatalf("GetForumStats = %+v, %v", stats, err)
+	}
+
+	if ok, err := UnbanUser(ctx, db, alice); err != nil || !ok {
+		t.Fatalf("UnbanUser = %v, %v; want true", ok, err)
+	}
+	if ok, _ := IsUserBanned(ctx, db, alice); ok {
+		t.Fatal("IsUserBanned = true after the unban")
+	}
+	if _, err := BanUser(ctx, db, alice+1, time.Now()); err != sql.ErrNoRows {
+		t.Errorf("BanUser of an unknown user: err = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/models"
+)
+
+// CreateAPIToken stores a new token by hash and returns its ID
+func CreateAPIToken(ctx context.Context, db *sql.DB, userID int, name, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx,
+		"INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, name, tokenHash, strings.Join(scopes, ","), expiresAt.UTC(), time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// GetAPITokenByHash looks a token up for authentication (sql.ErrNoRows if unknown).
+// Expiry is checked by the caller.
+func GetAPITokenByHash(ctx context.Context, db *sql.DB, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := db.QueryRowContext(ctx,
+		"SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE token_hash = ?",
+		tokenHash,
+	)
+	return scanAPIToken(row)
+}
+
+// ListAPITokens returns the tokens of a user, newest first
+func ListAPITokens(ctx context.Context, db *sql.DB, userID int) ([]models.APIToken, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE user_id = ? ORDER BY id DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes a token; it reports false if the user has no such token
+func DeleteAPIToken(ctx context.Context, db *sql.DB, userID, tokenID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteAPITokensByUserID revokes all tokens of a user and returns number of rows removed
+func DeleteAPITokensByUserID(ctx context.Context, db *sql.DB, userID int) (int64, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM api_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+// TouchAPIToken records a use. To avoid a write on every request,
+// last_used_at is only moved when it is older than resolution.
+func TouchAPIToken(ctx context.Context, db *sql.DB, tokenID int, now time.Time, resolution time.Duration) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE api_tokens SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now.UTC(), tokenID, now.Add(-resolution).UTC(),
+	)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var (
+		t        models.APIToken
+		scopes   string
+		lastUsed sql.NullTime
+	)
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.ExpiresAt, &lastUsed, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	return &t, nil
+}
@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Backups are named forum-<UTC timestamp>.db: the timestamp makes lexical
+// order chronological, which rotation relies on
+const (
+	backupPrefix     = "forum-"
+	backupTimeLayout = "20060102-150405.000"
+	backupPattern    = backupPrefix + "*.db"
+)
+
+// Backup writes a consistent copy of the live database into dir with VACUUM
+// INTO, checks the copy with VerifyBackup and then deletes all but the keep
+// newest backups (keep <= 0 keeps everything). It returns the path of the copy.
+// No query timeout applies: the copy may take a while on a big forum.
+func Backup(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create backup directory: %v", err)
+	}
+
+	path := filepath.Join(dir, backupPrefix+time.Now().UTC().Format(backupTimeLayout)+".db")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("backup %s already exists", path)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("vacuum into %s: %v", path, err)
+	}
+	if _, err := VerifyBackup(path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	if err := rotateBackups(dir, keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// VerifyBackup runs PRAGMA integrity_check on a backup file without
+// modifying it, checks that it holds the forum tables and returns its
+// schema version
+func VerifyBackup(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("verify %s: %v", path, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("verify %s: integrity check failed: %s", path, result)
+	}
+	// у любого SQLite-файла user_version = 0, поэтому смотрим и на таблицы
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&tables); err != nil {
+		return 0, fmt.Errorf("verify %s: %v", path, err)
+	}
+	if tables == 0 {
+		return 0, fmt.Errorf("verify %s: %w", path, ErrNotForumDatabase)
+	}
+	return SchemaVersion(db)
+}
+
+// ErrBackupTooNew is returned by Restore for backups made by a newer
+// version of the forum: this binary cannot know their schema
+var ErrBackupTooNew = errors.New("backup schema is newer than this build")
+
+// ErrNotForumDatabase is returned by VerifyBackup for SQLite files without
+// the forum tables
+var ErrNotForumDatabase = errors.New("not a forum database")
+
+// Restore replaces the database file at dbPath with the backup at src.
+// The backup must pass VerifyBackup and its schema version must not be newer
+// than this build; older ones are upgraded by RunMigrations on the next start.
+// The current file is checkpointed and kept as dbPath + ".pre-restore-" +
+// UTC timestamp, so repeated restores never overwrite an earlier copy; its
+// path is returned, or "" when there was no database at dbPath.
+// The server must be stopped while Restore runs.
+func Restore(src, dbPath string) (string, error) {
+	version, err := VerifyBackup(src)
+	if err != nil {
+		return "", err
+	}
+	if version > len(schemaUpgrades) {
+		return "", fmt.Errorf("%w: version %d, expected at most %d", ErrBackupTooNew, version, len(schemaUpgrades))
+	}
+
+	// копируем рядом с базой, чтобы rename ниже был атомарным
+	tmp := dbPath + ".restore"
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".pre-restore-" + time.Now().UTC().Format(backupTimeLayout)
+		if _, err := os.Stat(previous); err == nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("%s already exists", previous)
+		}
+		// сливаем WAL в основной файл, иначе в копию попадут не все данные
+		if err := checkpoint(dbPath); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+		if err := os.Rename(dbPath, previous); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return previous, err
+		}
+	}
+	return previous, os.Rename(tmp, dbPath)
+}
+
+// checkpoint moves the contents of the WAL of the database at path into the
+// main file and truncates the WAL
+func checkpoint(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint %s: %v", path, err)
+	}
+	return nil
+}
+
+// rotateBackups deletes all but the keep newest backups in dir
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	files, err := ListBackups(dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > keep {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// ListBackups returns the backups in dir, oldest first
+func ListBackups(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, backupPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openLiveDB opens a migrated database at path the way the server does
+func openLiveDB(t *testing.T, path string) *Pools {
+	t.Helper()
+	pools, err := InitDB(path, Options{BusyTimeout: time.Second, MaxWriteConns: 2, MaxReadConns: 2})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := RunMigrations(pools.Write); err != nil {
+		pools.Close()
+		t.Fatalf("run migrations: %v", err)
+	}
+	return pools
+}
+
+func countUsers(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	return n
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "forum.db")
+
+	pools := openLiveDB(t, dbPath)
+	if _, err := pools.Write.Exec("INSERT INTO users (email, username, password_hash) VALUES ('a@example.com', 'alice', 'x')"); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	backup, err := Backup(ctx, pools.Write, filepath.Join(dir, "backups"), 0)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if v, err := VerifyBackup(backup); err != nil || v != len(schemaUpgrades) {
+		t.Fatalf("VerifyBackup = %d, %v; want %d", v, err, len(schemaUpgrades))
+	}
+
+	// изменения после бэкапа остаются только в WAL живой базы
+	if _, err := pools.Write.Exec("DELETE FROM users"); err != nil {
+		t.Fatalf("delete users: %v", err)
+	}
+	pools.Close()
+
+	previousPath, err := Restore(backup, dbPath)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	pools = openLiveDB(t, dbPath)
+	defer pools.Close()
+	if n := countUsers(t, pools.Read); n != 1 {
+		t.Errorf("users after restore = %d, want 1", n)
+	}
+
+	previous, err := sql.Open("sqlite3", "file:"+previousPath+"?mode=ro")
+	if err != nil {
+		t.Fatalf("open previous file: %v", err)
+	}
+	defer previous.Close()
+	if n := countUsers(t, previous); n != 0 {
+		t.Errorf("users in %s = %d, want 0", previousPath, n)
+	}
+
+	// второй restore не должен затереть первую копию
+	time.Sleep(2 * time.Millisecond) // copies are named by the millisecond
+	again, err := Restore(backup, dbPath)
+	if err != nil {
+		t.Fatalf("second Restore: %v", err)
+	}
+	if again == previousPath {
+		t.Fatalf("second Restore reused %s", previousPath)
+	}
+	if _, err := os.Stat(previousPath); err != nil {
+		t.Errorf("first pre-restore copy is gone: %v", err)
+	}
+}
+
+func TestBackupRotation(t *testing.T) {
+	dir := t.TempDir()
+	pools := openLiveDB(t, filepath.Join(dir, "forum.db"))
+	defer pools.Close()
+
+	backups := filepath.Join(dir, "backups")
+	var last string
+	for i := 0; i < 4; i++ {
+		path, err := Backup(context.Background(), pools.Write, backups, 2)
+		if err != nil {
+			t.Fatalf("Backup #%d: %v", i+1, err)
+		}
+		last = path
+		time.Sleep(2 * time.Millisecond) // backups are named by the millisecond
+	}
+
+	files, err := ListBackups(backups)
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(files) != 2 || files[1] != last {
+		t.Errorf("backups after rotation = %v, want 2 ending with %s", files, last)
+	}
+}
+
+func TestRestoreRejectsBadBackups(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "forum.db")
+	pools := openLiveDB(t, dbPath)
+	backup, err := Backup(context.Background(), pools.Write, filepath.Join(dir, "backups"), 0)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	pools.Close()
+
+	newer, err := sql.Open("sqlite3", backup)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	if _, err := newer.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(schemaUpgrades)+1)); err != nil {
+		t.Fatalf("bump user_version: %v", err)
+	}
+	newer.Close()
+	if _, err := Restore(backup, dbPath); !errors.Is(err, ErrBackupTooNew) {
+		t.Errorf("Restore of a newer schema: err = %v, want ErrBackupTooNew", err)
+	}
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("definitely not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(garbage, dbPath); err == nil {
+		t.Error("Restore of a corrupt file succeeded")
+	}
+
+	// валидный SQLite-файл, но не база форума
+	foreign := filepath.Join(dir, "foreign.db")
+	other, err := sql.Open("sqlite3", foreign)
+	if err != nil {
+		t.Fatalf("open foreign db: %v", err)
+	}
+	if _, err := other.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("create foreign table: %v", err)
+	}
+	other.Close()
+	if _, err := Restore(foreign, dbPath); !errors.Is(err, ErrNotForumDatabase) {
+		t.Errorf("Restore of a foreign database: err = %v, want ErrNotForumDatabase", err)
+	}
+
+	if files, _ := filepath.Glob(dbPath + ".pre-restore-*"); len(files) != 0 {
+		t.Errorf("rejected restores touched the live file: %v", files)
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"real-time-forum/internal/models"
+)
+
+// BlockUser makes blocker stop receiving DMs from blocked; blocking twice is a no-op
+func BlockUser(ctx context.Context, db *sql.DB, blocker, blocked int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)",
+		blocker, blocked, time.Now().UTC(),
+	)
+	return err
+}
+
+// UnblockUser removes a block; it reports false if there was none
+func UnblockUser(ctx context.Context, db *sql.DB, blocker, blocked int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM blocks WHERE blocker_id = ? AND blocked_id = ?", blocker, blocked)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListBlockedUsers returns the block list of a user, most recent first
+func ListBlockedUsers(ctx context.Context, db *sql.DB, blocker int) ([]models.BlockedUser, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT u.id, u.username, b.created_at
+		FROM blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC`,
+		blocker,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.BlockedUser{}
+	for rows.Next() {
+		var b models.BlockedUser
+		if err := rows.Scan(&b.ID, &b.Username, &b.BlockedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// GetBlockedIDs returns the set of users blocker has blocked
+func GetBlockedIDs(ctx context.Context, db *sql.DB, blocker int) (map[int]bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT blocked_id FROM blocks WHERE blocker_id = ?", blocker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// IsBlocked reports whether blocker has blocked blocked
+func IsBlocked(ctx context.Context, db *sql.DB, blocker, blocked int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = ? AND blocked_id = ?)",
+		blocker, blocked,
+	).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBlocksHideUsersFromChatRoster(t *testing.T) {
+	db := setupInMemoryDB(t)
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	ids := map[string]int{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		res, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES (?, ?, 'x')", name+"@example.com", name)
+		if err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		ids[name] = int(id)
+	}
+	alice, bob := ids["alice"], ids["bob"]
+
+	if err := BlockUser(context.Background(), db, alice, bob); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+	if err := BlockUser(context.Background(), db, alice, bob); err != nil {
+		t.Fatalf("blocking twice should be a no-op: %v", err)
+	}
+
+	roster, err := ListChatUsers(context.Background(), db, alice)
+	if err != nil {
+		t.Fatalf("ListChatUsers: %v", err)
+	}
+	if len(roster) != 1 || roster[0].Username != "carol" {
+		t.Fatalf("alice's roster = %+v, want only carol", roster)
+	}
+
+	// the block is one-sided: bob still sees alice
+	roster, err = ListChatUsers(context.Background(), db, bob)
+	if err != nil {
+		t.Fatalf("ListChatUsers: %v", err)
+	}
+	if len(roster) != 2 {
+		t.Fatalf("bob's roster has %d users, want 2", len(roster))
+	}
+
+	if blocked, _ := IsBlocked(context.Background(), db, alice, bob); !blocked {
+		t.Fatal("IsBlocked(context.Background(), alice, bob) = false")
+	}
+	if blocked, _ := IsBlocked(context.Background(), db, bob, alice); blocked {
+		t.Fatal("IsBlocked(context.Background(), bob, alice) = true")
+	}
+
+	list, err := ListBlockedUsers(context.Background(), db, alice)
+	if err != nil || len(list) != 1 || list[0].ID != bob {
+		t.Fatalf("ListBlockedUsers = %+v, %v", list, err)
+	}
+
+	if removed, err := UnblockUser(context.Background(), db, alice, bob); err != nil || !removed {
+		t.Fatalf("UnblockUser = %v, %v", removed, err)
+	}
+	if removed, _ := UnblockUser(context.Background(), db, alice, bob); removed {
+		t.Fatal("second UnblockUser reported a removal")
+	}
+	if roster, _ := ListChatUsers(context.Background(), db, alice); len(roster) != 2 {
+		t.Fatalf("after unblock alice's roster has %d users, want 2", len(roster))
+	}
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"real-time-forum/internal/models"
+)
+
+var (
+	// ErrCategoryExists is returned when the name or slug is already taken
+	ErrCategoryExists = errors.New("category name or slug already exists")
+	// ErrCategoryCycle is returned when a category would become its own ancestor
+	ErrCategoryCycle = errors.New("category cannot be nested under itself")
+)
+
+const categoryColumns = "id, name, slug, COALESCE(description, ''), parent_id, sort_order, archived, created_at"
+
+// GetAllCategories returns the category tree: top-level categories with
+// their Children, each level sorted by sort_order and name. Unless
+// includeArchived is set, archived categories are left out together with
+// everything below them.
+func GetAllCategories(ctx context.Context, db *sql.DB, includeArchived bool) ([]models.Category, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY sort_order, name COLLATE NOCASE, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		if c.Archived && !includeArchived {
+			continue
+		}
+		all = append(all, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCategoryTree(all), nil
+}
+
+// BuildCategoryTree nests categories under their parents, keeping the
+// input order on every level. Categories whose parent is missing from the
+// list (e.g. archived) are dropped.
+func BuildCategoryTree(all []models.Category) []models.Category {
+	present := make(map[int]bool, len(all))
+	children := make(map[int][]models.Category)
+	for _, c := range all {
+		present[c.ID] = true
+	}
+
+	var roots []models.Category
+	for _, c := range all {
+		switch {
+		case c.ParentID == nil:
+			roots = append(roots, c)
+		case present[*c.ParentID]:
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var attach func(list []models.Category) []models.Category
+	attach = func(list []models.Category) []models.Category {
+		for i := range list {
+			list[i].Children = attach(children[list[i].ID])
+		}
+		return list
+	}
+
+	out := attach(roots)
+	if out == nil {
+		out = []models.Category{}
+	}
+	return out
+}
+
+// CategoryAcceptsPosts reports whether the category exists and neither it nor
+// any of its ancestors is archived (GetAllCategories hides the whole subtree
+// of an archived category), walking up the parent chain from id
+func CategoryAcceptsPosts(ctx context.Context, db DBTX, id int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	seen := map[int]bool{}
+	for !seen[id] {
+		seen[id] = true
+
+		var (
+			archived bool
+			parent   sql.NullInt64
+		)
+		err := db.QueryRowContext(ctx, "SELECT archived, parent_id FROM categories WHERE id = ?", id).Scan(&archived, &parent)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if archived {
+			return false, nil
+		}
+		if !parent.Valid {
+			return true, nil
+		}
+		id = int(parent.Int64)
+	}
+	// a cycle in the tree; should not happen
+	return false, nil
+}
+
+// GetCategoryByID returns a single category without its children
+func GetCategoryByID(ctx context.Context, db *sql.DB, id int) (*models.Category, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	return scanCategory(db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = ?", id))
+}
+
+// CreateCategory inserts c and returns its ID
+func CreateCategory(ctx context.Context, db *sql.DB, c *models.Category) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	if c.ParentID != nil {
+		if _, err := GetCategoryByID(ctx, db, *c.ParentID); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := db.ExecContext(ctx,
+		"INSERT INTO categories (name, slug, description, parent_id, sort_order, archived) VALUES (?, ?, ?, ?, ?, ?)",
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.Archived,
+	)
+	if err != nil {
+		return 0, categoryError(err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdateCategory saves every field of c except CreatedAt
+func UpdateCategory(ctx context.Context, db *sql.DB, c *models.Category) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	if c.ParentID != nil {
+		cycle, err := isCategoryInSubtree(ctx, db, *c.ParentID, c.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	res, err := db.ExecContext(ctx,
+		"UPDATE categories SET name = ?, slug = ?, description = ?, parent_id = ?, sort_order = ?, archived = ? WHERE id = ?",
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.Archived, c.ID,
+	)
+	if err != nil {
+		return categoryError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteCategory removes a category; callers make sure it has no children or posts
+func DeleteCategory(ctx context.Context, db *sql.DB, id int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountCategoryUsage returns the number of direct subcategories and of posts in a category
+func CountCategoryUsage(ctx context.Context, db *sql.DB, id int) (children, posts int, err error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM categories WHERE parent_id = ?),
+			(SELECT COUNT(*) FROM post_categories WHERE category_id = ?)`,
+		id, id,
+	).Scan(&children, &posts)
+	return children, posts, err
+}
+
+// isCategoryInSubtree reports whether id is root or one of its descendants,
+// walking up the parent chain from id
+func isCategoryInSubtree(ctx context.Context, db *sql.DB, id, root int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	seen := map[int]bool{}
+	for {
+		if id == root {
+			return true, nil
+		}
+		if seen[id] {
+			// a cycle that does not involve root; should not happen
+			return true, nil
+		}
+		seen[id] = true
+
+		var parent sql.NullInt64
+		if err := db.QueryRowContext(ctx, "SELECT parent_id FROM categories WHERE id = ?", id).Scan(&parent); err != nil {
+			return false, err
+		}
+		if !parent.Valid {
+			return false, nil
+		}
+		id = int(parent.Int64)
+	}
+}
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var (
+		c      models.Category
+		parent sql.NullInt64
+	)
+	if err := row.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &parent, &c.SortOrder, &c.Archived, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if parent.Valid {
+		id := int(parent.Int64)
+		c.ParentID = &id
+	}
+	return &c, nil
+}
+
+func categoryError(err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrCategoryExists
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"real-time-forum/internal/models"
+)
+
+func TestCategoryTree(t *testing.T) {
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	seeded, err := GetAllCategories(context.Background(), db, false)
+	if err != nil {
+		t.Fatalf("GetAllCategories: %v", err)
+	}
+	if len(seeded) != 6 || seeded[0].Slug != "job-search" {
+		t.Fatalf("seeded categories = %+v", seeded)
+	}
+	// reseeding must not bring back deleted defaults
+	db.Exec("DELETE FROM categories WHERE slug = 'internships'")
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if all, _ := GetAllCategories(context.Background(), db, true); len(all) != 5 {
+		t.Fatalf("after rerun %d categories, want 5", len(all))
+	}
+
+	parent := seeded[0].ID
+	create := func(name, slug string, parentID *int, order int, archived bool) int {
+		t.Helper()
+		id, err := CreateCategory(context.Background(), db, &models.Category{Name: name, Slug: slug, ParentID: parentID, SortOrder: order, Archived: archived})
+		if err != nil {
+			t.Fatalf("CreateCategory(context.Background(), %s): %v", name, err)
+		}
+		return id
+	}
+	remote := create("Remote", "remote", &parent, 2, false)
+	create("Onsite", "onsite", &parent, 1, false)
+	create("Old", "old", &parent, 3, true)
+	nested := create("Europe", "europe", &remote, 0, false)
+
+	if _, err := CreateCategory(context.Background(), db, &models.Category{Name: "Remote 2", Slug: "remote"}); err != ErrCategoryExists {
+		t.Fatalf("duplicate slug: %v, want ErrCategoryExists", err)
+	}
+
+	tree, err := GetAllCategories(context.Background(), db, false)
+	if err != nil {
+		t.Fatalf("GetAllCategories: %v", err)
+	}
+	children := tree[0].Children
+	if len(children) != 2 || children[0].Slug != "onsite" || children[1].Slug != "remote" {
+		t.Fatalf("children of %s = %+v, want onsite, remote", tree[0].Slug, children)
+	}
+	if len(children[1].Children) != 1 || children[1].Children[0].ID != nested {
+		t.Fatalf("remote's children = %+v", children[1].Children)
+	}
+	if full, _ := GetAllCategories(context.Background(), db, true); len(full[0].Children) != 3 {
+		t.Fatalf("with archived: %d children, want 3", len(full[0].Children))
+	}
+
+	// moving a category under its own descendant is refused
+	c, _ := GetCategoryByID(context.Background(), db, parent)
+	c.ParentID = &nested
+	if err := UpdateCategory(context.Background(), db, c); err != ErrCategoryCycle {
+		t.Fatalf("cycle: %v, want ErrCategoryCycle", err)
+	}
+	c.ParentID = &c.ID
+	if err := UpdateCategory(context.Background(), db, c); err != ErrCategoryCycle {
+		t.Fatalf("self parent: %v, want ErrCategoryCycle", err)
+	}
+
+	// archiving hides the whole subtree
+	r, _ := GetCategoryByID(context.Background(), db, remote)
+	r.Archived = true
+	if err := UpdateCategory(context.Background(), db, r); err != nil {
+		t.Fatalf("UpdateCategory: %v", err)
+	}
+	tree, _ = GetAllCategories(context.Background(), db, false)
+	if len(tree[0].Children) != 1 || tree[0].Children[0].Slug != "onsite" {
+		t.Fatalf("after archiving remote: %+v", tree[0].Children)
+	}
+}
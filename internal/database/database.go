@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -13,39 +14,83 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database connection
-func InitDB() (*sql.DB, error) {
+// Options tunes the SQLite connection pools opened by InitDB
+type Options struct {
+	// BusyTimeout is how long a connection waits for the write lock
+	// before failing with "database is locked"
+	BusyTimeout   time.Duration
+	MaxWriteConns int
+	MaxReadConns  int
+}
+
+// Pools are the two connection pools of the forum database. SQLite lets
+// one writer in at a time, so Write opens its transactions with BEGIN
+// IMMEDIATE: they queue for the lock up front (for up to BusyTimeout)
+// instead of failing when a deferred transaction tries to upgrade. Read is
+// query-only; in WAL mode its readers never block the writer and vice versa
+type Pools struct {
+	Write *sql.DB
+	Read  *sql.DB
+}
+
+// Close closes both pools (once if they are the same pool)
+func (p *Pools) Close() error {
+	if p.Read == p.Write {
+		return p.Write.Close()
+	}
+	rerr := p.Read.Close()
+	if err := p.Write.Close(); err != nil {
+		return err
+	}
+	return rerr
+}
+
+// InitDB opens the SQLite database at path in WAL mode and returns its pools
+func InitDB(path string, opts Options) (*Pools, error) {
 	// Create data directory if it doesn't exist
-	dataDir := "data"
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 
-	// Open database connection
-	dbPath := filepath.Join(dataDir, "forum.db")
-	// Enable foreign key constraints via connection string
-	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	// synchronous=NORMAL безопасен в WAL: после сбоя питания можно потерять
+	// последние транзакции, но не повредить файл
+	params := fmt.Sprintf("_foreign_keys=on&_busy_timeout=%d&_synchronous=NORMAL", opts.BusyTimeout.Milliseconds())
+
+	write, err := sql.Open("sqlite3", "file:"+path+"?"+params+"&_journal_mode=WAL&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
-
-	log.Printf("Using database file: %s", dbPath)
-
-	// Test connection
-	if err := db.Ping(); err != nil {
+	write.SetMaxOpenConns(opts.MaxWriteConns)
+	// journal_mode is stored in the file, so the first write connection
+	// switches it to WAL before any reader opens
+	if err := write.Ping(); err != nil {
+		write.Close()
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	// Enable foreign key constraints for this connection
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %v", err)
+	read, err := sql.Open("sqlite3", "file:"+path+"?"+params+"&_query_only=true")
+	if err != nil {
+		write.Close()
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	read.SetMaxOpenConns(opts.MaxReadConns)
+	if err := read.Ping(); err != nil {
+		read.Close()
+		write.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	return db, nil
+	log.Printf("Using database file: %s", path)
+	return &Pools{Write: write, Read: read}, nil
 }
 
 // RunMigrations executes all database migrations
 func RunMigrations(db *sql.DB) error {
+	var existing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&existing); err != nil {
+		return fmt.Errorf("migration failed: %v", err)
+	}
+
 	migrations := []string{
 		createUsersTable,
 		createCategoriesTable,
@@ -57,6 +102,14 @@ func RunMigrations(db *sql.DB) error {
 		createSessionsTable,
 		createMessagesTable,
 		createPresenceTable,
+		createLoginAttemptsTable,
+		createUserTokensTable,
+		createRecoveryCodesTable,
+		createUserIdentitiesTable,
+		createOIDCStatesTable,
+		createAPITokensTable,
+		createBlocksTable,
+		createTagsTables,
 		insertDefaultCategories,
 		createCaseInsensitiveIndexes,
 	}
@@ -67,9 +120,115 @@ func RunMigrations(db *sql.DB) error {
 		}
 	}
 
+	// a fresh database already has the latest table definitions
+	if existing == 0 {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(schemaUpgrades))); err != nil {
+			return fmt.Errorf("migration failed: %v", err)
+		}
+	}
+	// table rebuilds run before the column additions below, so a rebuilt
+	// table never loses a column it does not declare yet
+	if err := upgradeSchema(db); err != nil {
+		return fmt.Errorf("schema upgrade failed: %v", err)
+	}
+
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS
+	// does not touch existing databases, so they are added one by one.
+	// backfill runs once, right after the column is created.
+	columns := []struct{ table, column, definition, backfill string }{
+		{"users", "is_admin", "INTEGER NOT NULL DEFAULT 0", ""},
+		// accounts created before verification existed are trusted as-is
+		{"users", "email_verified_at", "DATETIME", "UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP)"},
+		// TOTP 2FA: secret is set on enrollment, enabled_at once a code was confirmed
+		{"users", "totp_secret", "TEXT", ""},
+		{"users", "totp_enabled_at", "DATETIME", ""},
+		{"users", "totp_last_step", "INTEGER NOT NULL DEFAULT 0", ""},
+		// profile: free-text bio and an external avatar image URL
+		{"users", "bio", "TEXT NOT NULL DEFAULT ''", ""},
+		{"users", "avatar_url", "TEXT NOT NULL DEFAULT ''", ""},
+		// privacy: who sees real name / age / gender (public, members, nobody)
+		// and who may open a DM (everyone, contacts)
+		{"users", "name_visibility", "TEXT NOT NULL DEFAULT 'members'", ""},
+		{"users", "age_visibility", "TEXT NOT NULL DEFAULT 'members'", ""},
+		{"users", "gender_visibility", "TEXT NOT NULL DEFAULT 'members'", ""},
+		{"users", "dm_policy", "TEXT NOT NULL DEFAULT 'everyone'", ""},
+		// hide posts and comments of blocked users from listings
+		{"users", "hide_blocked_content", "INTEGER NOT NULL DEFAULT 1", ""},
+		// account deletion: set on request, the account is purged after the grace period
+		{"users", "deletion_requested_at", "DATETIME", ""},
+		// set by forumctl users ban: banned accounts cannot sign in
+		{"users", "banned_at", "DATETIME", ""},
+		// category management: URL slug (unique, derived from the name for existing
+		// rows), display order, optional parent and an archived flag that closes
+		// the category for new posts
+		{"categories", "slug", "TEXT NOT NULL DEFAULT ''",
+			"UPDATE categories SET slug = LOWER(REPLACE(TRIM(name), ' ', '-')); CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories (slug)"},
+		{"categories", "sort_order", "INTEGER NOT NULL DEFAULT 0", "UPDATE categories SET sort_order = id"},
+		{"categories", "parent_id", "INTEGER REFERENCES categories (id) ON DELETE SET NULL", ""},
+		{"categories", "archived", "INTEGER NOT NULL DEFAULT 0", ""},
+		// cached reaction counts, kept current by reactionCountTriggers
+		{"posts", "reaction_counts", "TEXT NOT NULL DEFAULT '{}'",
+			"UPDATE posts SET reaction_counts = " + countReactionsSQL("post_likes", "post_id", "posts.id")},
+		{"comments", "reaction_counts", "TEXT NOT NULL DEFAULT '{}'",
+			"UPDATE comments SET reaction_counts = " + countReactionsSQL("comment_likes", "comment_id", "comments.id")},
+	}
+
+	for _, c := range columns {
+		added, err := addColumnIfMissing(db, c.table, c.column, c.definition)
+		if err != nil {
+			return fmt.Errorf("migration failed: %v", err)
+		}
+		if added && c.backfill != "" {
+			if _, err := db.Exec(c.backfill); err != nil {
+				return fmt.Errorf("migration backfill failed: %v", err)
+			}
+		}
+	}
+
+	// after the columns they update; a rebuilt reaction table loses its
+	// triggers, so they are recreated on every start
+	if _, err := db.Exec(reactionCountTriggers()); err != nil {
+		return fmt.Errorf("migration failed: %v", err)
+	}
+
 	return nil
 }
 
+// addColumnIfMissing adds a column to an existing table and reports whether it was added
+func addColumnIfMissing(db *sql.DB, table, column, definition string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(name, column) {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return false, fmt.Errorf("add column %s.%s: %v", table, column, err)
+	}
+	return true, nil
+}
+
 const createUsersTable = `
 CREATE TABLE IF NOT EXISTS users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -96,11 +255,11 @@ CREATE TABLE IF NOT EXISTS categories (
 const createPostsTable = `
 CREATE TABLE IF NOT EXISTS posts (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    user_id INTEGER NOT NULL,
+    user_id INTEGER,           -- NULL once the author's account is deleted
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users (id)
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
 );
 `
 
@@ -108,11 +267,11 @@ const createCommentsTable = `
 CREATE TABLE IF NOT EXISTS comments (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     post_id INTEGER NOT NULL,
-    user_id INTEGER NOT NULL,
+    user_id INTEGER,           -- NULL once the author's account is deleted
     content TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (post_id) REFERENCES posts (id),
-    FOREIGN KEY (user_id) REFERENCES users (id)
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
 );
 `
 
@@ -121,8 +280,8 @@ CREATE TABLE IF NOT EXISTS post_categories (
     post_id INTEGER NOT NULL,
     category_id INTEGER NOT NULL,
     PRIMARY KEY (post_id, category_id),
-    FOREIGN KEY (post_id) REFERENCES posts (id),
-    FOREIGN KEY (category_id) REFERENCES categories (id)
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE
 );
 `
 
@@ -131,11 +290,11 @@ CREATE TABLE IF NOT EXISTS post_likes (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     post_id INTEGER NOT NULL,
     user_id INTEGER NOT NULL,
-    is_like BOOLEAN NOT NULL,
+    reaction TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(post_id, user_id),
-    FOREIGN KEY (post_id) REFERENCES posts (id),
-    FOREIGN KEY (user_id) REFERENCES users (id)
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
 `
 
@@ -144,11 +303,11 @@ CREATE TABLE IF NOT EXISTS comment_likes (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     comment_id INTEGER NOT NULL,
     user_id INTEGER NOT NULL,
-    is_like BOOLEAN NOT NULL,
+    reaction TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(comment_id, user_id),
-    FOREIGN KEY (comment_id) REFERENCES comments (id),
-    FOREIGN KEY (user_id) REFERENCES users (id)
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
 `
 
@@ -158,7 +317,7 @@ CREATE TABLE IF NOT EXISTS sessions (
     user_id INTEGER NOT NULL,
     expires_at DATETIME NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users (id)
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
 `
 
@@ -169,8 +328,8 @@ CREATE TABLE IF NOT EXISTS messages (
 	to_user INTEGER NOT NULL,
 	content TEXT NOT NULL,
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (from_user) REFERENCES users (id),
-	FOREIGN KEY (to_user) REFERENCES users (id)
+	FOREIGN KEY (from_user) REFERENCES users (id) ON DELETE CASCADE,
+	FOREIGN KEY (to_user) REFERENCES users (id) ON DELETE CASCADE
 );
 `
 
@@ -180,18 +339,128 @@ CREATE TABLE IF NOT EXISTS presence (
 	status TEXT NOT NULL,
 	nickname TEXT,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users (id)
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+`
+
+const createLoginAttemptsTable = `
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key TEXT PRIMARY KEY,           -- "ip:<addr>", "user:<id>" or "account:<identifier>"
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure_at DATETIME,
+	locked_until DATETIME
+);
+`
+
+// user_tokens holds hashes of single-use tokens (password reset links etc.)
+const createUserTokensTable = `
+CREATE TABLE IF NOT EXISTS user_tokens (
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	purpose TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens (user_id, purpose);
+`
+
+const createRecoveryCodesTable = `
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes (user_id);
+`
+
+const createUserIdentitiesTable = `
+CREATE TABLE IF NOT EXISTS user_identities (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_login_at DATETIME,
+	UNIQUE (provider, subject),
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id);
+`
+
+// oidc_states holds in-flight logins between the redirect to the IdP and the callback
+const createOIDCStatesTable = `
+CREATE TABLE IF NOT EXISTS oidc_states (
+	state_hash TEXT PRIMARY KEY,
+	nonce TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	link_user_id INTEGER,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (link_user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+`
+
+const createAPITokensTable = `
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 );
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens (user_id);
 `
 
+const createBlocksTable = `
+CREATE TABLE IF NOT EXISTS blocks (
+	blocker_id INTEGER NOT NULL,
+	blocked_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (blocker_id, blocked_id),
+	FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+	FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked ON blocks (blocked_id);
+`
+
+// tags are free-form labels on posts; names are stored normalized (see utils.NormalizeTag)
+const createTagsTables = `
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY (post_id, tag_id),
+	FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+	FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags (tag_id);
+`
+
+// Seeded into an empty table only: afterwards categories are managed
+// through the admin API and renamed or deleted defaults must stay that way.
 const insertDefaultCategories = `
-INSERT OR IGNORE INTO categories (name, description) VALUES
+INSERT INTO categories (name, description)
+SELECT column1, column2 FROM (VALUES
     ('Job Search', 'Discussions about searching for jobs and career advice'),
     ('Job Offers', 'Posts with job offers and recruitment'),
     ('Company Reviews', 'Reviews and feedback about companies'),
     ('Job Search Tips', 'Tips and advice for job seekers'),
     ('Hiring Tips', 'Advice for employers on hiring and recruitment'),
-    ('Internships', 'Internship opportunities and experiences');
+    ('Internships', 'Internship opportunities and experiences')
+)
+WHERE NOT EXISTS (SELECT 1 FROM categories);
 `
 
 const createCaseInsensitiveIndexes = `
@@ -202,9 +471,12 @@ CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
 // Database functions for user profile management
 
 // GetUserByUsername retrieves a user by username
-func GetUserByUsername(db *sql.DB, username string) (*models.User, error) {
+func GetUserByUsername(ctx context.Context, db *sql.DB, username string) (*models.User, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := "SELECT id, email, username, password_hash, age, gender, first_name, last_name, created_at FROM users WHERE LOWER(username) = LOWER(?)"
-	row := db.QueryRow(query, username)
+	row := db.QueryRowContext(ctx, query, username)
 
 	var user models.User
 	err := row.Scan(
@@ -226,9 +498,12 @@ func GetUserByUsername(db *sql.DB, username string) (*models.User, error) {
 }
 
 // GetUserByID retrieves a user by ID
-func GetUserByID(db *sql.DB, userID int) (*models.User, error) {
+func GetUserByID(ctx context.Context, db *sql.DB, userID int) (*models.User, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := "SELECT id, email, username, password_hash, age, gender, first_name, last_name, created_at FROM users WHERE id = ?"
-	row := db.QueryRow(query, userID)
+	row := db.QueryRowContext(ctx, query, userID)
 
 	var user models.User
 	err := row.Scan(
@@ -249,15 +524,93 @@ func GetUserByID(db *sql.DB, userID int) (*models.User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail retrieves a user by email (case-insensitive)
+func GetUserByEmail(ctx context.Context, db *sql.DB, email string) (*models.User, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT id, email, username, password_hash, age, gender, first_name, last_name, created_at FROM users WHERE LOWER(email) = LOWER(?)"
+	row := db.QueryRowContext(ctx, query, email)
+
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Age,
+		&user.Gender,
+		&user.FirstName,
+		&user.LastName,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUserByLogin retrieves a user by email or username (case-insensitive), as typed on the login form
+func GetUserByLogin(ctx context.Context, db *sql.DB, identifier string) (*models.User, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT id, email, username, password_hash, age, gender, first_name, last_name, created_at FROM users WHERE LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)"
+	row := db.QueryRowContext(ctx, query, identifier, identifier)
+
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Age,
+		&user.Gender,
+		&user.FirstName,
+		&user.LastName,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CreateUser inserts a registered user and returns its ID
+func CreateUser(ctx context.Context, db *sql.DB, u *models.User) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		u.Email, u.Username, u.PasswordHash,
+		u.Age, u.Gender, u.FirstName, u.LastName,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
 // UpdateUserPassword updates the password hash for a user
-func UpdateUserPassword(db *sql.DB, userID int, hashedPassword string) error {
-	_, err := db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", hashedPassword, userID)
+func UpdateUserPassword(ctx context.Context, db *sql.DB, userID int, hashedPassword string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", hashedPassword, userID)
 	return err
 }
 
 // DeleteSessionsByUserID deletes all sessions for a given user and returns number of rows removed
-func DeleteSessionsByUserID(db *sql.DB, userID int) (int64, error) {
-	res, err := db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+func DeleteSessionsByUserID(ctx context.Context, db *sql.DB, userID int) (int64, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
 	if err != nil {
 		return 0, err
 	}
@@ -265,47 +618,65 @@ func DeleteSessionsByUserID(db *sql.DB, userID int) (int64, error) {
 	return rows, nil
 }
 
-// GetPostsByUserID retrieves all posts by a user
-func GetPostsByUserID(db *sql.DB, userID int) ([]models.Post, error) {
+// GetPostsByUserID retrieves all posts by a user; viewerID fills MyReaction
+func GetPostsByUserID(ctx context.Context, db *sql.DB, userID, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, u.username
+		SELECT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]'), ` + myPostReaction + `
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		WHERE p.user_id = ?
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := db.Query(query, userID)
+	rows, err := db.QueryContext(ctx, query, viewerID, userID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	posts, err := ScanPosts(rows)
-	if err != nil {
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username, &post.MyReaction); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
 	for i := range posts {
 		p := &posts[i]
 		// Load categories for this post
-		categories, err := GetCategoriesForPost(db, p.ID)
+		categories, err := GetCategoriesForPost(ctx, db, p.ID)
 		if err != nil {
 			log.Printf("Failed to get categories for post %d: %v", p.ID, err)
 		} else {
 			p.Categories = categories
 		}
 
-		// Load likes and dislikes count
-		likeCount, dislikeCount, err := GetPostLikesDislikesCount(db, p.ID)
+		// Load tags for this post
+		tags, err := GetTagsForPost(ctx, db, p.ID)
 		if err != nil {
-			log.Printf("Failed to get likes/dislikes for post %d: %v", p.ID, err)
+			log.Printf("Failed to get tags for post %d: %v", p.ID, err)
 		} else {
-			p.Likes = likeCount
-			p.Dislikes = dislikeCount
+			p.Tags = tags
+		}
+
+		// Load reaction counts
+		reactions, err := GetPostReactionCounts(ctx, db, p.ID)
+		if err != nil {
+			log.Printf("Failed to get reactions for post %d: %v", p.ID, err)
+		} else {
+			p.SetReactions(reactions)
 		}
 
 		// Load comment count
-		commentCount, err := GetCommentCount(db, p.ID)
+		commentCount, err := GetCommentCount(ctx, db, p.ID)
 		if err != nil {
 			log.Printf("Failed to get comment count for post %d: %v", p.ID, err)
 		} else {
@@ -317,16 +688,19 @@ func GetPostsByUserID(db *sql.DB, userID int) ([]models.Post, error) {
 }
 
 // GetCommentsByUserID retrieves all comments by a user
-func GetCommentsByUserID(db *sql.DB, userID int) ([]models.Comment, error) {
+func GetCommentsByUserID(ctx context.Context, db *sql.DB, userID int) ([]models.Comment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, u.username
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), c.content, c.created_at, COALESCE(u.username, '[deleted]')
 		FROM comments c
-		JOIN users u ON c.user_id = u.id
+		LEFT JOIN users u ON c.user_id = u.id
 		WHERE c.user_id = ?
 		ORDER BY c.created_at DESC
 	`
 
-	rows, err := db.Query(query, userID)
+	rows, err := db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -374,16 +748,21 @@ func GetCommentsByUserID(db *sql.DB, userID int) ([]models.Comment, error) {
 // 	return comments, nil
 // }
 
-func GetCommentsByPostID(db *sql.DB, postID int) ([]models.Comment, error) {
+// GetCommentsByPostID returns the comments of a post with their reaction
+// counts; viewerID fills MyReaction
+func GetCommentsByPostID(ctx context.Context, db *sql.DB, postID, viewerID int) ([]models.Comment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT c.id, c.post_id, c.user_id, u.username, c.content, c.created_at
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), COALESCE(u.username, '[deleted]'), c.content, c.created_at, ` + myCommentReaction + `
 		FROM comments c
-		JOIN users u ON c.user_id = u.id
+		LEFT JOIN users u ON c.user_id = u.id
 		WHERE c.post_id = ?
 		ORDER BY c.created_at ASC
 	`
 
-	rows, err := db.Query(query, postID)
+	rows, err := db.QueryContext(ctx, query, viewerID, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -399,18 +778,17 @@ func GetCommentsByPostID(db *sql.DB, postID int) ([]models.Comment, error) {
 			&c.Username,
 			&c.Content,
 			&c.CreatedAt,
+			&c.MyReaction,
 		); err != nil {
 			return nil, err
 		}
 
-		// ✅ догружаем лайки
-		db.QueryRow(`
-			SELECT
-				SUM(CASE WHEN is_like = 1 THEN 1 ELSE 0 END),
-				SUM(CASE WHEN is_like = 0 THEN 1 ELSE 0 END)
-			FROM comment_likes
-			WHERE comment_id = ?
-		`, c.ID).Scan(&c.Likes, &c.Dislikes)
+		// ✅ догружаем реакции
+		reactions, err := GetCommentReactionCounts(ctx, db, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		c.SetReactions(reactions)
 
 		comments = append(comments, c)
 	}
@@ -418,83 +796,116 @@ func GetCommentsByPostID(db *sql.DB, postID int) ([]models.Comment, error) {
 	return comments, nil
 }
 
+// CreateComment inserts a comment and returns its ID
+func CreateComment(ctx context.Context, db *sql.DB, postID, userID int, content string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO comments (post_id, user_id, content, created_at)
+		VALUES (?, ?, ?, datetime('now'))`,
+		postID, userID, content,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
 // GetCommentByID returns a single comment with aggregated reaction counters.
-func GetCommentByID(db *sql.DB, commentID int) (*models.Comment, error) {
+func GetCommentByID(ctx context.Context, db *sql.DB, commentID int) (*models.Comment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var c models.Comment
-	err := db.QueryRow(`
-		SELECT c.id, c.post_id, c.user_id, u.username, c.content, c.created_at
+	err := db.QueryRowContext(ctx, `
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), COALESCE(u.username, '[deleted]'), c.content, c.created_at
 		FROM comments c
-		JOIN users u ON c.user_id = u.id
+		LEFT JOIN users u ON c.user_id = u.id
 		WHERE c.id = ?
 	`, commentID).Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	db.QueryRow(`
-		SELECT
-			COALESCE(SUM(CASE WHEN is_like = 1 THEN 1 ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN is_like = 0 THEN 1 ELSE 0 END), 0)
-		FROM comment_likes
-		WHERE comment_id = ?
-	`, commentID).Scan(&c.Likes, &c.Dislikes)
+	reactions, err := GetCommentReactionCounts(ctx, db, commentID)
+	if err != nil {
+		return nil, err
+	}
+	c.SetReactions(reactions)
 
 	return &c, nil
 }
 
 // GetUserPostCount gets the total number of posts by a user
-func GetUserPostCount(db *sql.DB, userID int) (int, error) {
+func GetUserPostCount(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := "SELECT COUNT(*) FROM posts WHERE user_id = ?"
-	err := db.QueryRow(query, userID).Scan(&count)
+	err := db.QueryRowContext(ctx, query, userID).Scan(&count)
 	return count, err
 }
 
 // GetUserCommentCount gets the total number of comments by a user
-func GetUserCommentCount(db *sql.DB, userID int) (int, error) {
+func GetUserCommentCount(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := "SELECT COUNT(*) FROM comments WHERE user_id = ?"
-	err := db.QueryRow(query, userID).Scan(&count)
+	err := db.QueryRowContext(ctx, query, userID).Scan(&count)
 	return count, err
 }
 
 // GetUserLikeCount gets the total number of likes given by a user
-func GetUserLikeCount(db *sql.DB, userID int) (int, error) {
+func GetUserLikeCount(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := `
 		SELECT COUNT(*) FROM (
-			SELECT 1 FROM post_likes WHERE user_id = ? AND is_like = 1
+			SELECT 1 FROM post_likes WHERE user_id = ? AND reaction = 'like'
 			UNION ALL
-			SELECT 1 FROM comment_likes WHERE user_id = ? AND is_like = 1
+			SELECT 1 FROM comment_likes WHERE user_id = ? AND reaction = 'like'
 		)
 	`
-	err := db.QueryRow(query, userID, userID).Scan(&count)
+	err := db.QueryRowContext(ctx, query, userID, userID).Scan(&count)
 	return count, err
 }
 
 // GetUserDislikeCount gets the total number of dislikes given by a user
-func GetUserDislikeCount(db *sql.DB, userID int) (int, error) {
+func GetUserDislikeCount(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := `
 		SELECT COUNT(*) FROM (
-			SELECT 1 FROM post_likes WHERE user_id = ? AND is_like = 0
+			SELECT 1 FROM post_likes WHERE user_id = ? AND reaction = 'dislike'
 			UNION ALL
-			SELECT 1 FROM comment_likes WHERE user_id = ? AND is_like = 0
+			SELECT 1 FROM comment_likes WHERE user_id = ? AND reaction = 'dislike'
 		)
 	`
-	err := db.QueryRow(query, userID, userID).Scan(&count)
+	err := db.QueryRowContext(ctx, query, userID, userID).Scan(&count)
 	return count, err
 }
 
 // CreatePost creates a new post in the database
-func CreatePost(db *sql.DB, userID int, title, content string) (int, error) {
+func CreatePost(ctx context.Context, db DBTX, userID int, title, content string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	log.Printf("=== DATABASE CREATE POST DEBUG ===")
 	log.Printf("UserID: %d, Title: '%s', Content length: %d", userID, title, len(content))
 
 	query := `INSERT INTO posts (user_id, title, content, created_at) VALUES (?, ?, ?, datetime('now'))`
 	log.Printf("SQL Query: %s", query)
 
-	result, err := db.Exec(query, userID, title, content)
+	result, err := db.ExecContext(ctx, query, userID, title, content)
 	if err != nil {
 		log.Printf("Database insert error: %v", err)
 		return 0, err
@@ -528,44 +939,55 @@ func CreatePost(db *sql.DB, userID int, title, content string) (int, error) {
 // 	return &post, nil
 // }
 
-func GetPostByID(db *sql.DB, postID int) (*models.Post, error) {
+// GetPostByID returns a post with its counters; viewerID fills MyReaction
+func GetPostByID(ctx context.Context, db *sql.DB, postID, viewerID int) (*models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT p.id, p.user_id, u.username, p.title, p.content, p.created_at
+		SELECT p.id, COALESCE(p.user_id, 0), COALESCE(u.username, '[deleted]'), p.title, p.content, p.created_at, ` + myPostReaction + `
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		WHERE p.id = ?
 	`
 
 	var post models.Post
-	err := db.QueryRow(query, postID).Scan(
+	err := db.QueryRowContext(ctx, query, viewerID, postID).Scan(
 		&post.ID,
 		&post.UserID,
 		&post.Username,
 		&post.Title,
 		&post.Content,
 		&post.CreatedAt,
+		&post.MyReaction,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// ✅ ДОГРУЖАЕМ ВСЁ ОСТАЛЬНОЕ
-	post.Categories, _ = GetCategoriesForPost(db, post.ID)
-	post.Likes, post.Dislikes, _ = GetPostLikesDislikesCount(db, post.ID)
-	post.CommentCount, _ = GetCommentCount(db, post.ID)
+	post.Categories, _ = GetCategoriesForPost(ctx, db, post.ID)
+	post.Tags, _ = GetTagsForPost(ctx, db, post.ID)
+	reactions, _ := GetPostReactionCounts(ctx, db, post.ID)
+	post.SetReactions(reactions)
+	post.CommentCount, _ = GetCommentCount(ctx, db, post.ID)
 
 	return &post, nil
 }
 
-func GetLikedPosts(db *sql.DB, userID int) ([]models.Post, error) {
-	rows, err := db.Query(`
-        SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at
+// GetLikedPosts returns the posts the user liked; viewerID fills MyReaction
+func GetLikedPosts(ctx context.Context, db *sql.DB, userID, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.id, p.title, p.content, COALESCE(p.user_id, 0), COALESCE(u.username, '[deleted]'), p.created_at, `+myPostReaction+`
         FROM posts p
-        JOIN users u ON p.user_id = u.id
+        LEFT JOIN users u ON p.user_id = u.id
         JOIN post_likes l ON p.id = l.post_id
-        WHERE l.user_id = ? AND l.is_like = 1
+        WHERE l.user_id = ? AND l.reaction = 'like'
         ORDER BY p.created_at DESC
-    `, userID)
+    `, viewerID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -574,29 +996,36 @@ func GetLikedPosts(db *sql.DB, userID int) ([]models.Post, error) {
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt); err != nil {
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.MyReaction); err != nil {
 			return nil, err
 		}
 
 		// Load categories for this post
-		categories, err := GetCategoriesForPost(db, post.ID)
+		categories, err := GetCategoriesForPost(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get categories for post %d: %v", post.ID, err)
 		} else {
 			post.Categories = categories
 		}
 
-		// Load likes and dislikes count
-		likeCount, dislikeCount, err := GetPostLikesDislikesCount(db, post.ID)
+		// Load tags for this post
+		tags, err := GetTagsForPost(ctx, db, post.ID)
+		if err != nil {
+			log.Printf("Failed to get tags for post %d: %v", post.ID, err)
+		} else {
+			post.Tags = tags
+		}
+
+		// Load reaction counts
+		reactions, err := GetPostReactionCounts(ctx, db, post.ID)
 		if err != nil {
-			log.Printf("Failed to get likes/dislikes for post %d: %v", post.ID, err)
+			log.Printf("Failed to get reactions for post %d: %v", post.ID, err)
 		} else {
-			post.Likes = likeCount
-			post.Dislikes = dislikeCount
+			post.SetReactions(reactions)
 		}
 
 		// Load comment count
-		commentCount, err := GetCommentCount(db, post.ID)
+		commentCount, err := GetCommentCount(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get comment count for post %d: %v", post.ID, err)
 		} else {
@@ -610,31 +1039,34 @@ func GetLikedPosts(db *sql.DB, userID int) ([]models.Post, error) {
 }
 
 // GetPostsByUserIDAndCategories retrieves posts by user ID filtered by categories
-func GetPostsByUserIDAndCategories(db *sql.DB, userID int, categoryIDs []int) ([]models.Post, error) {
+func GetPostsByUserIDAndCategories(ctx context.Context, db *sql.DB, userID int, categoryIDs []int, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	if len(categoryIDs) == 0 {
-		return GetPostsByUserID(db, userID)
+		return GetPostsByUserID(ctx, db, userID, viewerID)
 	}
 
 	// Create placeholders for the IN clause
 	placeholders := strings.Repeat("?,", len(categoryIDs)-1) + "?"
 
 	query := fmt.Sprintf(`
-		SELECT DISTINCT p.id, p.user_id, p.title, p.content, p.created_at, u.username
+		SELECT DISTINCT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]'), `+myPostReaction+`
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		JOIN post_categories pc ON p.id = pc.post_id
 		WHERE p.user_id = ? AND pc.category_id IN (%s)
 		ORDER BY p.created_at DESC
 	`, placeholders)
 
-	// Prepare arguments: userID first, then category IDs
-	args := make([]interface{}, 1+len(categoryIDs))
-	args[0] = userID
+	// Prepare arguments: viewerID and userID first, then category IDs
+	args := make([]interface{}, 2+len(categoryIDs))
+	args[0], args[1] = viewerID, userID
 	for i, catID := range categoryIDs {
-		args[i+1] = catID
+		args[i+2] = catID
 	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -643,30 +1075,37 @@ func GetPostsByUserIDAndCategories(db *sql.DB, userID int, categoryIDs []int) ([
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username)
+		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username, &post.MyReaction)
 		if err != nil {
 			return nil, err
 		}
 
 		// Load categories for this post
-		categories, err := GetCategoriesForPost(db, post.ID)
+		categories, err := GetCategoriesForPost(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get categories for post %d: %v", post.ID, err)
 		} else {
 			post.Categories = categories
 		}
 
-		// Load likes and dislikes count
-		likeCount, dislikeCount, err := GetPostLikesDislikesCount(db, post.ID)
+		// Load tags for this post
+		tags, err := GetTagsForPost(ctx, db, post.ID)
+		if err != nil {
+			log.Printf("Failed to get tags for post %d: %v", post.ID, err)
+		} else {
+			post.Tags = tags
+		}
+
+		// Load reaction counts
+		reactions, err := GetPostReactionCounts(ctx, db, post.ID)
 		if err != nil {
-			log.Printf("Failed to get likes/dislikes for post %d: %v", post.ID, err)
+			log.Printf("Failed to get reactions for post %d: %v", post.ID, err)
 		} else {
-			post.Likes = likeCount
-			post.Dislikes = dislikeCount
+			post.SetReactions(reactions)
 		}
 
 		// Load comment count
-		commentCount, err := GetCommentCount(db, post.ID)
+		commentCount, err := GetCommentCount(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get comment count for post %d: %v", post.ID, err)
 		} else {
@@ -680,32 +1119,35 @@ func GetPostsByUserIDAndCategories(db *sql.DB, userID int, categoryIDs []int) ([
 }
 
 // GetLikedPostsByCategories retrieves liked posts filtered by categories
-func GetLikedPostsByCategories(db *sql.DB, userID int, categoryIDs []int) ([]models.Post, error) {
+func GetLikedPostsByCategories(ctx context.Context, db *sql.DB, userID int, categoryIDs []int, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	if len(categoryIDs) == 0 {
-		return GetLikedPosts(db, userID)
+		return GetLikedPosts(ctx, db, userID, viewerID)
 	}
 
 	// Create placeholders for the IN clause
 	placeholders := strings.Repeat("?,", len(categoryIDs)-1) + "?"
 
 	query := fmt.Sprintf(`
-		SELECT DISTINCT p.id, p.title, p.content, p.user_id, u.username, p.created_at
+		SELECT DISTINCT p.id, p.title, p.content, COALESCE(p.user_id, 0), COALESCE(u.username, '[deleted]'), p.created_at, `+myPostReaction+`
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		JOIN post_likes l ON p.id = l.post_id
 		JOIN post_categories pc ON p.id = pc.post_id
-		WHERE l.user_id = ? AND l.is_like = 1 AND pc.category_id IN (%s)
+		WHERE l.user_id = ? AND l.reaction = 'like' AND pc.category_id IN (%s)
 		ORDER BY p.created_at DESC
 	`, placeholders)
 
-	// Prepare arguments: userID first, then category IDs
-	args := make([]interface{}, 1+len(categoryIDs))
-	args[0] = userID
+	// Prepare arguments: viewerID and userID first, then category IDs
+	args := make([]interface{}, 2+len(categoryIDs))
+	args[0], args[1] = viewerID, userID
 	for i, catID := range categoryIDs {
-		args[i+1] = catID
+		args[i+2] = catID
 	}
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -714,29 +1156,36 @@ func GetLikedPostsByCategories(db *sql.DB, userID int, categoryIDs []int) ([]mod
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt); err != nil {
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.MyReaction); err != nil {
 			return nil, err
 		}
 
 		// Load categories for this post
-		categories, err := GetCategoriesForPost(db, post.ID)
+		categories, err := GetCategoriesForPost(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get categories for post %d: %v", post.ID, err)
 		} else {
 			post.Categories = categories
 		}
 
-		// Load likes and dislikes count
-		likeCount, dislikeCount, err := GetPostLikesDislikesCount(db, post.ID)
+		// Load tags for this post
+		tags, err := GetTagsForPost(ctx, db, post.ID)
 		if err != nil {
-			log.Printf("Failed to get likes/dislikes for post %d: %v", post.ID, err)
+			log.Printf("Failed to get tags for post %d: %v", post.ID, err)
 		} else {
-			post.Likes = likeCount
-			post.Dislikes = dislikeCount
+			post.Tags = tags
+		}
+
+		// Load reaction counts
+		reactions, err := GetPostReactionCounts(ctx, db, post.ID)
+		if err != nil {
+			log.Printf("Failed to get reactions for post %d: %v", post.ID, err)
+		} else {
+			post.SetReactions(reactions)
 		}
 
 		// Load comment count
-		commentCount, err := GetCommentCount(db, post.ID)
+		commentCount, err := GetCommentCount(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get comment count for post %d: %v", post.ID, err)
 		} else {
@@ -749,15 +1198,19 @@ func GetLikedPostsByCategories(db *sql.DB, userID int, categoryIDs []int) ([]mod
 	return posts, nil
 }
 
-func GetAllPosts(db *sql.DB) ([]models.Post, error) {
+// GetAllPosts returns every post with its counters; viewerID fills MyReaction
+func GetAllPosts(ctx context.Context, db *sql.DB, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, u.username
+		SELECT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]'), ` + myPostReaction + `
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -766,30 +1219,37 @@ func GetAllPosts(db *sql.DB) ([]models.Post, error) {
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username)
+		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username, &post.MyReaction)
 		if err != nil {
 			return nil, err
 		}
 
 		// Load categories for this post
-		categories, err := GetCategoriesForPost(db, post.ID)
+		categories, err := GetCategoriesForPost(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get categories for post %d: %v", post.ID, err)
 		} else {
 			post.Categories = categories
 		}
 
-		// Load likes and dislikes count
-		likeCount, dislikeCount, err := GetPostLikesDislikesCount(db, post.ID)
+		// Load tags for this post
+		tags, err := GetTagsForPost(ctx, db, post.ID)
 		if err != nil {
-			log.Printf("Failed to get likes/dislikes for post %d: %v", post.ID, err)
+			log.Printf("Failed to get tags for post %d: %v", post.ID, err)
 		} else {
-			post.Likes = likeCount
-			post.Dislikes = dislikeCount
+			post.Tags = tags
+		}
+
+		// Load reaction counts
+		reactions, err := GetPostReactionCounts(ctx, db, post.ID)
+		if err != nil {
+			log.Printf("Failed to get reactions for post %d: %v", post.ID, err)
+		} else {
+			post.SetReactions(reactions)
 		}
 
 		// Load comment count
-		commentCount, err := GetCommentCount(db, post.ID)
+		commentCount, err := GetCommentCount(ctx, db, post.ID)
 		if err != nil {
 			log.Printf("Failed to get comment count for post %d: %v", post.ID, err)
 		} else {
@@ -803,17 +1263,20 @@ func GetAllPosts(db *sql.DB) ([]models.Post, error) {
 }
 
 // GetPostsByCategory возвращает посты, связанные с категорией через post_categories
-func GetPostsByCategory(db *sql.DB, categoryID int) ([]models.Post, error) {
+func GetPostsByCategory(ctx context.Context, db *sql.DB, categoryID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, u.username
+		SELECT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]')
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		JOIN post_categories pc ON p.id = pc.post_id
 		WHERE pc.category_id = ?
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := db.Query(query, categoryID)
+	rows, err := db.QueryContext(ctx, query, categoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -827,10 +1290,11 @@ func GetPostsByCategory(db *sql.DB, categoryID int) ([]models.Post, error) {
 		}
 
 		// Load categories for this post
-		post.Categories, _ = GetPostCategories(db, post.ID)
+		post.Categories, _ = GetPostCategories(ctx, db, post.ID)
 
 		// Load likes and dislikes count
-		post.Likes, post.Dislikes, _ = GetPostLikesCount(db, post.ID)
+		reactions, _ := GetPostReactionCounts(ctx, db, post.ID)
+		post.SetReactions(reactions)
 
 		posts = append(posts, post)
 	}
@@ -838,30 +1302,34 @@ func GetPostsByCategory(db *sql.DB, categoryID int) ([]models.Post, error) {
 	return posts, nil
 }
 
-// GetPostsByCategories возвращает посты, связанные с любой из указанных категорий
-func GetPostsByCategories(db *sql.DB, categoryIDs []int) ([]models.Post, error) {
+// GetPostsByCategories возвращает посты, связанные с любой из указанных категорий;
+// viewerID fills MyReaction
+func GetPostsByCategories(ctx context.Context, db *sql.DB, categoryIDs []int, viewerID int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	if len(categoryIDs) == 0 {
 		return []models.Post{}, nil
 	}
 
 	// Build IN clause for multiple category IDs
 	placeholders := make([]string, len(categoryIDs))
-	args := make([]interface{}, len(categoryIDs))
+	args := []interface{}{viewerID}
 	for i, id := range categoryIDs {
 		placeholders[i] = "?"
-		args[i] = id
+		args = append(args, id)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT DISTINCT p.id, p.user_id, p.title, p.content, p.created_at, u.username
+		SELECT DISTINCT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]'), `+myPostReaction+`
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
+		LEFT JOIN users u ON p.user_id = u.id
 		JOIN post_categories pc ON p.id = pc.post_id
 		WHERE pc.category_id IN (%s)
 		ORDER BY p.created_at DESC
 	`, strings.Join(placeholders, ","))
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -870,15 +1338,18 @@ func GetPostsByCategories(db *sql.DB, categoryIDs []int) ([]models.Post, error)
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username); err != nil {
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.Username, &post.MyReaction); err != nil {
 			return nil, err
 		}
 
 		// Load categories for this post
-		post.Categories, _ = GetPostCategories(db, post.ID)
+		post.Categories, _ = GetPostCategories(ctx, db, post.ID)
+		post.Tags, _ = GetTagsForPost(ctx, db, post.ID)
 
 		// Load likes and dislikes count
-		post.Likes, post.Dislikes, _ = GetPostLikesCount(db, post.ID)
+		reactions, _ := GetPostReactionCounts(ctx, db, post.ID)
+		post.SetReactions(reactions)
+		post.CommentCount, _ = GetCommentCount(ctx, db, post.ID)
 
 		posts = append(posts, post)
 	}
@@ -886,31 +1357,15 @@ func GetPostsByCategories(db *sql.DB, categoryIDs []int) ([]models.Post, error)
 	return posts, nil
 }
 
-func GetAllCategories(db *sql.DB) ([]models.Category, error) {
-	rows, err := db.Query("SELECT id, name FROM categories ORDER BY id ASC")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var categories []models.Category
-	for rows.Next() {
-		var c models.Category
-		if err := rows.Scan(&c.ID, &c.Name); err != nil {
-			return nil, err
-		}
-		categories = append(categories, c)
-	}
-
-	return categories, nil
-}
-
 // Message-related functions
 
 // InsertMessage inserts a new private message and returns the new message ID and created_at
-func InsertMessage(db *sql.DB, fromUser int, toUser int, content string) (int64, string, error) {
+func InsertMessage(ctx context.Context, db *sql.DB, fromUser int, toUser int, content string) (int64, string, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `INSERT INTO messages (from_user, to_user, content, created_at) VALUES (?, ?, ?, datetime('now'))`
-	res, err := db.Exec(query, fromUser, toUser, content)
+	res, err := db.ExecContext(ctx, query, fromUser, toUser, content)
 	if err != nil {
 		return 0, "", err
 	}
@@ -920,7 +1375,7 @@ func InsertMessage(db *sql.DB, fromUser int, toUser int, content string) (int64,
 	}
 
 	var createdAt string
-	err = db.QueryRow("SELECT created_at FROM messages WHERE id = ?", id).Scan(&createdAt)
+	err = db.QueryRowContext(ctx, "SELECT created_at FROM messages WHERE id = ?", id).Scan(&createdAt)
 	if err != nil {
 		return id, "", err
 	}
@@ -928,7 +1383,10 @@ func InsertMessage(db *sql.DB, fromUser int, toUser int, content string) (int64,
 }
 
 // GetMessagesBetween returns messages between two users ordered by created_at DESC with offset/limit
-func GetMessagesBetween(db *sql.DB, userA int, userB int, offset int, limit int) ([]models.Comment, error) {
+func GetMessagesBetween(ctx context.Context, db *sql.DB, userA int, userB int, offset int, limit int) ([]models.Comment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	// reuse Comment struct for lightweight message representation (id, user_id etc.)
 	query := `
 		SELECT id, from_user, to_user, content, created_at
@@ -937,7 +1395,7 @@ func GetMessagesBetween(db *sql.DB, userA int, userB int, offset int, limit int)
 		ORDER BY datetime(created_at) DESC
 		LIMIT ? OFFSET ?
 	`
-	rows, err := db.Query(query, userA, userB, userB, userA, limit, offset)
+	rows, err := db.QueryContext(ctx, query, userA, userB, userB, userA, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -959,7 +1417,11 @@ func GetMessagesBetween(db *sql.DB, userA int, userB int, offset int, limit int)
 }
 
 // ListChatUsers returns users with presence and last message timestamps for chat roster.
-func ListChatUsers(db *sql.DB, currentUserID int) ([]models.ChatUser, error) {
+// Users blocked by currentUserID are left out.
+func ListChatUsers(ctx context.Context, db *sql.DB, currentUserID int) ([]models.ChatUser, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT
 			u.id,
@@ -970,13 +1432,14 @@ func ListChatUsers(db *sql.DB, currentUserID int) ([]models.ChatUser, error) {
 		LEFT JOIN presence p ON p.user_id = u.id
 		LEFT JOIN messages m ON ((m.from_user = u.id AND m.to_user = ?) OR (m.from_user = ? AND m.to_user = u.id))
 		WHERE u.id != ?
+		  AND u.id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?)
 		GROUP BY u.id
 		ORDER BY
 			CASE WHEN MAX(m.created_at) IS NULL THEN 1 ELSE 0 END,
 			datetime(MAX(m.created_at)) DESC,
 			u.username COLLATE NOCASE ASC
 	`
-	rows, err := db.Query(query, currentUserID, currentUserID, currentUserID)
+	rows, err := db.QueryContext(ctx, query, currentUserID, currentUserID, currentUserID, currentUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -998,15 +1461,21 @@ func ListChatUsers(db *sql.DB, currentUserID int) ([]models.ChatUser, error) {
 }
 
 // CountMessagesBetween returns total number of messages between two users
-func CountMessagesBetween(db *sql.DB, userA int, userB int) (int, error) {
+func CountMessagesBetween(ctx context.Context, db *sql.DB, userA int, userB int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM messages WHERE (from_user = ? AND to_user = ?) OR (from_user = ? AND to_user = ?)`
 	var count int
-	err := db.QueryRow(query, userA, userB, userB, userA).Scan(&count)
+	err := db.QueryRowContext(ctx, query, userA, userB, userB, userA).Scan(&count)
 	return count, err
 }
 
 // GetPostCategories returns category names for a specific post
-func GetPostCategories(db *sql.DB, postID int) ([]string, error) {
+func GetPostCategories(ctx context.Context, db *sql.DB, postID int) ([]string, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT c.name 
 		FROM categories c
@@ -1014,7 +1483,7 @@ func GetPostCategories(db *sql.DB, postID int) ([]string, error) {
 		WHERE pc.post_id = ?
 	`
 
-	rows, err := db.Query(query, postID)
+	rows, err := db.QueryContext(ctx, query, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -1032,33 +1501,12 @@ func GetPostCategories(db *sql.DB, postID int) ([]string, error) {
 	return categories, nil
 }
 
-// GetPostLikesCount returns the count of likes and dislikes for a post
-func GetPostLikesCount(db *sql.DB, postID int) (int, int, error) {
-	var likes, dislikes int
-
-	// Count likes
-	err := db.QueryRow("SELECT COUNT(*) FROM post_likes WHERE post_id = ? AND is_like = 1", postID).Scan(&likes)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	// Count dislikes
-	err = db.QueryRow("SELECT COUNT(*) FROM post_likes WHERE post_id = ? AND is_like = 0", postID).Scan(&dislikes)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return likes, dislikes, nil
-}
-
-// GetPostLikesDislikesCount is an alias for GetPostLikesCount for consistency
-func GetPostLikesDislikesCount(db *sql.DB, postID int) (int, int, error) {
-	return GetPostLikesCount(db, postID)
-}
-
 // GetCategoriesForPost returns the category names for a specific post
-func GetCategoriesForPost(db *sql.DB, postID int) ([]string, error) {
-	rows, err := db.Query(`
+func GetCategoriesForPost(ctx context.Context, db *sql.DB, postID int) ([]string, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
 		SELECT c.name 
 		FROM categories c 
 		JOIN post_categories pc ON c.id = pc.category_id 
@@ -1082,9 +1530,12 @@ func GetCategoriesForPost(db *sql.DB, postID int) ([]string, error) {
 }
 
 // GetCommentCount returns the number of comments for a specific post
-func GetCommentCount(db *sql.DB, postID int) (int, error) {
+func GetCommentCount(ctx context.Context, db *sql.DB, postID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM comments WHERE post_id = ?", postID).Scan(&count)
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE post_id = ?", postID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -1092,14 +1543,17 @@ func GetCommentCount(db *sql.DB, postID int) (int, error) {
 }
 
 // GetUserSessions возвращает все активные сессии пользователя
-func GetUserSessions(db *sql.DB, userID int) ([]struct {
+func GetUserSessions(ctx context.Context, db *sql.DB, userID int) ([]struct {
 	ID        string
 	CreatedAt time.Time
 	ExpiresAt time.Time
 }, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := "SELECT id, created_at, expires_at FROM sessions WHERE user_id = ? AND datetime(expires_at) > datetime('now') ORDER BY created_at DESC"
 
-	rows, err := db.Query(query, userID)
+	rows, err := db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -1135,37 +1589,52 @@ func GetUserSessions(db *sql.DB, userID int) ([]struct {
 }
 
 // TerminateUserSession завершает конкретную сессию пользователя
-func TerminateUserSession(db *sql.DB, sessionID string, userID int) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
+func TerminateUserSession(ctx context.Context, db *sql.DB, sessionID string, userID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
 	return err
 }
 
 // TerminateAllOtherSessions завершает все сессии пользователя кроме текущей
-func TerminateAllOtherSessions(db *sql.DB, currentSessionID string, userID int) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, currentSessionID)
+func TerminateAllOtherSessions(ctx context.Context, db *sql.DB, currentSessionID string, userID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, currentSessionID)
 	return err
 }
 
-func EmailExists(db *sql.DB, email string) (bool, error) {
+func EmailExists(ctx context.Context, db *sql.DB, email string) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
-	err := db.QueryRow(
+	err := db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM users WHERE LOWER(email) = LOWER(?)",
 		email,
 	).Scan(&count)
 	return count > 0, err
 }
 
-func UsernameExists(db *sql.DB, username string) (bool, error) {
+func UsernameExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
-	err := db.QueryRow(
+	err := db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM users WHERE LOWER(username) = LOWER(?)",
 		username,
 	).Scan(&count)
 	return count > 0, err
 }
 
-func AddCategoryToPost(db *sql.DB, postID, categoryID int) error {
-	_, err := db.Exec(`
+func AddCategoryToPost(ctx context.Context, db DBTX, postID, categoryID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
 		INSERT OR IGNORE INTO post_categories (post_id, category_id)
 		VALUES (?, ?)
 	`, postID, categoryID)
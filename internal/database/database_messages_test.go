@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -39,14 +40,14 @@ func TestInsertAndQueryMessages(t *testing.T) {
 	idB, _ := res2.LastInsertId()
 
 	// insert messages
-	if _, _, err := InsertMessage(db, int(idA), int(idB), "hello"); err != nil {
+	if _, _, err := InsertMessage(context.Background(), db, int(idA), int(idB), "hello"); err != nil {
 		t.Fatalf("InsertMessage failed: %v", err)
 	}
-	if _, _, err := InsertMessage(db, int(idB), int(idA), "hi"); err != nil {
+	if _, _, err := InsertMessage(context.Background(), db, int(idB), int(idA), "hi"); err != nil {
 		t.Fatalf("InsertMessage failed: %v", err)
 	}
 
-	msgs, err := GetMessagesBetween(db, int(idA), int(idB), 0, 10)
+	msgs, err := GetMessagesBetween(context.Background(), db, int(idA), int(idB), 0, 10)
 	if err != nil {
 		t.Fatalf("GetMessagesBetween failed: %v", err)
 	}
@@ -54,7 +55,7 @@ func TestInsertAndQueryMessages(t *testing.T) {
 		t.Fatalf("expected 2 messages, got %d", len(msgs))
 	}
 
-	count, err := CountMessagesBetween(db, int(idA), int(idB))
+	count, err := CountMessagesBetween(context.Background(), db, int(idA), int(idB))
 	if err != nil {
 		t.Fatalf("CountMessagesBetween failed: %v", err)
 	}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"real-time-forum/internal/models"
+)
+
+// Queries used by the personal data export (GET /api/me/export)
+
+// GetUserReactions returns the reactions a user left on posts or comments;
+// table is "post_likes" or "comment_likes"
+func GetUserReactions(ctx context.Context, db *sql.DB, table string, userID int) ([]models.Reaction, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	target := "post_id"
+	if table == "comment_likes" {
+		target = "comment_id"
+	} else {
+		table = "post_likes"
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, user_id, "+target+", reaction, created_at FROM "+table+" WHERE user_id = ? ORDER BY id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Reaction{}
+	for rows.Next() {
+		var l models.Reaction
+		if err := rows.Scan(&l.ID, &l.UserID, &l.TargetID, &l.Reaction, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// GetUserMessages returns every private message a user sent or received, oldest first
+func GetUserMessages(ctx context.Context, db *sql.DB, userID int) ([]models.Message, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, from_user, to_user, content, created_at
+		FROM messages
+		WHERE from_user = ? OR to_user = ?
+		ORDER BY id`,
+		userID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Message{}
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.From, &m.To, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// GetUserIdentities returns the external accounts linked to a user
+func GetUserIdentities(ctx context.Context, db *sql.DB, userID int) ([]models.Identity, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = ?
+		ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Identity{}
+	for rows.Next() {
+		var (
+			i         models.Identity
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &lastLogin); err != nil {
+			return nil, err
+		}
+		if lastLogin.Valid {
+			t := lastLogin.Time
+			i.LastLoginAt = &t
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
+
+// GetAccountInfo returns the account fields that are not part of the public profile
+func GetAccountInfo(ctx context.Context, db *sql.DB, userID int) (emailVerifiedAt, deletionRequestedAt *time.Time, err error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var verified, deletion sql.NullTime
+	err = db.QueryRowContext(ctx,
+		"SELECT email_verified_at, deletion_requested_at FROM users WHERE id = ?", userID,
+	).Scan(&verified, &deletion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if verified.Valid {
+		emailVerifiedAt = &verified.Time
+	}
+	if deletion.Valid {
+		deletionRequestedAt = &deletion.Time
+	}
+	return emailVerifiedAt, deletionRequestedAt, nil
+}
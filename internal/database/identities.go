@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrIdentityLinked is returned when an external identity already belongs to a user
+var ErrIdentityLinked = errors.New("identity is already linked to an account")
+
+// OIDCState is an in-flight login; the state value itself is stored hashed
+type OIDCState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   int // non-zero when a logged-in user is linking an identity
+	ExpiresAt    time.Time
+}
+
+// ExternalUser is what an identity provider told us about a new user
+type ExternalUser struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	FirstName     string
+	LastName      string
+}
+
+// SaveOIDCState stores a login in flight and drops expired ones
+func SaveOIDCState(ctx context.Context, db *sql.DB, st OIDCState) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	if _, err := db.ExecContext(ctx, "DELETE FROM oidc_states WHERE expires_at < ?", now); err != nil {
+		return err
+	}
+
+	var linkUserID interface{}
+	if st.LinkUserID != 0 {
+		linkUserID = st.LinkUserID
+	}
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO oidc_states (state_hash, nonce, code_verifier, link_user_id, expires_at) VALUES (?, ?, ?, ?, ?)",
+		st.StateHash, st.Nonce, st.CodeVerifier, linkUserID, st.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// ConsumeOIDCState deletes and returns a login in flight; each state is usable once
+func ConsumeOIDCState(ctx context.Context, db *sql.DB, stateHash string) (*OIDCState, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		st         OIDCState
+		linkUserID sql.NullInt64
+	)
+	err = tx.QueryRowContext(ctx,
+		"SELECT state_hash, nonce, code_verifier, link_user_id, expires_at FROM oidc_states WHERE state_hash = ?",
+		stateHash,
+	).Scan(&st.StateHash, &st.Nonce, &st.CodeVerifier, &linkUserID, &st.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM oidc_states WHERE state_hash = ?", stateHash)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return nil, ErrInvalidToken
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if !time.Now().UTC().Before(st.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	st.LinkUserID = int(linkUserID.Int64)
+	return &st, nil
+}
+
+// GetUserIDByIdentity finds the user linked to an external subject (sql.ErrNoRows if none)
+func GetUserIDByIdentity(ctx context.Context, db *sql.DB, provider, subject string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var userID int
+	err := db.QueryRowContext(ctx,
+		"SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?",
+		provider, subject,
+	).Scan(&userID)
+	return userID, err
+}
+
+// TouchIdentity records a successful login through an identity
+func TouchIdentity(ctx context.Context, db *sql.DB, provider, subject, email string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE user_identities SET last_login_at = ?, email = ? WHERE provider = ? AND subject = ?",
+		time.Now().UTC(), email, provider, subject,
+	)
+	return err
+}
+
+// LinkIdentity attaches an external identity to an existing user
+func LinkIdentity(ctx context.Context, db *sql.DB, userID int, provider, subject, email string) error {
+	return linkIdentity(ctx, db, userID, provider, subject, email)
+}
+
+func linkIdentity(ctx context.Context, db execer, userID int, provider, subject, email string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, provider, subject, email, time.Now().UTC(), time.Now().UTC(),
+	)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrIdentityLinked
+	}
+	return err
+}
+
+// NoPassword is stored as the password hash of accounts created through an
+// identity provider; no password ever matches it
+const NoPassword = "!"
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateExternalUser provisions a user on first login through an identity provider.
+// The account gets no usable password: password_hash holds a value bcrypt never matches.
+func CreateExternalUser(ctx context.Context, db *sql.DB, u ExternalUser) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var verifiedAt interface{}
+	if u.EmailVerified {
+		verifiedAt = time.Now().UTC()
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name, email_verified_at)
+		VALUES (?, ?, ?, 0, '', ?, ?, ?)`,
+		u.Email, u.Username, NoPassword, u.FirstName, u.LastName, verifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := linkIdentity(ctx, tx, int(id), u.Provider, u.Subject, u.Email); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UniqueUsername returns base, or base with the smallest numeric suffix that is not taken.
+// base must already be a valid username; it is shortened to leave room for the suffix.
+func UniqueUsername(ctx context.Context, db *sql.DB, base string) (string, error) {
+	return UniqueUsernameWith(ctx, func(ctx context.Context, name string) (bool, error) {
+		return UsernameExists(ctx, db, name)
+	}, base)
+}
+
+// UniqueUsernameWith is UniqueUsername for any storage: exists reports
+// whether a username is taken
+func UniqueUsernameWith(ctx context.Context, exists func(ctx context.Context, username string) (bool, error), base string) (string, error) {
+	const maxLen = 20
+
+	candidate := base
+	for n := 2; n < 10000; n++ {
+		taken, err := exists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+
+		suffix := fmt.Sprint(n)
+		stem := base
+		for utf8.RuneCountInString(stem)+len(suffix) > maxLen {
+			_, size := utf8.DecodeLastRuneInString(stem)
+			stem = stem[:len(stem)-size]
+		}
+		candidate = stem + suffix
+	}
+	return "", fmt.Errorf("no free username for %q", base)
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUniqueUsername(t *testing.T) {
+	db := setupInMemoryDB(t)
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	for _, name := range []string{"alice", "alice2", "twentycharacterslong"} {
+		if _, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES (?, ?, 'x')", name+"@example.com", name); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+
+	cases := map[string]string{
+		"bob":                  "bob",
+		"Alice":                "Alice3", // UsernameExists is case-insensitive
+		"twentycharacterslong": "twentycharacterslon2",
+	}
+	for base, want := range cases {
+		got, err := UniqueUsername(context.Background(), db, base)
+		if err != nil || got != want {
+			t.Errorf("UniqueUsername(context.Background(), %q) = %q, %v; want %q", base, got, err, want)
+		}
+	}
+}
+
+func TestConsumeOIDCState(t *testing.T) {
+	db := setupInMemoryDB(t)
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	if err := SaveOIDCState(context.Background(), db, OIDCState{StateHash: "live", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("SaveOIDCState: %v", err)
+	}
+	if err := SaveOIDCState(context.Background(), db, OIDCState{StateHash: "stale", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("SaveOIDCState: %v", err)
+	}
+
+	st, err := ConsumeOIDCState(context.Background(), db, "live")
+	if err != nil || st.Nonce != "n" || st.CodeVerifier != "v" || st.LinkUserID != 0 {
+		t.Fatalf("ConsumeOIDCState(context.Background(), live) = %+v, %v", st, err)
+	}
+	if _, err := ConsumeOIDCState(context.Background(), db, "live"); err != ErrInvalidToken {
+		t.Errorf("second use: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := ConsumeOIDCState(context.Background(), db, "stale"); err != ErrInvalidToken {
+		t.Errorf("expired: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/models"
+)
+
+// GetLoginAttempt returns the failure counter stored under key, or nil when there is none
+func GetLoginAttempt(ctx context.Context, db *sql.DB, key string) (*models.LoginAttempt, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		a           models.LoginAttempt
+		lastFailure sql.NullTime
+		lockedUntil sql.NullTime
+	)
+	err := db.QueryRowContext(ctx,
+		"SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = ?",
+		key,
+	).Scan(&a.Key, &a.Failures, &lastFailure, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lastFailure.Valid {
+		a.LastFailureAt = lastFailure.Time
+	}
+	if lockedUntil.Valid {
+		a.LockedUntil = lockedUntil.Time
+	}
+	return &a, nil
+}
+
+// RecordLoginFailure atomically adds a failure to the counter under key and
+// returns the new count. A counter whose last failure is before since starts
+// over at 1, and its old lockout is dropped.
+func RecordLoginFailure(ctx context.Context, db *sql.DB, key string, now, since time.Time) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	// один upsert вместо чтения и записи: параллельные неудачи не теряются
+	var failures int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.last_failure_at < ? THEN NULL ELSE login_attempts.locked_until END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures`,
+		key, now.UTC(), since.UTC(), since.UTC(),
+	).Scan(&failures)
+	return failures, err
+}
+
+// ExtendLoginLockout locks key until the given time unless it is already
+// locked for longer
+func ExtendLoginLockout(ctx context.Context, db *sql.DB, key string, until time.Time) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE login_attempts SET locked_until = ? WHERE key = ? AND (locked_until IS NULL OR locked_until < ?)",
+		until.UTC(), key, until.UTC(),
+	)
+	return err
+}
+
+// ClearLoginAttempts removes failure counters for the given keys and returns number of rows removed
+func ClearLoginAttempts(ctx context.Context, db *sql.DB, keys ...string) (int64, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(keys)-1) + "?"
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+// GetUserIDByIdentifier resolves an email or username (case-insensitive) to a user ID
+func GetUserIDByIdentifier(ctx context.Context, db *sql.DB, identifier string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := db.QueryRowContext(ctx,
+		"SELECT id FROM users WHERE LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)",
+		identifier, identifier,
+	).Scan(&id)
+	return id, err
+}
+
+// IsUserAdmin reports whether the user has the admin flag set
+func IsUserAdmin(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var isAdmin bool
+	err := db.QueryRowContext(ctx, "SELECT is_admin FROM users WHERE id = ?", userID).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isAdmin, err
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"real-time-forum/internal/models"
+)
+
+// GetPrivacySettings loads the privacy settings of a user
+func GetPrivacySettings(ctx context.Context, db *sql.DB, userID int) (*models.PrivacySettings, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var p models.PrivacySettings
+	err := db.QueryRowContext(ctx,
+		"SELECT name_visibility, age_visibility, gender_visibility, dm_policy, hide_blocked_content FROM users WHERE id = ?",
+		userID,
+	).Scan(&p.NameVisibility, &p.AgeVisibility, &p.GenderVisibility, &p.DMPolicy, &p.HideBlockedContent)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdatePrivacySettings overwrites the privacy settings of a user
+func UpdatePrivacySettings(ctx context.Context, db *sql.DB, userID int, p *models.PrivacySettings) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE users SET name_visibility = ?, age_visibility = ?, gender_visibility = ?, dm_policy = ?, hide_blocked_content = ? WHERE id = ?",
+		p.NameVisibility, p.AgeVisibility, p.GenderVisibility, p.DMPolicy, p.HideBlockedContent, userID,
+	)
+	return err
+}
+
+// CanSendMessage reports whether from may send a private message to to under
+// to's DM policy. With the "contacts" policy only people to has written to
+// before get through.
+func CanSendMessage(ctx context.Context, db *sql.DB, from, to int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var policy string
+	if err := db.QueryRowContext(ctx, "SELECT dm_policy FROM users WHERE id = ?", to).Scan(&policy); err != nil {
+		return false, err
+	}
+	if policy != models.DMContacts {
+		return true, nil
+	}
+
+	var talked bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM messages WHERE from_user = ? AND to_user = ?)",
+		to, from,
+	).Scan(&talked)
+	return talked, err
+}
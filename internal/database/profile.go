@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"real-time-forum/internal/models"
+)
+
+// GetUserProfile loads the public profile fields of a user (sql.ErrNoRows if missing).
+// Stats and recent activity are filled separately.
+func GetUserProfile(ctx context.Context, db *sql.DB, userID int) (*models.UserProfile, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var p models.UserProfile
+	err := db.QueryRowContext(ctx, `
+		SELECT id, username, COALESCE(first_name, ''), COALESCE(last_name, ''),
+		       COALESCE(age, 0), COALESCE(gender, ''), bio, avatar_url, created_at
+		FROM users WHERE id = ?`,
+		userID,
+	).Scan(&p.ID, &p.Username, &p.FirstName, &p.LastName, &p.Age, &p.Gender, &p.Bio, &p.AvatarURL, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdateUserProfile overwrites the editable profile fields with p
+func UpdateUserProfile(ctx context.Context, db *sql.DB, p *models.UserProfile) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE users
+		SET first_name = ?, last_name = ?, age = ?, gender = ?, bio = ?, avatar_url = ?
+		WHERE id = ?`,
+		p.FirstName, p.LastName, p.Age, p.Gender, p.Bio, p.AvatarURL, p.ID,
+	)
+	return err
+}
+
+// GetUserStats collects the activity counters shown on the profile page
+func GetUserStats(ctx context.Context, db *sql.DB, userID int) (models.UserStats, error) {
+	var (
+		s   models.UserStats
+		err error
+	)
+	if s.Posts, err = GetUserPostCount(ctx, db, userID); err != nil {
+		return s, err
+	}
+	if s.Comments, err = GetUserCommentCount(ctx, db, userID); err != nil {
+		return s, err
+	}
+	if s.LikesGiven, err = GetUserLikeCount(ctx, db, userID); err != nil {
+		return s, err
+	}
+	if s.DislikesGiven, err = GetUserDislikeCount(ctx, db, userID); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// GetRecentPostsByUserID returns the latest posts of a user with their counters
+func GetRecentPostsByUserID(ctx context.Context, db *sql.DB, userID, limit int) ([]models.Post, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.id, COALESCE(p.user_id, 0), p.title, p.content, p.created_at, COALESCE(u.username, '[deleted]')
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ?
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := ScanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		p := &posts[i]
+		p.Categories, _ = GetCategoriesForPost(ctx, db, p.ID)
+		p.Tags, _ = GetTagsForPost(ctx, db, p.ID)
+		reactions, _ := GetPostReactionCounts(ctx, db, p.ID)
+		p.SetReactions(reactions)
+		p.CommentCount, _ = GetCommentCount(ctx, db, p.ID)
+	}
+	if posts == nil {
+		posts = []models.Post{}
+	}
+	return posts, nil
+}
+
+// GetRecentCommentsByUserID returns the latest comments of a user with the title of their post
+func GetRecentCommentsByUserID(ctx context.Context, db *sql.DB, userID, limit int) ([]models.Comment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.id, c.post_id, p.title, COALESCE(c.user_id, 0), COALESCE(u.username, '[deleted]'), c.content, c.created_at
+		FROM comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.user_id = ?
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.PostTitle, &c.UserID, &c.Username, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Reactions are stored one row per user and target in post_likes and
+// comment_likes; reaction holds the name from the configured set.
+
+// myPostReaction and myCommentReaction select the viewer's own reaction as a
+// column of the post (p) or comment (c) listings, empty when there is none. They
+// take the viewer's ID as the first query argument; guests pass 0.
+const (
+	myPostReaction    = "COALESCE((SELECT reaction FROM post_likes WHERE post_id = p.id AND user_id = ?), '')"
+	myCommentReaction = "COALESCE((SELECT reaction FROM comment_likes WHERE comment_id = c.id AND user_id = ?), '')"
+)
+
+// reaction_counts on posts and comments caches the number of each reaction
+// as a JSON object ({"like": 2, "love": 1}). Triggers on the reaction tables
+// recount it inside the transaction of every change, cascades from deleted
+// accounts included, so readers never see it out of step with the rows.
+
+// countReactionsSQL is the JSON object of counts for the target idExpr
+func countReactionsSQL(table, column, idExpr string) string {
+	return fmt.Sprintf(
+		"(SELECT json_group_object(reaction, n) FROM (SELECT reaction, COUNT(*) AS n FROM %s WHERE %s = %s GROUP BY reaction))",
+		table, column, idExpr,
+	)
+}
+
+// reactionCountTriggers creates the triggers keeping reaction_counts current
+func reactionCountTriggers() string {
+	var b strings.Builder
+	for _, t := range []struct{ table, column, target string }{
+		{"post_likes", "post_id", "posts"},
+		{"comment_likes", "comment_id", "comments"},
+	} {
+		for _, e := range []struct{ name, event, row string }{
+			{"insert", "INSERT", "NEW"},
+			{"update", "UPDATE OF reaction", "NEW"},
+			{"delete", "DELETE", "OLD"},
+		} {
+			id := e.row + "." + t.column
+			fmt.Fprintf(&b, "CREATE TRIGGER IF NOT EXISTS %s_count_%s AFTER %s ON %s BEGIN UPDATE %s SET reaction_counts = %s WHERE id = %s; END;\n",
+				t.table, e.name, e.event, t.table, t.target, countReactionsSQL(t.table, t.column, id), id)
+		}
+	}
+	return b.String()
+}
+
+// GetPostReactionCounts returns how many users left each reaction on a post
+func GetPostReactionCounts(ctx context.Context, db DBTX, postID int) (map[string]int, error) {
+	return reactionCounts(ctx, db, "posts", postID)
+}
+
+// GetCommentReactionCounts returns how many users left each reaction on a comment
+func GetCommentReactionCounts(ctx context.Context, db DBTX, commentID int) (map[string]int, error) {
+	return reactionCounts(ctx, db, "comments", commentID)
+}
+
+func reactionCounts(ctx context.Context, db DBTX, table string, id int) (map[string]int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var raw string
+	if err := db.QueryRowContext(ctx, "SELECT reaction_counts FROM "+table+" WHERE id = ?", id).Scan(&raw); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	if err := json.Unmarshal([]byte(raw), &counts); err != nil {
+		return nil, fmt.Errorf("decode reaction counts of %s %d: %v", table, id, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// schemaUpgrades change existing tables in ways ALTER TABLE cannot express.
+// Step i brings a database from PRAGMA user_version i to i+1; new steps go
+// to the end and the CREATE TABLE constants must already match the result of
+// the last step. A step that rebuilds a table changed again later keeps its
+// own frozen copy of the definition (see createPostLikesTableV1).
+var schemaUpgrades = []func(ctx context.Context, tx *sql.Tx) error{
+	// 1: ON DELETE actions on every foreign key to users, posts and comments
+	func(ctx context.Context, tx *sql.Tx) error {
+		// v1 is the definition at this version for tables changed again later
+		tables := []struct{ name, create, v1 string }{
+			{"posts", createPostsTable, ""},
+			{"comments", createCommentsTable, ""},
+			{"post_categories", createPostCategoriesTable, ""},
+			{"post_likes", createPostLikesTable, createPostLikesTableV1},
+			{"comment_likes", createCommentLikesTable, createCommentLikesTableV1},
+			{"sessions", createSessionsTable, ""},
+			{"messages", createMessagesTable, ""},
+			{"presence", createPresenceTable, ""},
+		}
+		for _, t := range tables {
+			create := t.create
+			// a table the old database lacked was just created in the latest shape
+			if t.v1 != "" {
+				legacy, err := hasColumn(ctx, tx, t.name, "is_like")
+				if err != nil {
+					return err
+				}
+				if legacy {
+					create = t.v1
+				}
+			}
+			if err := rebuildTable(ctx, tx, t.name, create); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: reactions are named ("like", "love", ...) instead of a like/dislike flag
+	func(ctx context.Context, tx *sql.Tx) error {
+		tables := []struct{ name, create string }{
+			{"post_likes", createPostLikesTable},
+			{"comment_likes", createCommentLikesTable},
+		}
+		for _, t := range tables {
+			legacy, err := hasColumn(ctx, tx, t.name, "is_like")
+			if err != nil {
+				return err
+			}
+			if !legacy {
+				continue
+			}
+			err = rebuildTableWith(ctx, tx, t.name, t.create, map[string]string{
+				"is_like": "reaction = CASE WHEN is_like THEN 'like' ELSE 'dislike' END",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// Reaction tables as of version 1, before step 2 replaced is_like
+const createPostLikesTableV1 = `
+CREATE TABLE IF NOT EXISTS post_likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    is_like BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+`
+
+const createCommentLikesTableV1 = `
+CREATE TABLE IF NOT EXISTS comment_likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    is_like BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(comment_id, user_id),
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+`
+
+// SchemaVersion returns the schema version of db (PRAGMA user_version)
+func SchemaVersion(db *sql.DB) (int, error) {
+	var v int
+	err := db.QueryRow("PRAGMA user_version").Scan(&v)
+	return v, err
+}
+
+// upgradeSchema applies the pending schemaUpgrades, each in its own transaction.
+// Foreign keys are switched off for the duration, as SQLite requires for table
+// rebuilds, and checked before every commit instead.
+func upgradeSchema(db *sql.DB) error {
+	ctx := context.Background()
+
+	// PRAGMA foreign_keys is per connection, so everything runs on one
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var version int
+	if err := conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(schemaUpgrades) {
+		return nil
+	}
+
+	var fkEnabled bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fkEnabled); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	if fkEnabled {
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	for ; version < len(schemaUpgrades); version++ {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := schemaUpgrades[version](ctx, tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("upgrade to version %d: %v", version+1, err)
+		}
+		if err := checkForeignKeys(ctx, tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("upgrade to version %d: %v", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildTable recreates table from its CREATE TABLE IF NOT EXISTS statement
+// and copies the rows over (https://www.sqlite.org/lang_altertable.html#otheralter)
+func rebuildTable(ctx context.Context, tx *sql.Tx, table, create string) error {
+	return rebuildTableWith(ctx, tx, table, create, nil)
+}
+
+// rebuildTableWith is rebuildTable for a changed column set: replaced maps an
+// old column to "new_column = expression", the expression being computed
+// over the old row. Other columns are copied as they are.
+func rebuildTableWith(ctx context.Context, tx *sql.Tx, table, create string, replaced map[string]string) error {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	values := append([]string(nil), columns...)
+	for i, c := range columns {
+		if r, ok := replaced[c]; ok {
+			name, expr, _ := strings.Cut(r, " = ")
+			columns[i], values[i] = name, expr
+		}
+	}
+
+	tmp := table + "_new"
+	stmt := strings.Replace(create, "CREATE TABLE IF NOT EXISTS "+table+" (", "CREATE TABLE "+tmp+" (", 1)
+	if stmt == create {
+		return fmt.Errorf("rebuild %s: unexpected CREATE statement", table)
+	}
+
+	steps := []string{
+		stmt,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmp, strings.Join(columns, ", "), strings.Join(values, ", "), table),
+		"DROP TABLE " + table,
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table),
+	}
+	for _, s := range steps {
+		if _, err := tx.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("rebuild %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+func hasColumn(ctx context.Context, tx *sql.Tx, table, column string) (bool, error) {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range columns {
+		if c == column {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkForeignKeys fails if any row points to a missing parent
+func checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var (
+			table  string
+			rowID  sql.NullInt64
+			parent string
+			fkID   int
+		)
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references missing %s", table, rowID.Int64, parent)
+	}
+	return rows.Err()
+}
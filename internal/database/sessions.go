@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// sessions.expires_at и created_at хранятся строками datetime() в UTC
+const sessionTimeLayout = "2006-01-02 15:04:05"
+
+// GetSession returns the owner and expiry of a session (sql.ErrNoRows if unknown).
+// A session with a broken expires_at comes back with the zero time, i.e. expired.
+func GetSession(ctx context.Context, db *sql.DB, id string) (int, time.Time, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	// Берём unix timestamp из expires_at - это надёжнее для сравнения
+	var (
+		userID      int
+		expiresUnix sql.NullString
+	)
+	err := db.QueryRowContext(ctx, "SELECT user_id, strftime('%s', expires_at) FROM sessions WHERE id = ?", id).Scan(&userID, &expiresUnix)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	sec, err := strconv.ParseInt(expiresUnix.String, 10, 64)
+	if !expiresUnix.Valid || err != nil {
+		return userID, time.Time{}, nil
+	}
+	return userID, time.Unix(sec, 0), nil
+}
+
+// ReplaceUserSessions stores a new session and deletes all other sessions of the user
+func ReplaceUserSessions(ctx context.Context, db *sql.DB, userID int, id string, expiresAt time.Time) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO sessions (id, user_id, expires_at, created_at) VALUES (?, ?, datetime(?), datetime('now'))",
+			id, userID, expiresAt.UTC().Format(sessionTimeLayout),
+		)
+		return err
+	})
+}
+
+// DeleteSession removes a session and reports whether it existed
+func DeleteSession(ctx context.Context, db *sql.DB, id string) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteExpiredSessions removes every expired session and returns their number
+func DeleteExpiredSessions(ctx context.Context, db *sql.DB) (int64, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE datetime(expires_at) <= datetime('now')")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+
+	"real-time-forum/internal/models"
+)
+
+// GetTagsForPost returns the tag names of a post in alphabetical order
+func GetTagsForPost(ctx context.Context, db *sql.DB, postID int) ([]string, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.name
+		FROM tags t
+		JOIN post_tags pt ON t.id = pt.tag_id
+		WHERE pt.post_id = ?
+		ORDER BY t.name`,
+		postID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+// AddTagsToPost attaches already normalized tags to a post, creating unknown
+// ones; run it in a transaction to add all tags or none
+func AddTagsToPost(ctx context.Context, db DBTX, postID int, tags []string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	for _, name := range tags {
+		if _, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", name); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx,
+			"INSERT OR IGNORE INTO post_tags (post_id, tag_id) SELECT ?, id FROM tags WHERE name = ?",
+			postID, name,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchTags returns up to limit tags starting with prefix, most used first
+func SearchTags(ctx context.Context, db *sql.DB, prefix string, limit int) ([]models.Tag, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.name, COUNT(pt.post_id) AS uses
+		FROM tags t
+		JOIN post_tags pt ON t.id = pt.tag_id
+		WHERE substr(t.name, 1, ?) = ?
+		GROUP BY t.id
+		ORDER BY uses DESC, t.name
+		LIMIT ?`,
+		utf8.RuneCountInString(prefix), prefix, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// GetPostIDsByTags returns the posts carrying at least one of the tags
+func GetPostIDsByTags(ctx context.Context, db *sql.DB, tags []string) (map[int]bool, error) {
+	if len(tags) == 0 {
+		return map[int]bool{}, nil
+	}
+	args := make([]interface{}, len(tags))
+	for i, t := range tags {
+		args[i] = t
+	}
+	return queryPostIDs(ctx, db, `
+		SELECT DISTINCT pt.post_id
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE t.name IN (`+strings.Repeat("?,", len(tags)-1)+`?)`,
+		args...,
+	)
+}
+
+// GetPostIDsByCategories returns the posts in at least one of the categories
+func GetPostIDsByCategories(ctx context.Context, db *sql.DB, categoryIDs []int) (map[int]bool, error) {
+	if len(categoryIDs) == 0 {
+		return map[int]bool{}, nil
+	}
+	args := make([]interface{}, len(categoryIDs))
+	for i, id := range categoryIDs {
+		args[i] = id
+	}
+	return queryPostIDs(ctx, db,
+		"SELECT DISTINCT post_id FROM post_categories WHERE category_id IN ("+strings.Repeat("?,", len(categoryIDs)-1)+"?)",
+		args...,
+	)
+}
+
+func queryPostIDs(ctx context.Context, db *sql.DB, query string, args ...interface{}) (map[int]bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPostTags(t *testing.T) {
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	res, _ := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('a@example.com', 'alice', 'x')")
+	alice, _ := res.LastInsertId()
+
+	newPost := func(tags ...string) int {
+		t.Helper()
+		id, err := CreatePost(context.Background(), db, int(alice), "title", "content")
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		if err := AddTagsToPost(context.Background(), db, id, tags); err != nil {
+			t.Fatalf("AddTagsToPost: %v", err)
+		}
+		return id
+	}
+	p1 := newPost("golang", "go", "web")
+	p2 := newPost("golang")
+	p3 := newPost()
+
+	if tags, _ := GetTagsForPost(context.Background(), db, p1); len(tags) != 3 || tags[0] != "go" || tags[2] != "web" {
+		t.Fatalf("tags of post 1 = %q", tags)
+	}
+	if tags, err := GetTagsForPost(context.Background(), db, p3); err != nil || tags == nil || len(tags) != 0 {
+		t.Fatalf("untagged post: %q, %v; want empty list", tags, err)
+	}
+	// повторное добавление не дублирует
+	if err := AddTagsToPost(context.Background(), db, p2, []string{"golang"}); err != nil {
+		t.Fatalf("re-adding a tag: %v", err)
+	}
+
+	found, err := SearchTags(context.Background(), db, "go", 10)
+	if err != nil {
+		t.Fatalf("SearchTags: %v", err)
+	}
+	if len(found) != 2 || found[0].Name != "golang" || found[0].Count != 2 || found[1].Name != "go" {
+		t.Fatalf("SearchTags(context.Background(), go) = %+v, want golang(2), go(1)", found)
+	}
+	if found, _ := SearchTags(context.Background(), db, "go", 1); len(found) != 1 {
+		t.Fatalf("limit ignored: %+v", found)
+	}
+
+	ids, err := GetPostIDsByTags(context.Background(), db, []string{"web", "missing"})
+	if err != nil || len(ids) != 1 || !ids[p1] {
+		t.Fatalf("GetPostIDsByTags = %v, %v", ids, err)
+	}
+
+	// удаление поста убирает и его связи с тегами
+	db.Exec("DELETE FROM posts WHERE id = ?", p1)
+	if found, _ := SearchTags(context.Background(), db, "web", 10); len(found) != 0 {
+		t.Fatalf("tag of a deleted post still suggested: %+v", found)
+	}
+}
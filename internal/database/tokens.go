@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Purposes of rows in user_tokens
+const (
+	TokenPasswordReset = "password_reset"
+	TokenEmailVerify   = "email_verify"
+	TokenLogin2FA      = "login_2fa" // password checked, waiting for the second factor
+)
+
+// ErrInvalidToken is returned for unknown, expired or already used tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// CreateUserToken stores the hash of a single-use token
+func CreateUserToken(ctx context.Context, db *sql.DB, userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		tokenHash, userID, purpose, expiresAt.UTC(), time.Now().UTC(),
+	)
+	return err
+}
+
+// ConsumeUserToken marks a token as used and returns its owner.
+// A token can be consumed only once, and only before it expires.
+func ConsumeUserToken(ctx context.Context, db *sql.DB, purpose, tokenHash string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID    int
+		expiresAt time.Time
+		usedAt    sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, expires_at, used_at FROM user_tokens WHERE token_hash = ? AND purpose = ?",
+		tokenHash, purpose,
+	).Scan(&userID, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if usedAt.Valid || !now.Before(expiresAt) {
+		return 0, ErrInvalidToken
+	}
+
+	// used_at IS NULL guards against two requests consuming the same token
+	res, err := tx.ExecContext(ctx,
+		"UPDATE user_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL",
+		now, tokenHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return 0, ErrInvalidToken
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// PeekUserToken returns the owner of a valid, unused token without consuming it
+func PeekUserToken(ctx context.Context, db *sql.DB, purpose, tokenHash string) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		userID    int
+		expiresAt time.Time
+		usedAt    sql.NullTime
+	)
+	err := db.QueryRowContext(ctx,
+		"SELECT user_id, expires_at, used_at FROM user_tokens WHERE token_hash = ? AND purpose = ?",
+		tokenHash, purpose,
+	).Scan(&userID, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if usedAt.Valid || !time.Now().UTC().Before(expiresAt) {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// InvalidateUserTokens marks every unused token of the given purpose as used
+func InvalidateUserTokens(ctx context.Context, db *sql.DB, userID int, purpose string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE user_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL",
+		time.Now().UTC(), userID, purpose,
+	)
+	return err
+}
+
+// LatestUserTokenAt returns when the newest token of the given purpose was issued
+// (zero time if there is none); used to throttle resends.
+func LatestUserTokenAt(ctx context.Context, db *sql.DB, userID int, purpose string) (time.Time, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var latest sql.NullTime
+	err := db.QueryRowContext(ctx,
+		"SELECT created_at FROM user_tokens WHERE user_id = ? AND purpose = ? ORDER BY created_at DESC LIMIT 1",
+		userID, purpose,
+	).Scan(&latest)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return latest.Time, nil
+}
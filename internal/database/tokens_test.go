@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConsumeUserToken(t *testing.T) {
+	db := setupInMemoryDB(t)
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	res, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES (?, ?, ?)", "a@example.com", "alice", "x")
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	userID := int(id)
+
+	if err := CreateUserToken(context.Background(), db, userID, TokenPasswordReset, "live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+	if err := CreateUserToken(context.Background(), db, userID, TokenPasswordReset, "stale", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+
+	got, err := ConsumeUserToken(context.Background(), db, TokenPasswordReset, "live")
+	if err != nil || got != userID {
+		t.Fatalf("ConsumeUserToken(context.Background(), live) = %d, %v; want %d", got, err, userID)
+	}
+
+	cases := []struct{ name, purpose, hash string }{
+		{"second use", TokenPasswordReset, "live"},
+		{"expired", TokenPasswordReset, "stale"},
+		{"unknown", TokenPasswordReset, "missing"},
+		{"wrong purpose", "other", "live"},
+	}
+	for _, c := range cases {
+		if _, err := ConsumeUserToken(context.Background(), db, c.purpose, c.hash); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", c.name, err)
+		}
+	}
+
+	if err := CreateUserToken(context.Background(), db, userID, TokenPasswordReset, "revoked", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+	if err := InvalidateUserTokens(context.Background(), db, userID, TokenPasswordReset); err != nil {
+		t.Fatalf("InvalidateUserTokens: %v", err)
+	}
+	if _, err := ConsumeUserToken(context.Background(), db, TokenPasswordReset, "revoked"); err != ErrInvalidToken {
+		t.Errorf("revoked token: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TOTPState is the two-factor configuration of a user
+type TOTPState struct {
+	Secret   string // empty when 2FA was never set up
+	Enabled  bool
+	LastStep int64 // last accepted time step, codes at or before it are replays
+}
+
+// GetTOTPState loads the two-factor configuration of a user
+func GetTOTPState(ctx context.Context, db *sql.DB, userID int) (*TOTPState, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		secret    sql.NullString
+		enabledAt sql.NullTime
+		state     TOTPState
+	)
+	err := db.QueryRowContext(ctx,
+		"SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = ?",
+		userID,
+	).Scan(&secret, &enabledAt, &state.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	state.Secret = secret.String
+	state.Enabled = enabledAt.Valid && secret.Valid
+	return &state, nil
+}
+
+// SetPendingTOTPSecret stores a new secret that is not active until EnableTOTP
+func SetPendingTOTPSecret(ctx context.Context, db *sql.DB, userID int, secret string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE users SET totp_secret = ?, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ? AND totp_enabled_at IS NULL",
+		secret, userID,
+	)
+	return err
+}
+
+// EnableTOTP activates the pending secret and replaces the recovery codes
+func EnableTOTP(ctx context.Context, db *sql.DB, userID int, step int64, codeHashes []string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_enabled_at = ?, totp_last_step = ? WHERE id = ?",
+		time.Now().UTC(), step, userID,
+	); err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP removes the secret and all recovery codes
+func DisableTOTP(ctx context.Context, db *sql.DB, userID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?",
+		userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AdvanceTOTPStep records step as used. It returns false when a concurrent
+// request already used this or a later step.
+func AdvanceTOTPStep(ctx context.Context, db *sql.DB, userID int, step int64) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx,
+		"UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// UseRecoveryCode marks a recovery code as spent; false if it is unknown or used
+func UseRecoveryCode(ctx context.Context, db *sql.DB, userID int, codeHash string) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx,
+		"UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().UTC(), userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func CountRecoveryCodes(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)",
+			userID, h,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
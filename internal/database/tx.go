@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so a function taking it
+// can run on its own or as one step of a larger transaction
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryTimeout bounds every data-layer call; see SetQueryTimeout
+var queryTimeout = 5 * time.Second
+
+// SetQueryTimeout sets how long a single data-layer call may run before its
+// context is cancelled; zero or less disables the limit. Call it at startup.
+func SetQueryTimeout(d time.Duration) {
+	queryTimeout = d
+}
+
+// WithQueryTimeout derives the context of one data-layer call from ctx. The
+// caller's cancellation (client gone, server shutting down) still applies.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// WithTx runs fn inside a transaction: it commits when fn returns nil and
+// rolls back otherwise
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueriesHonourContext(t *testing.T) {
+	db := openFileDB(t)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetUserByID(ctx, db, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetUserByID with a cancelled context: err = %v, want context.Canceled", err)
+	}
+
+	defer SetQueryTimeout(queryTimeout)
+	SetQueryTimeout(time.Nanosecond)
+	if _, err := GetAllCategories(context.Background(), db, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetAllCategories past the query timeout: err = %v, want context.DeadlineExceeded", err)
+	}
+
+	SetQueryTimeout(0)
+	if _, err := GetAllCategories(context.Background(), db, false); err != nil {
+		t.Fatalf("GetAllCategories without a timeout: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IsEmailVerified reports whether the user confirmed their e-mail address
+func IsEmailVerified(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	var verifiedAt sql.NullTime
+	err := db.QueryRowContext(ctx, "SELECT email_verified_at FROM users WHERE id = ?", userID).Scan(&verifiedAt)
+	if err != nil {
+		return false, err
+	}
+	return verifiedAt.Valid, nil
+}
+
+// MarkEmailVerified stores the verification time unless the address is already verified
+func MarkEmailVerified(ctx context.Context, db *sql.DB, userID int) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx,
+		"UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL",
+		time.Now().UTC(), userID,
+	)
+	return err
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== ACCOUNT =====================
+//
+// Deleting the account and exporting its data accept only a browser session,
+// like the other security-sensitive endpoints.
+
+// DELETE /api/me
+// Body: {"password": "..."}; accounts created through single sign-on have no
+// password and confirm with {"confirm": "<username>"} instead.
+// The account is purged after cfg.AccountDeletionGrace; signing in before
+// that cancels the deletion.
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+		Confirm  string `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if user.PasswordHash == database.NoPassword {
+		if req.Confirm != user.Username {
+			http.Error(w, "type your username to confirm", http.StatusForbidden)
+			return
+		}
+	} else if utils.VerifyPassword(user.PasswordHash, req.Password) != nil {
+		http.Error(w, "password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	if err := h.repos.Accounts.RequestDeletion(r.Context(), userID, now); err != nil {
+		log.Printf("account deletion for %d: %v", userID, err)
+		http.Error(w, "failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.disconnect <- userID
+	middleware.LogoutUser(w, r, h.repos)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "deletion_scheduled",
+		"purge_at": now.Add(h.cfg.AccountDeletionGrace).UTC(),
+	})
+}
+
+// GET /api/me/export
+// Streams a ZIP archive with one JSON file per kind of data the forum keeps about the user.
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// account.json is assembled up front, so a broken account still gets a
+	// proper error status instead of a truncated archive
+	account, err := h.exportAccount(r.Context(), userID, user.Email)
+	if err != nil {
+		log.Printf("export account %d: %v", userID, err)
+		http.Error(w, "failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	files := []struct {
+		name string
+		load func() (interface{}, error)
+	}{
+		{"account.json", func() (interface{}, error) { return account, nil }},
+		{"posts.json", func() (interface{}, error) {
+			return h.repos.Posts.ListPosts(r.Context(), repos.PostFilter{AuthorID: userID, ViewerID: userID})
+		}},
+		{"comments.json", func() (interface{}, error) { return h.repos.Accounts.UserComments(r.Context(), userID) }},
+		{"post_reactions.json", func() (interface{}, error) { return h.repos.Accounts.UserPostReactions(r.Context(), userID) }},
+		{"comment_reactions.json", func() (interface{}, error) {
+			return h.repos.Accounts.UserCommentReactions(r.Context(), userID)
+		}},
+		{"messages.json", func() (interface{}, error) { return h.repos.Accounts.UserMessages(r.Context(), userID) }},
+		{"sessions.json", func() (interface{}, error) { return h.exportSessions(r.Context(), userID) }},
+		{"api_tokens.json", func() (interface{}, error) { return h.repos.APITokens.ListAPITokens(r.Context(), userID) }},
+		{"identities.json", func() (interface{}, error) { return h.repos.Accounts.UserIdentities(r.Context(), userID) }},
+		{"blocks.json", func() (interface{}, error) { return h.repos.Blocks.ListBlocked(r.Context(), userID) }},
+	}
+
+	filename := fmt.Sprintf("forum-export-%s-%s.zip", user.Username, time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		data, err := f.load()
+		if err != nil {
+			// заголовки уже отправлены — обрываем архив, клиент увидит битый ZIP
+			log.Printf("export %s for %d: %v", f.name, userID, err)
+			return
+		}
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("export for %d: %v", userID, err)
+	}
+}
+
+// exportAccount collects the profile, e-mail and settings of a user
+func (h *Handler) exportAccount(ctx context.Context, userID int, email string) (map[string]interface{}, error) {
+	profile, err := h.repos.Profiles.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	privacy, err := h.repos.Users.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := h.repos.TwoFactor.GetTOTPState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	verifiedAt, deletionRequestedAt, err := h.repos.Accounts.AccountInfo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":                    profile.ID,
+		"username":              profile.Username,
+		"email":                 email,
+		"email_verified_at":     verifiedAt,
+		"first_name":            profile.FirstName,
+		"last_name":             profile.LastName,
+		"age":                   profile.Age,
+		"gender":                profile.Gender,
+		"bio":                   profile.Bio,
+		"avatar_url":            profile.AvatarURL,
+		"created_at":            profile.CreatedAt,
+		"privacy":               privacy,
+		"two_factor_enabled":    totp.Enabled,
+		"deletion_requested_at": deletionRequestedAt,
+	}, nil
+}
+
+// exportSessions lists the user's sessions without their IDs, which are credentials
+func (h *Handler) exportSessions(ctx context.Context, userID int) (interface{}, error) {
+	sessions, err := h.repos.Sessions.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type session struct {
+		CreatedAt time.Time `json:"created_at"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	out := make([]session, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, session{CreatedAt: s.CreatedAt, ExpiresAt: s.ExpiresAt})
+	}
+	return out, nil
+}
+
+// errAccountBanned is returned by startSession for accounts banned with forumctl
+var errAccountBanned = errors.New("account is banned")
+
+// startSession signs the user in; doing so during the deletion grace period
+// cancels the pending account deletion
+func (h *Handler) startSession(ctx context.Context, w http.ResponseWriter, userID int) error {
+	if banned, err := h.repos.Admin.IsBanned(ctx, userID); err != nil {
+		return err
+	} else if banned {
+		return errAccountBanned
+	}
+	if cancelled, err := h.repos.Accounts.CancelDeletion(ctx, userID); err != nil {
+		return err
+	} else if cancelled {
+		log.Printf("account deletion cancelled for user %d", userID)
+	}
+	return middleware.CreateSession(ctx, w, h.repos, userID)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	hash, _ := utils.HashPassword("correct horse")
+	h.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", hash, alice)
+
+	del := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodDelete, "/api/me", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.Me(rec, req)
+		return rec
+	}
+
+	if rec := del(`{"password": "wrong"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong password: status %d, want 403", rec.Code)
+	}
+	if at, _ := database.GetDeletionRequestedAt(context.Background(), h.db, alice); at != nil {
+		t.Fatal("deletion scheduled despite the wrong password")
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/api/me", strings.NewReader(`{"password": "correct horse"}`)), h, alice)
+	rec := httptest.NewRecorder()
+	h.Me(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if at, _ := database.GetDeletionRequestedAt(context.Background(), h.db, alice); at == nil {
+		t.Fatal("deletion was not scheduled")
+	}
+	if _, err := middleware.GetUserIDFromSession(req, h.repos); err == nil {
+		t.Fatal("session still valid after the deletion request")
+	}
+
+	// signing in again within the grace period keeps the account
+	rec = httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"identifier": "alice", "password": "correct horse"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if at, _ := database.GetDeletionRequestedAt(context.Background(), h.db, alice); at != nil {
+		t.Fatal("login did not cancel the deletion")
+	}
+}
+
+func TestDeleteAccountWithoutPassword(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	h.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", database.NoPassword, alice)
+
+	for body, want := range map[string]int{
+		`{"confirm": "bob"}`:   http.StatusForbidden,
+		`{"confirm": "alice"}`: http.StatusAccepted,
+	} {
+		rec := httptest.NewRecorder()
+		h.Me(rec, withUser(httptest.NewRequest(http.MethodDelete, "/api/me", strings.NewReader(body)), h, alice))
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", body, rec.Code, want)
+		}
+	}
+}
+
+func TestExportData(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+
+	postID, _ := database.CreatePost(context.Background(), h.db, alice, "my post", "content")
+	h.db.Exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'my comment')", postID, alice)
+	h.db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'love')", postID, alice)
+	database.InsertMessage(context.Background(), h.db, bob, alice, "hi alice")
+	database.BlockUser(context.Background(), h.db, alice, bob)
+
+	rec := httptest.NewRecorder()
+	h.ExportData(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/me/export", nil), h, alice))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if !json.Valid(data) {
+			t.Errorf("%s is not valid JSON", f.Name)
+		}
+		files[f.Name] = string(data)
+	}
+
+	checks := map[string]string{
+		"account.json":        "alice@example.com",
+		"posts.json":          "my post",
+		"comments.json":       "my comment",
+		"post_reactions.json": `"reaction": "love"`,
+		"messages.json":       "hi alice",
+		"blocks.json":         `"username": "bob"`,
+	}
+	for name, want := range checks {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("%s does not contain %q:\n%s", name, want, files[name])
+		}
+	}
+	if strings.Contains(files["sessions.json"], `"id"`) {
+		t.Error("sessions.json exposes session IDs")
+	}
+
+	// API tokens can't export the account
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/me/export", nil)
+	req.Header.Set("Authorization", "Bearer "+middleware.APITokenPrefix+"whatever")
+	h.ExportData(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bearer token: status %d, want 401", rec.Code)
+	}
+}
+
+func TestBannedUserCannotLogIn(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	hash, _ := utils.HashPassword("correct horse")
+	h.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", hash, alice)
+	session := withUser(httptest.NewRequest(http.MethodGet, "/api/me", nil), h, alice)
+
+	if _, err := database.BanUser(context.Background(), h.db, alice, time.Now()); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	if _, err := middleware.GetUserIDFromSession(session, h.repos); err == nil {
+		t.Fatal("session still valid after the ban")
+	}
+
+	login := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"identifier": "alice", "password": "correct horse"}`)))
+		return rec
+	}
+	if rec := login(); rec.Code != http.StatusForbidden {
+		t.Fatalf("banned login: status %d, want 403", rec.Code)
+	}
+
+	database.UnbanUser(context.Background(), h.db, alice)
+	if rec := login(); rec.Code != http.StatusOK {
+		t.Fatalf("login after unban: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
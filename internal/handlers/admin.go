@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"real-time-forum/internal/middleware"
+)
+
+//
+// ===================== ADMIN =====================
+//
+
+// POST /api/admin/unlock
+// Body: {"identifier": "email or username"} and/or {"ip": "1.2.3.4"}
+func (h *Handler) AdminUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Identifier string `json:"identifier"`
+		IP         string `json:"ip"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	req.Identifier = strings.TrimSpace(req.Identifier)
+	req.IP = strings.TrimSpace(req.IP)
+	if req.Identifier == "" && req.IP == "" {
+		http.Error(w, "identifier or ip required", http.StatusBadRequest)
+		return
+	}
+
+	var keys []string
+	if req.Identifier != "" {
+		keys = append(keys, middleware.IdentifierKey(req.Identifier))
+		userID, err := h.repos.Users.IDByLogin(r.Context(), req.Identifier)
+		if err == nil {
+			keys = append(keys, middleware.AccountKey(userID))
+		} else if err != sql.ErrNoRows {
+			http.Error(w, "failed to unlock", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.IP != "" {
+		keys = append(keys, "ip:"+req.IP)
+	}
+
+	cleared, err := h.repos.LoginAttempts.ClearLoginAttempts(r.Context(), keys...)
+	if err != nil {
+		http.Error(w, "failed to unlock", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"cleared": cleared})
+}
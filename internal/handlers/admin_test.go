@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+func setupLockoutHandler(t *testing.T) (*testHandler, int) {
+	t.Helper()
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.LoginMaxAttempts = 2
+		cfg.LoginLockoutBase = time.Minute
+		cfg.LoginLockoutMax = time.Hour
+	})
+	alice := createTestUser(t, h, "alice")
+	hash, _ := utils.HashPassword("correct horse")
+	h.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", hash, alice)
+	return h, alice
+}
+
+func tryLogin(h *testHandler, identifier, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"identifier": identifier, "password": password})
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(string(body))))
+	return rec
+}
+
+func TestLoginLockout(t *testing.T) {
+	h, _ := setupLockoutHandler(t)
+
+	for i := 0; i < 2; i++ {
+		if rec := tryLogin(h, "alice", "wrong"); rec.Code != http.StatusUnauthorized || rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("free failure #%d: status %d, Retry-After %q", i+1, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if rec := tryLogin(h, "alice", "wrong"); rec.Code != http.StatusUnauthorized || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("failure over the limit: status %d, Retry-After %q; want 401 and 60", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// даже верный пароль не пускает, пока аккаунт заблокирован
+	rec := tryLogin(h, "alice", "correct horse")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("login while locked: status %d, want 429", rec.Code)
+	}
+	secs, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || secs < 1 || secs > 60 {
+		t.Errorf("Retry-After = %q, want 1..60 seconds", rec.Header().Get("Retry-After"))
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" {
+			t.Error("locked login set a session cookie")
+		}
+	}
+
+	// неизвестные имена блокируются так же
+	for i := 0; i < 3; i++ {
+		tryLogin(h, "nobody", "wrong")
+	}
+	if rec := tryLogin(h, "nobody", "wrong"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("unknown identifier after 3 failures: status %d, want 429", rec.Code)
+	}
+}
+
+func TestAdminUnlock(t *testing.T) {
+	h, _ := setupLockoutHandler(t)
+	admin := createTestUser(t, h, "admin")
+	h.db.Exec("UPDATE users SET is_admin = 1 WHERE id = ?", admin)
+	bob := createTestUser(t, h, "bob")
+
+	for i := 0; i < 3; i++ {
+		tryLogin(h, "alice", "wrong")
+	}
+	if rec := tryLogin(h, "alice", "correct horse"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("login while locked: status %d, want 429", rec.Code)
+	}
+
+	unlock := func(userID int, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/admin/unlock", strings.NewReader(body)), h, userID)
+		rec := httptest.NewRecorder()
+		middleware.RequireAdmin(h.AdminUnlock, h.repos)(rec, req)
+		return rec
+	}
+
+	if rec := unlock(bob, `{"identifier": "alice"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("unlock by non-admin: status %d, want 403", rec.Code)
+	}
+	if rec := unlock(admin, `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unlock without identifier: status %d, want 400", rec.Code)
+	}
+
+	rec := unlock(admin, `{"identifier": "ALICE", "ip": "192.0.2.1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unlock: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Cleared int64 `json:"cleared"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	// счётчики аккаунта и IP (httptest ходит с 192.0.2.1)
+	if resp.Cleared != 2 {
+		t.Errorf("cleared = %d, want 2", resp.Cleared)
+	}
+
+	if rec := tryLogin(h, "alice", "correct horse"); rec.Code != http.StatusOK {
+		t.Errorf("login after unlock: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== API TOKENS =====================
+//
+
+// GET  /api/tokens — list the user's tokens (never the token values)
+// POST /api/tokens — create a token; the value is returned only once
+// Body: {"name": "...", "scopes": ["read", "post", "message"], "expires_in_days": 30}
+func (h *Handler) APITokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.repos.APITokens.ListAPITokens(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "failed to load tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+
+	case http.MethodPost:
+		h.createAPIToken(w, r, userID)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) createAPIToken(w http.ResponseWriter, r *http.Request, userID int) {
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 64 {
+		http.Error(w, "name must be 1-64 characters", http.StatusBadRequest)
+		return
+	}
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, s := range req.Scopes {
+		if !middleware.ValidScope(s) {
+			http.Error(w, "unknown scope: "+s, http.StatusBadRequest)
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		http.Error(w, "at least one scope is required", http.StatusBadRequest)
+		return
+	}
+
+	ttl := h.cfg.APITokenDefaultTTL
+	if req.ExpiresInDays != 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > h.cfg.APITokenMaxTTL {
+		http.Error(w, "invalid expiry", http.StatusBadRequest)
+		return
+	}
+
+	raw, err := utils.GenerateToken()
+	if err != nil {
+		http.Error(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+	raw = middleware.APITokenPrefix + raw
+	expiresAt := time.Now().Add(ttl)
+
+	id, err := h.repos.APITokens.CreateAPIToken(r.Context(), userID, req.Name, utils.HashToken(raw), scopes, expiresAt)
+	if err != nil {
+		http.Error(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"name":       req.Name,
+		"scopes":     scopes,
+		"expires_at": expiresAt.UTC(),
+		"token":      raw,
+	})
+}
+
+// DELETE /api/tokens/{id}
+func (h *Handler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/tokens/"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.repos.APITokens.DeleteAPIToken(r.Context(), userID, id)
+	if err != nil {
+		http.Error(w, "failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
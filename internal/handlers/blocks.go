@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"real-time-forum/internal/middleware"
+)
+
+//
+// ===================== BLOCKS =====================
+//
+
+// GET  /api/blocks — the current user's block list
+// POST /api/blocks — body: {"user_id": 42}
+func (h *Handler) Blocks(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		blocked, err := h.repos.Blocks.ListBlocked(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "failed to load blocks", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(blocked)
+
+	case http.MethodPost:
+		var req struct {
+			UserID int `json:"user_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		if req.UserID == userID {
+			http.Error(w, "cannot block yourself", http.StatusBadRequest)
+			return
+		}
+		if _, err := h.repos.Users.GetByID(r.Context(), req.UserID); err != nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if err := h.repos.Blocks.Block(r.Context(), userID, req.UserID); err != nil {
+			log.Printf("block user %d -> %d: %v", userID, req.UserID, err)
+			http.Error(w, "failed to block user", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// DELETE /api/blocks/{id}
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	blockedID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/blocks/"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.repos.Blocks.Unblock(r.Context(), userID, blockedID)
+	if err != nil {
+		http.Error(w, "failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "not blocked", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBlockEndpoints(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+
+	block := func(body string) int {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/blocks", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.Blocks(rec, req)
+		return rec.Code
+	}
+
+	if code := block(`{"user_id": ` + strconv.Itoa(alice) + `}`); code != http.StatusBadRequest {
+		t.Fatalf("blocking yourself: status %d, want 400", code)
+	}
+	if code := block(`{"user_id": 9999}`); code != http.StatusNotFound {
+		t.Fatalf("blocking unknown user: status %d, want 404", code)
+	}
+	if code := block(`{"user_id": ` + strconv.Itoa(bob) + `}`); code != http.StatusNoContent {
+		t.Fatalf("block: status %d, want 204", code)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Blocks(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/blocks", nil), h, alice))
+	var list []models.BlockedUser
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != bob || list[0].Username != "bob" {
+		t.Fatalf("block list = %+v", list)
+	}
+
+	unblock := func() int {
+		req := withUser(httptest.NewRequest(http.MethodDelete, "/api/blocks/"+strconv.Itoa(bob), nil), h, alice)
+		rec := httptest.NewRecorder()
+		h.Unblock(rec, req)
+		return rec.Code
+	}
+	if code := unblock(); code != http.StatusNoContent {
+		t.Fatalf("unblock: status %d, want 204", code)
+	}
+	if code := unblock(); code != http.StatusNotFound {
+		t.Fatalf("second unblock: status %d, want 404", code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Blocks(rec, httptest.NewRequest(http.MethodGet, "/api/blocks", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("guest: status %d, want 401", rec.Code)
+	}
+}
+
+func TestBlockedContentHiddenFromListings(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+
+	postID, err := database.CreatePost(context.Background(), h.db, alice, "alice's post", "content")
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if _, err := database.CreatePost(context.Background(), h.db, bob, "bob's post", "content"); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	for _, author := range []int{alice, bob} {
+		if _, err := h.db.Exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'hi')", postID, author); err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+	}
+	if err := database.BlockUser(context.Background(), h.db, alice, bob); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+
+	authors := func(viewer int) (posts, comments []int) {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+		if viewer > 0 {
+			req = withUser(req, h, viewer)
+		}
+		rec := httptest.NewRecorder()
+		h.GetPosts(rec, req)
+		var ps []models.Post
+		json.NewDecoder(rec.Body).Decode(&ps)
+		for _, p := range ps {
+			posts = append(posts, p.UserID)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/api/comments?post_id="+strconv.Itoa(postID), nil)
+		if viewer > 0 {
+			req = withUser(req, h, viewer)
+		}
+		rec = httptest.NewRecorder()
+		h.Comments(rec, req)
+		var cs []models.Comment
+		json.NewDecoder(rec.Body).Decode(&cs)
+		for _, c := range cs {
+			comments = append(comments, c.UserID)
+		}
+		return posts, comments
+	}
+
+	posts, comments := authors(alice)
+	if len(posts) != 1 || posts[0] != alice || len(comments) != 1 || comments[0] != alice {
+		t.Fatalf("alice sees posts by %v and comments by %v, want only her own", posts, comments)
+	}
+
+	// the blocked user and guests are unaffected
+	for _, viewer := range []int{bob, 0} {
+		if posts, comments := authors(viewer); len(posts) != 2 || len(comments) != 2 {
+			t.Fatalf("viewer %d sees %d posts and %d comments, want 2 and 2", viewer, len(posts), len(comments))
+		}
+	}
+
+	// hiding is optional
+	h.db.Exec("UPDATE users SET hide_blocked_content = 0 WHERE id = ?", alice)
+	if posts, comments := authors(alice); len(posts) != 2 || len(comments) != 2 {
+		t.Fatalf("with hiding off alice sees %d posts and %d comments, want 2 and 2", len(posts), len(comments))
+	}
+}
+
+func TestBlockedSenderMessagesDropped(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.RequireVerifiedEmail = false
+	})
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	if err := database.BlockUser(context.Background(), h.db, alice, bob); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+
+	aliceConn := dialWS(t, h, server, alice)
+	bobConn := dialWS(t, h, server, bob)
+
+	send := func(conn *websocket.Conn, to int, content string) {
+		if err := conn.WriteJSON(WSMessage{"type": "message", "to": to, "content": content}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// bob's message vanishes without an error, so he can't tell he is blocked
+	send(bobConn, alice, "you there?")
+	// alice can't write to someone she blocked either, but she is told why
+	send(aliceConn, bob, "hi bob")
+	if msg := nextFrame(t, aliceConn, "message", "error"); msg["type"] != "error" {
+		t.Fatalf("alice got %v, want an error", msg)
+	}
+
+	// frames are handled in order, so once this one arrives the dropped
+	// message would have been delivered already
+	send(bobConn, bob, "note to self")
+	if msg := nextFrame(t, bobConn, "message", "error"); msg["content"] != "note to self" {
+		t.Fatalf("bob got %v, want only his note to self", msg)
+	}
+
+	var stored int
+	h.db.QueryRow("SELECT COUNT(*) FROM messages WHERE from_user = ? AND to_user = ?", bob, alice).Scan(&stored)
+	if stored != 0 {
+		t.Fatalf("%d blocked messages were stored", stored)
+	}
+}
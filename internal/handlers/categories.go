@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== ADMIN: CATEGORIES =====================
+//
+// Routes are wrapped in middleware.RequireAdmin.
+
+// GET  /api/admin/categories — the full tree, archived categories included
+// POST /api/admin/categories — body: {"name", "slug", "description", "parent_id", "sort_order"};
+// the slug defaults to one derived from the name
+func (h *Handler) AdminCategories(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		categories, err := h.repos.Categories.ListCategories(r.Context(), true)
+		if err != nil {
+			http.Error(w, "failed to load categories", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(categories)
+
+	case http.MethodPost:
+		var req struct {
+			Name        string `json:"name"`
+			Slug        string `json:"slug"`
+			Description string `json:"description"`
+			ParentID    *int   `json:"parent_id"`
+			SortOrder   int    `json:"sort_order"`
+			Archived    bool   `json:"archived"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		c := &models.Category{
+			Name:        strings.TrimSpace(req.Name),
+			Slug:        req.Slug,
+			Description: strings.TrimSpace(req.Description),
+			ParentID:    req.ParentID,
+			SortOrder:   req.SortOrder,
+			Archived:    req.Archived,
+		}
+		if c.Slug == "" {
+			c.Slug = utils.Slugify(c.Name)
+		}
+		if ok, msg := utils.ValidateCategoryData(c.Name, c.Slug, c.Description); !ok {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		id, err := h.repos.Categories.CreateCategory(r.Context(), c)
+		if !categoryWriteOK(w, err) {
+			return
+		}
+
+		created, err := h.repos.Categories.GetCategory(r.Context(), id)
+		if err != nil {
+			http.Error(w, "failed to load category", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// PATCH  /api/admin/categories/{id} — any of name, slug, description, parent_id, sort_order, archived;
+// "parent_id": null moves the category to the top level
+// DELETE /api/admin/categories/{id} — only for categories without subcategories or posts;
+// archive the others instead
+func (h *Handler) AdminCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/categories/"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		c, err := h.repos.Categories.GetCategory(r.Context(), id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "category not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "failed to load category", http.StatusInternalServerError)
+			return
+		}
+
+		// nil fields were omitted and keep their current value
+		var req struct {
+			Name        *string         `json:"name"`
+			Slug        *string         `json:"slug"`
+			Description *string         `json:"description"`
+			ParentID    json.RawMessage `json:"parent_id"`
+			SortOrder   *int            `json:"sort_order"`
+			Archived    *bool           `json:"archived"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Name != nil {
+			c.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Slug != nil {
+			c.Slug = *req.Slug
+		}
+		if req.Description != nil {
+			c.Description = strings.TrimSpace(*req.Description)
+		}
+		if req.SortOrder != nil {
+			c.SortOrder = *req.SortOrder
+		}
+		if req.Archived != nil {
+			c.Archived = *req.Archived
+		}
+		// parent_id distinguishes "absent" from an explicit null
+		if req.ParentID != nil {
+			c.ParentID = nil
+			if string(req.ParentID) != "null" {
+				var parent int
+				if err := json.Unmarshal(req.ParentID, &parent); err != nil {
+					http.Error(w, "invalid parent_id", http.StatusBadRequest)
+					return
+				}
+				c.ParentID = &parent
+			}
+		}
+
+		if ok, msg := utils.ValidateCategoryData(c.Name, c.Slug, c.Description); !ok {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		if !categoryWriteOK(w, h.repos.Categories.UpdateCategory(r.Context(), c)) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c)
+
+	case http.MethodDelete:
+		children, posts, err := h.repos.Categories.CountCategoryUsage(r.Context(), id)
+		if err != nil {
+			http.Error(w, "failed to delete category", http.StatusInternalServerError)
+			return
+		}
+		if children > 0 || posts > 0 {
+			http.Error(w, "category has subcategories or posts; archive it instead", http.StatusConflict)
+			return
+		}
+
+		if err := h.repos.Categories.DeleteCategory(r.Context(), id); err == sql.ErrNoRows {
+			http.Error(w, "category not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "failed to delete category", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// categoryWriteOK maps the errors of CreateCategory / UpdateCategory to responses
+func categoryWriteOK(w http.ResponseWriter, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case database.ErrCategoryExists:
+		http.Error(w, "category name or slug already exists", http.StatusConflict)
+	case database.ErrCategoryCycle:
+		http.Error(w, "category cannot be nested under itself", http.StatusBadRequest)
+	case sql.ErrNoRows:
+		http.Error(w, "category or parent not found", http.StatusNotFound)
+	default:
+		log.Printf("category write: %v", err)
+		http.Error(w, "failed to save category", http.StatusInternalServerError)
+	}
+	return false
+}
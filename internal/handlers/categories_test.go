@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+)
+
+func TestAdminCategories(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	admin := createTestUser(t, h, "admin")
+	h.db.Exec("UPDATE users SET is_admin = 1 WHERE id = ?", admin)
+	alice := createTestUser(t, h, "alice")
+
+	list := middleware.RequireAdmin(h.AdminCategories, h.repos)
+	item := middleware.RequireAdmin(h.AdminCategory, h.repos)
+	call := func(handler http.HandlerFunc, userID int, method, path, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, path, strings.NewReader(body)), h, userID)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := call(list, alice, http.MethodPost, "/api/admin/categories", `{"name": "Remote"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: status %d, want 403", rec.Code)
+	}
+
+	rec := call(list, admin, http.MethodPost, "/api/admin/categories", `{"name": "Remote Work", "description": "anywhere"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var remote models.Category
+	json.NewDecoder(rec.Body).Decode(&remote)
+	if remote.Slug != "remote-work" {
+		t.Fatalf("slug = %q, want remote-work", remote.Slug)
+	}
+	path := "/api/admin/categories/" + strconv.Itoa(remote.ID)
+
+	if rec := call(list, admin, http.MethodPost, "/api/admin/categories", `{"name": "Other", "slug": "remote-work"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate slug: status %d, want 409", rec.Code)
+	}
+	if rec := call(list, admin, http.MethodPost, "/api/admin/categories", `{"name": "Child", "parent_id": 9999}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing parent: status %d, want 404", rec.Code)
+	}
+
+	rec = call(list, admin, http.MethodPost, "/api/admin/categories", `{"name": "Europe", "parent_id": `+strconv.Itoa(remote.ID)+`}`)
+	var europe models.Category
+	json.NewDecoder(rec.Body).Decode(&europe)
+
+	if rec := call(item, admin, http.MethodPatch, path, `{"parent_id": `+strconv.Itoa(europe.ID)+`}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("cycle: status %d, want 400", rec.Code)
+	}
+	if rec := call(item, admin, http.MethodDelete, path, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("delete with children: status %d, want 409", rec.Code)
+	}
+
+	// PATCH changes only the given fields
+	rec = call(item, admin, http.MethodPatch, "/api/admin/categories/"+strconv.Itoa(europe.ID), `{"sort_order": 5, "parent_id": null}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var moved models.Category
+	json.NewDecoder(rec.Body).Decode(&moved)
+	if moved.ParentID != nil || moved.SortOrder != 5 || moved.Name != "Europe" {
+		t.Fatalf("patched category = %+v", moved)
+	}
+
+	if rec := call(item, admin, http.MethodDelete, path, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(item, admin, http.MethodDelete, path, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("second delete: status %d, want 404", rec.Code)
+	}
+}
+
+func TestCreatePostChecksCategories(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	h.db.Exec("UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ?", alice)
+
+	archived, err := database.CreateCategory(context.Background(), h.db, &models.Category{Name: "Old", Slug: "old", Archived: true})
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	active, _ := database.GetAllCategories(context.Background(), h.db, false)
+
+	cases := map[string]int{
+		strconv.Itoa(active[0].ID): http.StatusOK,
+		strconv.Itoa(archived):     http.StatusBadRequest,
+		"9999":                     http.StatusBadRequest,
+	}
+	for category, want := range cases {
+		body := `{"title": "A valid title", "content": "Some content that is long enough", "categories": ["` + category + `"]}`
+		rec := httptest.NewRecorder()
+		h.CreatePost(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/posts/create", strings.NewReader(body)), h, alice))
+		if rec.Code != want {
+			t.Errorf("category %s: status %d, want %d (%s)", category, rec.Code, want, rec.Body.String())
+		}
+	}
+}
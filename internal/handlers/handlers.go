@@ -1,33 +1,62 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"real-time-forum/internal/database"
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/mailer"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/oidc"
 	"real-time-forum/internal/repos"
+	"real-time-forum/internal/services"
 	"real-time-forum/internal/utils"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
-	db    *sql.DB
-	hub   *Hub
-	repos *repos.Repos
+	cfg          *config.Config
+	hub          *Hub
+	repos        *repos.Repos
+	svc          *services.Services
+	loginLimiter *middleware.LoginLimiter
+	mailer       mailer.Mailer
+	oidc         *oidc.Provider // nil when single sign-on is not configured
 }
 
-func NewHandler(db *sql.DB) *Handler {
-	adapter := repos.NewSQLiteAdapter(db)
-	r := &repos.Repos{Users: adapter, Messages: adapter, Presence: adapter}
-	h := &Handler{db: db, hub: NewHub(), repos: r}
+// NewHandler serves requests from store, the repositories of the configured
+// database (see repos.New)
+func NewHandler(store *repos.Repos, cfg *config.Config) *Handler {
+	h := &Handler{cfg: cfg, hub: NewHub(cfg), repos: store, svc: services.New(store, cfg.ReactionNames()), mailer: mailer.New(cfg.MailerType, cfg.MailDir)}
+	h.hub.chat = h.svc.Chat
+	h.loginLimiter = middleware.NewLoginLimiter(store.LoginAttempts,
+		middleware.LoginPolicy{
+			FreeAttempts: cfg.LoginIPMaxAttempts,
+			BaseLockout:  cfg.LoginLockoutBase,
+			MaxLockout:   cfg.LoginLockoutMax,
+			Window:       cfg.LoginFailureWindow,
+		},
+		middleware.LoginPolicy{
+			FreeAttempts: cfg.LoginMaxAttempts,
+			BaseLockout:  cfg.LoginLockoutBase,
+			MaxLockout:   cfg.LoginLockoutMax,
+			Window:       cfg.LoginFailureWindow,
+		},
+	)
+	if cfg.OIDCEnabled() {
+		h.oidc = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		}, nil)
+	}
 	// start hub run loop for safe broadcasting
 	go h.hub.Run()
 	return h
@@ -35,7 +64,7 @@ func NewHandler(db *sql.DB) *Handler {
 
 // ServeWS proxies WebSocket requests to the hub
 func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
-	h.hub.ServeWS(w, r, h.db)
+	h.hub.ServeWS(w, r, h.repos)
 }
 
 // UsersHandler handles GET /api/users for chat roster
@@ -45,13 +74,13 @@ func (h *Handler) UsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := middleware.GetUserIDFromContextOrSession(r, h.db)
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	users, err := database.ListChatUsers(h.db, userID)
+	users, err := h.repos.Messages.ChatUsers(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "failed to load users", http.StatusInternalServerError)
 		return
@@ -69,7 +98,7 @@ func (h *Handler) MessagesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// authenticate (prefer userID from context when available)
-	userID, err := middleware.GetUserIDFromContextOrSession(r, h.db)
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -97,26 +126,16 @@ func (h *Handler) MessagesHandler(w http.ResponseWriter, r *http.Request) {
 	// limit fixed to 10 per requirements
 	limit := 10
 
-	// authorization check: ensure both users exist
-	if _, err := database.GetUserByID(h.db, otherID); err != nil {
+	msgs, hasMore, err := h.svc.Chat.History(r.Context(), userID, otherID, offset, limit)
+	if errors.Is(err, services.ErrNotFound) {
 		http.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
-
-	msgs, err := database.GetMessagesBetween(h.db, userID, otherID, offset, limit)
-	if err != nil {
-		http.Error(w, "failed to load messages", http.StatusInternalServerError)
-		return
-	}
-
-	total, err := database.CountMessagesBetween(h.db, userID, otherID)
 	if err != nil {
 		http.Error(w, "failed to load messages", http.StatusInternalServerError)
 		return
 	}
 
-	hasMore := (offset + limit) < total
-
 	// build response according to contract
 	type RespMsg struct {
 		ID        int    `json:"id"`
@@ -172,37 +191,24 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !utils.IsValidEmail(req.Email) ||
-		!utils.IsValidUsername(req.Username) ||
-		req.Age < 13 {
-		http.Error(w, "invalid data", http.StatusBadRequest)
-		return
-	}
-
-	if ok, _ := database.EmailExists(h.db, req.Email); ok {
-		http.Error(w, "email exists", http.StatusConflict)
-		return
-	}
-	if ok, _ := database.UsernameExists(h.db, req.Username); ok {
-		http.Error(w, "username exists", http.StatusConflict)
-		return
-	}
-
-	hash, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-
-	_, err := h.db.Exec(`
-		INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		req.Email, req.Username, string(hash),
-		req.Age, req.Gender, req.FirstName, req.LastName,
-	)
-
+	user, err := h.svc.Auth.Register(r.Context(), services.Registration(req))
 	if err != nil {
-		fmt.Printf("REGISTER DB ERROR: %v\n", err) // Это выведет реальную ошибку в терминал
-		http.Error(w, fmt.Sprintf("db error: %v", err), http.StatusInternalServerError)
+		var verr *services.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			http.Error(w, verr.Msg, http.StatusBadRequest)
+		case errors.Is(err, services.ErrEmailExists), errors.Is(err, services.ErrUsernameExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("ERROR: register: %v", err)
+			http.Error(w, "failed to register", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	// письмо с подтверждением; если не ушло — пользователь может запросить повторно
+	h.sendVerificationEmail(r.Context(), user)
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -219,38 +225,84 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewDecoder(r.Body).Decode(&req)
 
-	var user models.User
-	err := h.db.QueryRow(`
-		SELECT id, username, password_hash
-		FROM users
-		WHERE LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)`,
-		req.Identifier, req.Identifier,
-	).Scan(&user.ID, &user.Username, &user.PasswordHash)
+	user, err := h.svc.Auth.FindAccount(r.Context(), req.Identifier)
 
-	if err != nil ||
-		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+	// throttle by client IP and by account (or by the raw identifier if it matched nobody)
+	limiterKeys := []string{middleware.IPKey(r), middleware.IdentifierKey(req.Identifier)}
+	if err == nil {
+		limiterKeys[1] = middleware.AccountKey(user.ID)
+	}
+
+	if wait, lerr := h.loginLimiter.RetryAfter(r.Context(), limiterKeys...); lerr != nil {
+		http.Error(w, "login error", http.StatusInternalServerError)
+		return
+	} else if wait > 0 {
+		middleware.SetRetryAfter(w, wait)
+		http.Error(w, "too many login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err != nil || h.svc.Auth.CheckPassword(user, req.Password) != nil {
+		if wait, lerr := h.loginLimiter.Fail(r.Context(), limiterKeys...); lerr == nil && wait > 0 {
+			middleware.SetRetryAfter(w, wait)
+		}
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	if err := middleware.CreateSession(w, h.db, user.ID); err != nil {
+	// забаненным не выдаём даже challenge второго фактора
+	if banned, err := h.repos.Admin.IsBanned(r.Context(), user.ID); err != nil {
+		http.Error(w, "login error", http.StatusInternalServerError)
+		return
+	} else if banned {
+		http.Error(w, errAccountBanned.Error(), http.StatusForbidden)
+		return
+	}
+
+	totp, err := h.repos.TwoFactor.GetTOTPState(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "login error", http.StatusInternalServerError)
+		return
+	}
+
+	// с 2FA сессия создаётся только после проверки кода (POST /api/login/2fa);
+	// счётчик аккаунта не сбрасываем, иначе коды можно перебирать бесконечно
+	if totp.Enabled {
+		challenge, err := h.issueLoginChallenge(r.Context(), user.ID)
+		if err != nil {
+			http.Error(w, "login error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":    "2fa_required",
+			"challenge": challenge,
+		})
+		return
+	}
+
+	// успешный вход сбрасывает счётчик аккаунта (счётчик IP остаётся)
+	h.loginLimiter.Reset(r.Context(), limiterKeys[1])
+
+	if err := h.startSession(r.Context(), w, user.ID); err != nil {
 		http.Error(w, "session error", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 // POST /api/logout
 // func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
-// 	middleware.LogoutUser(w, r, h.db)
+// 	middleware.LogoutUser(w, r, h.repos)
 // 	w.WriteHeader(http.StatusNoContent)
 // }
 
 // POST /api/logout
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	// 1. Получаем ID пользователя перед тем, как уничтожить сессию
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
 	if err == nil {
 		// 2. Сигнализируем Hub о необходимости принудительно закрыть соединения
 		// Предполагается, что экземпляр Hub доступен через ваш Handler (например, h.hub)
@@ -258,22 +310,61 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Вызываем стандартную очистку кук и сессии
-	middleware.LogoutUser(w, r, h.db)
+	middleware.LogoutUser(w, r, h.repos)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // GET /api/me
+// Also issues the CSRF token (header + body) that every non-GET API call must echo back.
+// PATCH /api/me edits the profile (see UpdateProfile), DELETE /api/me deletes
+// the account (see DeleteAccount).
 func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+	if r.Method == http.MethodDelete {
+		h.DeleteAccount(w, r)
+		return
+	}
+	if r.Method == http.MethodPatch {
+		h.UpdateProfile(w, r)
+		return
+	}
+
+	csrfToken, err := middleware.EnsureCSRFToken(w, r)
+	if err != nil {
+		http.Error(w, "csrf error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(middleware.CSRFHeaderName, csrfToken)
+
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	user, _ := database.GetUserByID(h.db, userID)
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return
+	}
+	profile, err := h.repos.Profiles.GetProfile(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return
+	}
+	verified, _ := h.repos.Users.IsEmailVerified(r.Context(), userID)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":       user.ID,
-		"username": user.Username,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"first_name":     profile.FirstName,
+		"last_name":      profile.LastName,
+		"age":            profile.Age,
+		"gender":         profile.Gender,
+		"bio":            profile.Bio,
+		"avatar_url":     profile.AvatarURL,
+		"created_at":     profile.CreatedAt,
+		"email_verified": verified,
+		"csrf_token":     csrfToken,
 	})
 }
 
@@ -283,42 +374,37 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/posts
 func (h *Handler) GetPosts(w http.ResponseWriter, r *http.Request) {
-	userID, _ := middleware.GetUserIDFromSession(r, h.db)
+	userID, _ := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	query := r.URL.Query()
 
 	// filters
-	mine := query.Get("mine") == "1"
-	liked := query.Get("liked") == "1"
-
-	var categoryIDs []int
+	q := services.PostQuery{
+		Mine:  query.Get("mine") == "1",
+		Liked: query.Get("liked") == "1",
+	}
 	if raw := query.Get("categories"); raw != "" {
 		for _, s := range strings.Split(raw, ",") {
 			if id, err := strconv.Atoi(s); err == nil {
-				categoryIDs = append(categoryIDs, id)
+				q.CategoryIDs = append(q.CategoryIDs, id)
 			}
 		}
 	}
+	if raw := query.Get("tags"); raw != "" {
+		q.Tags = strings.Split(raw, ",")
+	}
 
-	var (
-		posts []models.Post
-		err   error
-	)
-
-	switch {
-	case mine && len(categoryIDs) > 0:
-		posts, err = database.GetPostsByUserIDAndCategories(h.db, userID, categoryIDs)
-	case mine:
-		posts, err = database.GetPostsByUserID(h.db, userID)
-	case liked && len(categoryIDs) > 0:
-		posts, err = database.GetLikedPostsByCategories(h.db, userID, categoryIDs)
-	case liked:
-		posts, err = database.GetLikedPosts(h.db, userID)
-	case len(categoryIDs) > 0:
-		posts, err = database.GetPostsByCategories(h.db, categoryIDs)
+	// mode=or: a post matches either the categories or the tags;
+	// по умолчанию (and) — и то, и другое
+	switch query.Get("mode") {
+	case "", "and":
+	case "or":
+		q.MatchAny = true
 	default:
-		posts, err = database.GetAllPosts(h.db)
+		http.Error(w, "invalid mode", http.StatusBadRequest)
+		return
 	}
 
+	posts, err := h.svc.Posts.List(r.Context(), userID, q)
 	if err != nil {
 		http.Error(w, "failed to load posts", http.StatusInternalServerError)
 		return
@@ -346,7 +432,8 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := database.GetPostByID(h.db, id)
+	viewerID, _ := middleware.GetUserIDFromContextOrSession(r, h.repos)
+	post, err := h.svc.Posts.Get(r.Context(), viewerID, id)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
@@ -359,16 +446,21 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 // POST /api/posts/create
 // POST /api/posts/create
 func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
+	if !h.requireVerifiedEmail(r.Context(), w, userID) {
+		return
+	}
+
 	var req struct {
 		Title      string   `json:"title"`
 		Content    string   `json:"content"`
 		Categories []string `json:"categories"` // IDs категорий строками
+		Tags       []string `json:"tags"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -376,36 +468,34 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ok, msg := utils.ValidateCompletePostData(
-		req.Title,
-		req.Content,
-		req.Categories,
-	); !ok {
-		http.Error(w, msg, http.StatusBadRequest)
-		return
-	}
-
-	// 1️⃣ создаём пост
-	postID, err := database.CreatePost(h.db, userID, req.Title, req.Content)
-	if err != nil {
-		http.Error(w, "failed to create post", http.StatusInternalServerError)
-		return
-	}
-
-	// 2️⃣ сохраняем категории
+	newPost := services.NewPost{Title: req.Title, Content: req.Content, Tags: req.Tags}
 	for _, catIDStr := range req.Categories {
-		catID, err := strconv.Atoi(catIDStr)
-		if err != nil {
-			continue
+		catID, err := strconv.Atoi(strings.TrimSpace(catIDStr))
+		if err != nil || catID <= 0 {
+			http.Error(w, "Invalid category ID format", http.StatusBadRequest)
+			return
 		}
+		newPost.CategoryIDs = append(newPost.CategoryIDs, catID)
+	}
 
-		if err := database.AddCategoryToPost(h.db, postID, catID); err != nil {
-			http.Error(w, "failed to save categories", http.StatusInternalServerError)
-			return
+	// пост, категории и теги сохраняются одной транзакцией
+	postID, err := h.svc.Posts.Create(r.Context(), userID, newPost)
+	if err != nil {
+		var verr *services.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			http.Error(w, verr.Msg, http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidCategory):
+			http.Error(w, "Invalid category selected", http.StatusBadRequest)
+		default:
+			log.Printf("create post: %v", err)
+			http.Error(w, "failed to create post", http.StatusInternalServerError)
 		}
+		return
 	}
 
-	if post, err := database.GetPostByID(h.db, postID); err == nil {
+	// рассылаем только после коммита
+	if post, err := h.svc.Posts.Get(r.Context(), 0, postID); err == nil {
 		h.hub.Broadcast(WSMessage{"type": "post_created", "post": post})
 	}
 
@@ -428,7 +518,8 @@ func (h *Handler) Comments(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		comments, err := database.GetCommentsByPostID(h.db, postID)
+		viewerID, _ := middleware.GetUserIDFromContextOrSession(r, h.repos)
+		comments, err := h.svc.Comments.List(r.Context(), viewerID, postID)
 		if err != nil {
 			http.Error(w, "failed to load comments", http.StatusInternalServerError)
 			return
@@ -437,45 +528,42 @@ func (h *Handler) Comments(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(comments)
 
 	case http.MethodPost:
-		userID, err := middleware.GetUserIDFromSession(r, h.db)
+		userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
+		if !h.requireVerifiedEmail(r.Context(), w, userID) {
+			return
+		}
+
 		var req struct {
 			PostID  int    `json:"post_id"`
 			Content string `json:"content"`
 		}
 		json.NewDecoder(r.Body).Decode(&req)
 
-		if ok, msg := utils.ValidateCommentData(req.Content); !ok {
-			http.Error(w, msg, http.StatusBadRequest)
-			return
-		}
-
-		res, err := h.db.Exec(`
-			INSERT INTO comments (post_id, user_id, content, created_at)
-			VALUES (?, ?, ?, datetime('now'))`,
-			req.PostID, userID, req.Content,
-		)
-
+		comment, commentCount, err := h.svc.Comments.Create(r.Context(), userID, req.PostID, req.Content)
 		if err != nil {
-			http.Error(w, "failed to create comment", http.StatusInternalServerError)
+			var verr *services.ValidationError
+			switch {
+			case errors.As(err, &verr):
+				http.Error(w, verr.Msg, http.StatusBadRequest)
+			case errors.Is(err, services.ErrNotFound):
+				http.Error(w, "post not found", http.StatusNotFound)
+			default:
+				http.Error(w, "failed to create comment", http.StatusInternalServerError)
+			}
 			return
 		}
 
-		commentID, _ := res.LastInsertId()
-		comment, _ := database.GetCommentByID(h.db, int(commentID))
-		commentCount, _ := database.GetCommentCount(h.db, req.PostID)
-		if comment != nil {
-			h.hub.Broadcast(WSMessage{
-				"type":          "comment_created",
-				"post_id":       req.PostID,
-				"comment_count": commentCount,
-				"comment":       comment,
-			})
-		}
+		h.hub.Broadcast(WSMessage{
+			"type":          "comment_created",
+			"post_id":       req.PostID,
+			"comment_count": commentCount,
+			"comment":       comment,
+		})
 
 		w.WriteHeader(http.StatusCreated)
 
@@ -488,43 +576,49 @@ func (h *Handler) Comments(w http.ResponseWriter, r *http.Request) {
 // ===================== REACTIONS =====================
 //
 
-// POST /api/posts/like
-func (h *Handler) LikePost(w http.ResponseWriter, r *http.Request) {
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+// GET /api/reactions
+// Returns the configured reaction set, in display order.
+func (h *Handler) Reactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.cfg.Reactions)
+}
+
+// POST /api/posts/react {"post_id": 1, "reaction": "love"}
+func (h *Handler) ReactPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	var req struct {
-		PostID int `json:"post_id"`
+		PostID   int    `json:"post_id"`
+		Reaction string `json:"reaction"`
 	}
-	json.NewDecoder(r.Body).Decode(&req)
-
-	// ✅ LIKE = true
-	likes, dislikes, err := utils.TogglePostReaction(h.db, userID, req.PostID, true)
-	if err != nil {
-		http.Error(w, "failed to like post", http.StatusInternalServerError)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
+	h.reactToPost(w, r, userID, req.PostID, req.Reaction)
+}
 
-	json.NewEncoder(w).Encode(map[string]int{
-		"likes":    likes,
-		"dislikes": dislikes,
-	})
-
-	// broadcast updated counters to everyone
-	h.hub.Broadcast(WSMessage{
-		"type":     "post_reaction",
-		"post_id":  req.PostID,
-		"likes":    likes,
-		"dislikes": dislikes,
-	})
+// POST /api/posts/like
+func (h *Handler) LikePost(w http.ResponseWriter, r *http.Request) {
+	h.postShortcut(w, r, models.ReactionLike)
 }
 
 // POST /api/posts/dislike
 func (h *Handler) DislikePost(w http.ResponseWriter, r *http.Request) {
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+	h.postShortcut(w, r, models.ReactionDislike)
+}
+
+// postShortcut serves the like/dislike endpoints that predate named reactions
+func (h *Handler) postShortcut(w http.ResponseWriter, r *http.Request, reaction string) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
@@ -533,77 +627,62 @@ func (h *Handler) DislikePost(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PostID int `json:"post_id"`
 	}
-	json.NewDecoder(r.Body).Decode(&req)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	h.reactToPost(w, r, userID, req.PostID, reaction)
+}
 
-	// ✅ DISLIKE = false
-	likes, dislikes, err := utils.TogglePostReaction(h.db, userID, req.PostID, false)
-	if err != nil {
-		http.Error(w, "failed to dislike post", http.StatusInternalServerError)
+func (h *Handler) reactToPost(w http.ResponseWriter, r *http.Request, userID, postID int, reaction string) {
+	counts, mine, err := h.svc.Reactions.ReactToPost(r.Context(), userID, postID, reaction)
+	if !h.reactionOK(w, err, "post") {
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]int{
-		"likes":    likes,
-		"dislikes": dislikes,
-	})
+	writeReactionState(w, counts, mine)
 
-	// broadcast updated counters to everyone
-	h.hub.Broadcast(WSMessage{
-		"type":     "post_reaction",
-		"post_id":  req.PostID,
-		"likes":    likes,
-		"dislikes": dislikes,
+	h.broadcastReaction(userID, mine, WSMessage{
+		"type":      "post_reaction",
+		"post_id":   postID,
+		"likes":     counts[models.ReactionLike],
+		"dislikes":  counts[models.ReactionDislike],
+		"reactions": counts,
 	})
 }
 
-// POST /api/comments/like
-func (h *Handler) LikeComment(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("LOG: LikeComment handler started")
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+// POST /api/comments/react {"comment_id": 1, "reaction": "love"}
+func (h *Handler) ReactComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
-		fmt.Println("LOG: Auth error in LikeComment:", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	var req struct {
-		CommentID int `json:"comment_id"`
+		CommentID int    `json:"comment_id"`
+		Reaction  string `json:"reaction"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		fmt.Println("LOG: Decode error:", err) // И ЭТО
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
+	h.reactToComment(w, r, userID, req.CommentID, req.Reaction)
+}
 
-	fmt.Printf("LOG: Calling ToggleCommentReaction for user %d, comment %d\n", userID, req.CommentID)
-
-	// ✅ LIKE = true
-	likes, dislikes, err := utils.ToggleCommentReaction(h.db, userID, req.CommentID, true)
-	if err != nil {
-		http.Error(w, "failed to like comment", http.StatusInternalServerError)
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]int{
-		"likes":    likes,
-		"dislikes": dislikes,
-	})
-
-	if comment, err := database.GetCommentByID(h.db, req.CommentID); err == nil && comment != nil {
-		h.hub.Broadcast(WSMessage{
-			"type":       "comment_reaction",
-			"post_id":    comment.PostID,
-			"comment_id": req.CommentID,
-			"likes":      likes,
-			"dislikes":   dislikes,
-			"comment":    comment,
-		})
-	}
+// POST /api/comments/like
+func (h *Handler) LikeComment(w http.ResponseWriter, r *http.Request) {
+	h.commentShortcut(w, r, models.ReactionLike)
 }
 
 // POST /api/comments/dislike
 func (h *Handler) DislikeComment(w http.ResponseWriter, r *http.Request) {
-	userID, err := middleware.GetUserIDFromSession(r, h.db)
+	h.commentShortcut(w, r, models.ReactionDislike)
+}
+
+// commentShortcut serves the like/dislike endpoints that predate named reactions
+func (h *Handler) commentShortcut(w http.ResponseWriter, r *http.Request, reaction string) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
@@ -612,44 +691,88 @@ func (h *Handler) DislikeComment(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		CommentID int `json:"comment_id"`
 	}
-	json.NewDecoder(r.Body).Decode(&req)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	h.reactToComment(w, r, userID, req.CommentID, reaction)
+}
 
-	// ✅ DISLIKE = false
-	likes, dislikes, err := utils.ToggleCommentReaction(h.db, userID, req.CommentID, false)
-	if err != nil {
-		http.Error(w, "failed to dislike comment", http.StatusInternalServerError)
+func (h *Handler) reactToComment(w http.ResponseWriter, r *http.Request, userID, commentID int, reaction string) {
+	counts, mine, err := h.svc.Reactions.ReactToComment(r.Context(), userID, commentID, reaction)
+	if !h.reactionOK(w, err, "comment") {
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]int{
-		"likes":    likes,
-		"dislikes": dislikes,
-	})
+	writeReactionState(w, counts, mine)
 
-	if comment, err := database.GetCommentByID(h.db, req.CommentID); err == nil && comment != nil {
-		h.hub.Broadcast(WSMessage{
+	if comment, err := h.svc.Comments.Get(r.Context(), commentID); err == nil {
+		h.broadcastReaction(userID, mine, WSMessage{
 			"type":       "comment_reaction",
 			"post_id":    comment.PostID,
-			"comment_id": req.CommentID,
-			"likes":      likes,
-			"dislikes":   dislikes,
+			"comment_id": commentID,
+			"likes":      counts[models.ReactionLike],
+			"dislikes":   counts[models.ReactionDislike],
+			"reactions":  counts,
 			"comment":    comment,
 		})
 	}
 }
 
+// broadcastReaction sends the updated counters to everyone, and a copy with
+// my_reaction to the reacting user's own sockets so their other tabs can
+// highlight it. Who reacted is not broadcast.
+func (h *Handler) broadcastReaction(userID int, mine string, msg WSMessage) {
+	h.hub.Broadcast(msg)
+
+	own := WSMessage{"my_reaction": mine}
+	for k, v := range msg {
+		own[k] = v
+	}
+	h.hub.SendToUser(userID, own)
+}
+
+// reactionOK answers a failed toggle and reports whether it succeeded
+func (h *Handler) reactionOK(w http.ResponseWriter, err error, target string) bool {
+	var verr *services.ValidationError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &verr):
+		http.Error(w, verr.Msg, http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotFound):
+		http.Error(w, target+" not found", http.StatusNotFound)
+	default:
+		log.Printf("react to %s: %v", target, err)
+		http.Error(w, "failed to react to "+target, http.StatusInternalServerError)
+	}
+	return false
+}
+
+// writeReactionState answers a toggle with the target's counters and the user's reaction
+func writeReactionState(w http.ResponseWriter, counts map[string]int, mine string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"likes":       counts[models.ReactionLike],
+		"dislikes":    counts[models.ReactionDislike],
+		"reactions":   counts,
+		"my_reaction": mine,
+	})
+}
+
 //
 // ===================== CATEGORIES =====================
 //
 
 // GET /api/categories
+// Returns the tree of active categories (see repos.CategoryRepo).
 func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	categories, err := database.GetAllCategories(h.db)
+	categories, err := h.repos.Categories.ListCategories(r.Context(), false)
 	if err != nil {
 		http.Error(w, "failed to load categories", http.StatusInternalServerError)
 		return
@@ -657,3 +780,20 @@ func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(categories)
 }
+
+// GET /api/tags?prefix=go — tag autocomplete
+func (h *Handler) Tags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := h.repos.Posts.SearchTags(r.Context(), utils.NormalizeTag(r.URL.Query().Get("prefix")), 10)
+	if err != nil {
+		http.Error(w, "failed to load tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
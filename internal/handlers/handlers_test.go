@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/repos"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testHandler keeps the database next to the handler, so tests can set up
+// fixtures and check rows with plain SQL
+type testHandler struct {
+	*Handler
+	db *sql.DB
+}
+
+// setupTestHandler builds a Handler over a fresh database file opened the
+// way production opens it; configure may adjust the config before the
+// handler is created.
+func setupTestHandler(t *testing.T, configure func(*config.Config)) *testHandler {
+	t.Helper()
+
+	cfg := config.Load()
+	if configure != nil {
+		configure(cfg)
+	}
+	pools, err := database.InitDB(filepath.Join(t.TempDir(), "forum.db"), database.Options{
+		BusyTimeout:   cfg.DBBusyTimeout,
+		MaxWriteConns: cfg.DBMaxWriteConns,
+		MaxReadConns:  cfg.DBMaxReadConns,
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { pools.Close() })
+	store, err := repos.New(pools, repos.DialectSQLite)
+	if err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return &testHandler{Handler: NewHandler(store, cfg), db: pools.Write}
+}
+
+// createTestUser inserts a user and returns its ID
+func createTestUser(t *testing.T, h *testHandler, username string) int {
+	t.Helper()
+	res, err := h.db.Exec(
+		"INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name) VALUES (?, ?, 'x', 30, 'other', '', '')",
+		username+"@example.com", username,
+	)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return int(id)
+}
+
+// withUser attaches a fresh session cookie of userID to r
+func withUser(r *http.Request, h *testHandler, userID int) *http.Request {
+	rec := httptest.NewRecorder()
+	middleware.CreateSession(context.Background(), rec, h.repos, userID)
+	for _, c := range rec.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+// sentMail is a message captured by recordingMailer
+type sentMail struct {
+	To, Subject, Body string
+}
+
+// recordingMailer keeps the mail a handler sends instead of delivering it
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return nil
+}
+
+func (m *recordingMailer) messages() []sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentMail(nil), m.sent...)
+}
+
+// withRecordingMailer swaps the handler's mailer for a recordingMailer
+func withRecordingMailer(h *testHandler) *recordingMailer {
+	m := &recordingMailer{}
+	h.mailer = m
+	return m
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/oidc"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== SINGLE SIGN-ON (OIDC) =====================
+//
+
+// oidcStateCookie binds the login in flight to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// GET /api/auth/oidc
+// Tells the login page whether to show the SSO button.
+func (h *Handler) OIDCStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"enabled": h.oidc != nil})
+}
+
+// GET /api/auth/oidc/login[?link=1]
+// Redirects to the identity provider. With link=1 the signed-in user attaches
+// the external identity to their account instead of logging in.
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.oidc == nil {
+		http.Error(w, "single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	var linkUserID int
+	if r.URL.Query().Get("link") == "1" {
+		userID, err := middleware.GetUserIDFromSession(r, h.repos)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		linkUserID = userID
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, "sso error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, "sso error", http.StatusInternalServerError)
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		http.Error(w, "sso error", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := h.oidc.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	err = h.repos.Identities.SaveOIDCState(r.Context(), database.OIDCState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(h.cfg.OIDCStateTTL),
+	})
+	if err != nil {
+		http.Error(w, "sso error", http.StatusInternalServerError)
+		return
+	}
+
+	// SameSite=Lax: the callback is a top-level redirect from the IdP's site
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(h.cfg.OIDCStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /api/auth/oidc/callback?code=...&state=...
+// Always answers with a redirect back to the SPA; failures carry ?sso_error=<reason>,
+// accounts with 2FA get /login#2fa=<challenge> to finish at /api/login/2fa.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.oidc == nil {
+		http.Error(w, "single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	fail := func(reason string) {
+		http.Redirect(w, r, "/login?sso_error="+url.QueryEscape(reason), http.StatusSeeOther)
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/api/auth/oidc", MaxAge: -1, HttpOnly: true})
+
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		fail("denied")
+		return
+	}
+
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if state == "" || err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		fail("invalid_state")
+		return
+	}
+
+	flow, err := h.repos.Identities.ConsumeOIDCState(r.Context(), utils.HashToken(state))
+	if err != nil {
+		fail("invalid_state")
+		return
+	}
+
+	claims, err := h.oidc.Exchange(r.Context(), q.Get("code"), flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		fail("exchange_failed")
+		return
+	}
+
+	provider := h.cfg.OIDCProviderName
+
+	if flow.LinkUserID != 0 {
+		err := h.repos.Identities.LinkIdentity(r.Context(), flow.LinkUserID, provider, claims.Subject, claims.Email)
+		if err == database.ErrIdentityLinked {
+			if owner, _ := h.repos.Identities.UserIDByIdentity(r.Context(), provider, claims.Subject); owner == flow.LinkUserID {
+				http.Redirect(w, r, "/?sso=linked", http.StatusSeeOther)
+				return
+			}
+			fail("identity_in_use")
+			return
+		} else if err != nil {
+			fail("server_error")
+			return
+		}
+		http.Redirect(w, r, "/?sso=linked", http.StatusSeeOther)
+		return
+	}
+
+	userID, err := h.repos.Identities.UserIDByIdentity(r.Context(), provider, claims.Subject)
+	switch {
+	case err == nil:
+		h.repos.Identities.TouchIdentity(r.Context(), provider, claims.Subject, claims.Email)
+	case err == sql.ErrNoRows:
+		userID, err = h.provisionOIDCUser(r.Context(), claims)
+		if err != nil {
+			if reason, ok := err.(provisionError); ok {
+				fail(string(reason))
+				return
+			}
+			log.Printf("oidc provisioning: %v", err)
+			fail("server_error")
+			return
+		}
+	default:
+		fail("server_error")
+		return
+	}
+
+	// SSO заменяет только пароль: при включённой 2FA сессия создаётся после
+	// POST /api/login/2fa, как и при обычном входе
+	if banned, err := h.repos.Admin.IsBanned(r.Context(), userID); err != nil {
+		fail("server_error")
+		return
+	} else if banned {
+		fail("banned")
+		return
+	}
+	totp, err := h.repos.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		fail("server_error")
+		return
+	}
+	if totp.Enabled {
+		challenge, err := h.issueLoginChallenge(r.Context(), userID)
+		if err != nil {
+			fail("server_error")
+			return
+		}
+		// во фрагменте challenge не уходит на сервер и в логи прокси
+		http.Redirect(w, r, "/login#2fa="+url.QueryEscape(challenge), http.StatusSeeOther)
+		return
+	}
+
+	if err := h.startSession(r.Context(), w, userID); errors.Is(err, errAccountBanned) {
+		fail("banned")
+		return
+	} else if err != nil {
+		fail("server_error")
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// provisionError is a reason shown to the user, not an internal failure
+type provisionError string
+
+func (e provisionError) Error() string { return string(e) }
+
+// provisionOIDCUser creates the forum account on first SSO login.
+// An existing account with the same e-mail is never taken over automatically:
+// its owner has to sign in with the password and link the identity.
+func (h *Handler) provisionOIDCUser(ctx context.Context, claims *oidc.Claims) (int, error) {
+	if !utils.IsValidEmail(claims.Email) {
+		return 0, provisionError("email_required")
+	}
+	if exists, err := h.repos.Users.EmailExists(ctx, claims.Email); err != nil {
+		return 0, err
+	} else if exists {
+		return 0, provisionError("account_exists")
+	}
+
+	username, err := database.UniqueUsernameWith(ctx, h.repos.Users.UsernameExists, usernameFromClaims(claims))
+	if err != nil {
+		return 0, err
+	}
+
+	return h.repos.Identities.CreateExternalUser(ctx, database.ExternalUser{
+		Provider:      h.cfg.OIDCProviderName,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Username:      username,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	})
+}
+
+// usernameFromClaims derives a valid username (see utils.IsValidUsername) from
+// preferred_username, the e-mail local part or the display name
+func usernameFromClaims(claims *oidc.Claims) string {
+	local, _, _ := strings.Cut(claims.Email, "@")
+
+	for _, candidate := range []string{claims.PreferredUsername, local, claims.Name} {
+		var b strings.Builder
+		for _, c := range strings.TrimSpace(candidate) {
+			switch {
+			case unicode.IsLetter(c) || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == ' ':
+				b.WriteRune(c)
+			case c == '.' || c == '+':
+				b.WriteRune('_')
+			}
+		}
+
+		name := b.String()
+		for utf8.RuneCountInString(name) > 20 {
+			_, size := utf8.DecodeLastRuneInString(name)
+			name = name[:len(name)-size]
+		}
+		if name = strings.TrimSpace(name); utils.IsValidUsername(name) {
+			return name
+		}
+	}
+	return "user"
+}
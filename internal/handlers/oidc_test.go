@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/oidc"
+	"real-time-forum/internal/oidc/oidctest"
+	"real-time-forum/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func setupOIDCHandler(t *testing.T) (*testHandler, *oidctest.Server) {
+	t.Helper()
+
+	idp := oidctest.NewServer("forum", "s3cret")
+	t.Cleanup(idp.Close)
+
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.OIDCIssuer = idp.Issuer()
+		cfg.OIDCClientID = "forum"
+		cfg.OIDCClientSecret = "s3cret"
+		cfg.OIDCRedirectURL = "http://forum.test/api/auth/oidc/callback"
+		cfg.OIDCProviderName = "corp"
+	})
+	return h, idp
+}
+
+// loginViaOIDC runs the whole browser round trip and returns the callback response
+func loginViaOIDC(t *testing.T, h *testHandler, start *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, start)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d: %s", rec.Code, rec.Body.String())
+	}
+	authURL := rec.Header().Get("Location")
+	if !strings.Contains(authURL, "code_challenge_method=S256") {
+		t.Fatalf("authorization URL without PKCE: %s", authURL)
+	}
+
+	var stateCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil {
+		t.Fatal("state cookie not set")
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?"+callback.RawQuery, nil)
+	req.AddCookie(stateCookie)
+	rec = httptest.NewRecorder()
+	h.OIDCCallback(rec, req)
+	return rec
+}
+
+func sessionUserID(t *testing.T, h *testHandler, rec *httptest.ResponseRecorder) int {
+	t.Helper()
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" && c.Value != "" {
+			var userID int
+			if err := h.db.QueryRow("SELECT user_id FROM sessions WHERE id = ?", c.Value).Scan(&userID); err != nil {
+				t.Fatalf("session lookup: %v", err)
+			}
+			return userID
+		}
+	}
+	t.Fatalf("no session created, redirected to %s", rec.Header().Get("Location"))
+	return 0
+}
+
+func TestOIDCFirstLoginProvisionsUser(t *testing.T) {
+	h, idp := setupOIDCHandler(t)
+
+	// "alice" is taken, so the new account must get a suffix
+	if _, err := h.db.Exec(`INSERT INTO users (email, username, password_hash) VALUES ('other@example.com', 'alice', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+	idp.SetUser(oidctest.User{Subject: "sub-1", Email: "alice@corp.example", EmailVerified: true, PreferredUsername: "alice", GivenName: "Alice"})
+
+	rec := loginViaOIDC(t, h, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if loc := rec.Header().Get("Location"); loc != "/" {
+		t.Fatalf("redirected to %s", loc)
+	}
+	userID := sessionUserID(t, h, rec)
+
+	user, err := database.GetUserByID(context.Background(), h.db, userID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.Username != "alice2" || user.Email != "alice@corp.example" || user.FirstName != "Alice" {
+		t.Errorf("provisioned user = %+v", user)
+	}
+	if verified, _ := database.IsEmailVerified(context.Background(), h.db, userID); !verified {
+		t.Error("email verified by the IdP was not marked as verified")
+	}
+
+	// The account has no password that could ever match
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("")) == nil {
+		t.Error("provisioned account accepts an empty password")
+	}
+
+	// Second login reuses the same account
+	rec = loginViaOIDC(t, h, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if again := sessionUserID(t, h, rec); again != userID {
+		t.Errorf("second login user = %d, want %d", again, userID)
+	}
+}
+
+func TestOIDCDoesNotTakeOverExistingEmail(t *testing.T) {
+	h, idp := setupOIDCHandler(t)
+
+	if _, err := h.db.Exec(`INSERT INTO users (email, username, password_hash) VALUES ('bob@corp.example', 'bob', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+	idp.SetUser(oidctest.User{Subject: "sub-bob", Email: "Bob@corp.example", EmailVerified: true})
+
+	rec := loginViaOIDC(t, h, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if loc := rec.Header().Get("Location"); loc != "/login?sso_error=account_exists" {
+		t.Fatalf("redirected to %s", loc)
+	}
+	if _, err := database.GetUserIDByIdentity(context.Background(), h.db, "corp", "sub-bob"); err != sql.ErrNoRows {
+		t.Errorf("identity was linked: %v", err)
+	}
+}
+
+func TestOIDCLinkToSignedInUser(t *testing.T) {
+	h, idp := setupOIDCHandler(t)
+
+	res, err := h.db.Exec(`INSERT INTO users (email, username, password_hash) VALUES ('carol@example.com', 'carol', 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carolID, _ := res.LastInsertId()
+
+	sessionRec := httptest.NewRecorder()
+	if err := middleware.CreateSession(context.Background(), sessionRec, h.repos, int(carolID)); err != nil {
+		t.Fatal(err)
+	}
+
+	idp.SetUser(oidctest.User{Subject: "sub-carol", Email: "carol@corp.example"})
+
+	start := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login?link=1", nil)
+	for _, c := range sessionRec.Result().Cookies() {
+		start.AddCookie(c)
+	}
+	rec := loginViaOIDC(t, h, start)
+	if loc := rec.Header().Get("Location"); loc != "/?sso=linked" {
+		t.Fatalf("redirected to %s", loc)
+	}
+
+	// From now on SSO logs in as carol
+	rec = loginViaOIDC(t, h, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if got := sessionUserID(t, h, rec); got != int(carolID) {
+		t.Errorf("SSO login user = %d, want %d", got, carolID)
+	}
+}
+
+func TestOIDCLoginAsksForSecondFactor(t *testing.T) {
+	h, idp := setupOIDCHandler(t)
+	ctx := context.Background()
+
+	res, err := h.db.Exec(`INSERT INTO users (email, username, password_hash) VALUES ('dave@example.com', 'dave', 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+	daveID := int(id)
+	if err := database.LinkIdentity(ctx, h.db, daveID, "corp", "sub-dave", "dave@corp.example"); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := utils.GenerateTOTPSecret()
+	if err := database.SetPendingTOTPSecret(ctx, h.db, daveID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.EnableTOTP(ctx, h.db, daveID, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	idp.SetUser(oidctest.User{Subject: "sub-dave", Email: "dave@corp.example"})
+	rec := loginViaOIDC(t, h, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	loc := rec.Header().Get("Location")
+	challenge, ok := strings.CutPrefix(loc, "/login#2fa=")
+	if !ok || challenge == "" {
+		t.Fatalf("redirected to %s, want the 2fa step", loc)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" && c.Value != "" {
+			t.Fatal("session created before the second factor")
+		}
+	}
+
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	body := fmt.Sprintf(`{"challenge": %q, "code": %q}`, challenge, code)
+	rec = httptest.NewRecorder()
+	h.LoginSecondFactor(rec, httptest.NewRequest(http.MethodPost, "/api/login/2fa", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("2fa status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := sessionUserID(t, h, rec); got != daveID {
+		t.Errorf("session user = %d, want %d", got, daveID)
+	}
+}
+
+func TestOIDCLinkRequiresSession(t *testing.T) {
+	h, _ := setupOIDCHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login?link=1", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsForeignState(t *testing.T) {
+	h, idp := setupOIDCHandler(t)
+	idp.SetUser(oidctest.User{Subject: "sub-1", Email: "a@corp.example"})
+
+	// A state that was started in another browser (no matching cookie)
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	authURL, _ := url.Parse(rec.Header().Get("Location"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=x&state="+url.QueryEscape(authURL.Query().Get("state")), nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "something-else"})
+	rec = httptest.NewRecorder()
+	h.OIDCCallback(rec, req)
+
+	if loc := rec.Header().Get("Location"); loc != "/login?sso_error=invalid_state" {
+		t.Fatalf("redirected to %s", loc)
+	}
+}
+
+func TestUsernameFromClaims(t *testing.T) {
+	tests := []struct {
+		claims oidc.Claims
+		want   string
+	}{
+		{oidc.Claims{PreferredUsername: "alice"}, "alice"},
+		{oidc.Claims{PreferredUsername: "a", Email: "john.doe@corp.example"}, "john_doe"},
+		{oidc.Claims{Email: "x@corp.example", Name: "Мария Иванова"}, "Мария Иванова"},
+		{oidc.Claims{PreferredUsername: "averyveryverylongusername"}, "averyveryverylonguse"},
+		{oidc.Claims{Email: "@@"}, "user"},
+	}
+
+	for _, tt := range tests {
+		if got := usernameFromClaims(&tt.claims); got != tt.want {
+			t.Errorf("usernameFromClaims(%+v) = %q, want %q", tt.claims, got, tt.want)
+		}
+	}
+}
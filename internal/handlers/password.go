@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== PASSWORD =====================
+//
+
+// POST /api/password/change
+// Body: {"current_password": "...", "new_password": "..."}
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if utils.VerifyPassword(user.PasswordHash, req.CurrentPassword) != nil {
+		http.Error(w, "current password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	if err := utils.ValidatePasswordStrength(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repos.Users.UpdatePassword(r.Context(), userID, hash); err != nil {
+		http.Error(w, "failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	// остальные устройства должны войти заново, текущая сессия остаётся
+	if cookie, cerr := r.Cookie("session_id"); cerr == nil {
+		err = h.repos.Sessions.DeleteOtherSessions(r.Context(), userID, cookie.Value)
+	} else {
+		_, err = h.repos.Sessions.DeleteUserSessions(r.Context(), userID)
+	}
+	if err != nil {
+		log.Printf("ERROR: sign out other sessions of user %d: %v", userID, err)
+		http.Error(w, "failed to sign out other sessions", http.StatusInternalServerError)
+		return
+	}
+	// сокеты закрываются у всех вкладок; текущая переподключится со своей сессией
+	h.hub.disconnect <- userID
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/password/forgot
+// Body: {"email": "..."}. Always answers 202 so the endpoint cannot be used
+// to find out which addresses are registered.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.repos.Users.GetByEmail(r.Context(), strings.TrimSpace(req.Email))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("ERROR: forgot password lookup: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		http.Error(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	// новая ссылка отменяет все предыдущие
+	h.repos.Tokens.InvalidateTokens(r.Context(), user.ID, database.TokenPasswordReset)
+
+	expiresAt := time.Now().Add(h.cfg.PasswordResetTTL)
+	if err := h.repos.Tokens.CreateToken(r.Context(), user.ID, database.TokenPasswordReset, utils.HashToken(token), expiresAt); err != nil {
+		http.Error(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.cfg.BaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf(
+		"Hi %s,\n\nSomeone asked to reset the password of your %s account.\n"+
+			"Open this link to choose a new one (valid for %s):\n\n%s\n\n"+
+			"If it wasn't you, just ignore this message.",
+		user.Username, h.cfg.SiteName, h.cfg.PasswordResetTTL, link,
+	)
+	if err := h.mailer.Send(user.Email, "Reset your password", body); err != nil {
+		log.Printf("ERROR: failed to send password reset mail to user %d: %v", user.ID, err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// POST /api/password/reset
+// Body: {"token": "...", "new_password": "..."}
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// проверяем пароль до того, как потратить одноразовый токен
+	if err := utils.ValidatePasswordStrength(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.repos.Tokens.ConsumeToken(r.Context(), database.TokenPasswordReset, utils.HashToken(req.Token))
+	if err == database.ErrInvalidToken {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repos.Users.UpdatePassword(r.Context(), userID, hash); err != nil {
+		http.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	// выкидываем все сессии, API-токены и сокеты, снимаем блокировку входа
+	if _, err := h.repos.Sessions.DeleteUserSessions(r.Context(), userID); err != nil {
+		log.Printf("ERROR: reset password: delete sessions of user %d: %v", userID, err)
+		http.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.repos.APITokens.DeleteUserAPITokens(r.Context(), userID); err != nil {
+		log.Printf("ERROR: reset password: delete api tokens of user %d: %v", userID, err)
+		http.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.repos.LoginAttempts.ClearLoginAttempts(r.Context(), middleware.AccountKey(userID)); err != nil {
+		log.Printf("ERROR: reset password: clear login attempts of user %d: %v", userID, err)
+	}
+	h.hub.disconnect <- userID
+
+	w.WriteHeader(http.StatusNoContent)
+}
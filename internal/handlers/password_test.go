@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+// setPassword gives userID a real password hash
+func setPassword(t *testing.T, h *testHandler, userID int, password string) {
+	t.Helper()
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if _, err := h.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", hash, userID); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+}
+
+func countRows(t *testing.T, h *testHandler, table string, userID int) int {
+	t.Helper()
+	var n int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", userID).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestChangePassword(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	setPassword(t, h, alice, "correct horse")
+	current := withUser(httptest.NewRequest(http.MethodGet, "/api/me", nil), h, alice)
+	// CreateSession оставляет одну сессию, второе устройство добавляем руками
+	if _, err := h.db.Exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('other-device', ?, datetime('now', '+1 hour'))", alice); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+	other := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	other.AddCookie(&http.Cookie{Name: "session_id", Value: "other-device"})
+
+	change := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/password/change", strings.NewReader(body))
+		for _, c := range current.Cookies() {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		h.ChangePassword(rec, req)
+		return rec
+	}
+
+	if rec := change(`{"current_password": "wrong", "new_password": "battery staple"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong current password: status %d, want 403", rec.Code)
+	}
+	if rec := change(`{"current_password": "correct horse", "new_password": "short"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("weak new password: status %d, want 400", rec.Code)
+	}
+	if _, err := middleware.GetUserIDFromSession(other, h.repos); err != nil {
+		t.Fatalf("rejected changes signed other devices out: %v", err)
+	}
+
+	rec := change(`{"current_password": "correct horse", "new_password": "battery staple"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("change: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := middleware.GetUserIDFromSession(current, h.repos); err != nil {
+		t.Errorf("current session was signed out: %v", err)
+	}
+	if _, err := middleware.GetUserIDFromSession(other, h.repos); err == nil {
+		t.Error("other session survived the password change")
+	}
+	if rec := tryLogin(h, "alice", "battery staple"); rec.Code != http.StatusOK {
+		t.Errorf("login with the new password: status %d", rec.Code)
+	}
+}
+
+var resetLink = regexp.MustCompile(`/reset-password\?token=(\S+)`)
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	h := setupTestHandler(t, nil)
+	mail := withRecordingMailer(h)
+	alice := createTestUser(t, h, "alice")
+	setPassword(t, h, alice, "correct horse")
+	session := withUser(httptest.NewRequest(http.MethodGet, "/api/me", nil), h, alice)
+	if _, err := database.CreateAPIToken(ctx, h.db, alice, "bot", utils.HashToken("secret"), []string{"read"}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	forgot := func(email string) int {
+		rec := httptest.NewRecorder()
+		h.ForgotPassword(rec, httptest.NewRequest(http.MethodPost, "/api/password/forgot", strings.NewReader(`{"email": "`+email+`"}`)))
+		return rec.Code
+	}
+	reset := func(token, password string) *httptest.ResponseRecorder {
+		body := `{"token": "` + token + `", "new_password": "` + password + `"}`
+		rec := httptest.NewRecorder()
+		h.ResetPassword(rec, httptest.NewRequest(http.MethodPost, "/api/password/reset", strings.NewReader(body)))
+		return rec
+	}
+
+	// по ответу нельзя узнать, зарегистрирован ли адрес
+	if code := forgot("nobody@example.com"); code != http.StatusAccepted {
+		t.Fatalf("forgot for an unknown email: status %d, want 202", code)
+	}
+	if n := len(mail.messages()); n != 0 {
+		t.Fatalf("unknown email got %d messages", n)
+	}
+
+	if code := forgot("ALICE@example.com"); code != http.StatusAccepted {
+		t.Fatalf("forgot: status %d, want 202", code)
+	}
+	sent := mail.messages()
+	if len(sent) != 1 || sent[0].To != "alice@example.com" {
+		t.Fatalf("sent = %+v, want one message to alice", sent)
+	}
+	m := resetLink.FindStringSubmatch(sent[0].Body)
+	if m == nil {
+		t.Fatalf("no reset link in %q", sent[0].Body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+
+	// слабый пароль не тратит токен
+	if rec := reset(token, "short"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("weak password: status %d, want 400", rec.Code)
+	}
+	if rec := reset(token, "battery staple"); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := reset(token, "another staple"); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused token: status %d, want 400", rec.Code)
+	}
+
+	if _, err := middleware.GetUserIDFromSession(session, h.repos); err == nil {
+		t.Error("session survived the reset")
+	}
+	if n := countRows(t, h, "sessions", alice); n != 0 {
+		t.Errorf("%d sessions left after the reset", n)
+	}
+	if n := countRows(t, h, "api_tokens", alice); n != 0 {
+		t.Errorf("%d api tokens left after the reset", n)
+	}
+	if rec := tryLogin(h, "alice", "battery staple"); rec.Code != http.StatusOK {
+		t.Errorf("login with the new password: status %d", rec.Code)
+	}
+
+	// просроченный токен не принимается
+	if err := database.CreateUserToken(ctx, h.db, alice, database.TokenPasswordReset, utils.HashToken("expired"), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+	if rec := reset("expired", "battery staple"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expired token: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== PRIVACY =====================
+//
+
+// GET   /api/me/privacy
+// PATCH /api/me/privacy — any of name_visibility, age_visibility, gender_visibility,
+// dm_policy, hide_blocked_content
+func (h *Handler) Privacy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// API-токен может читать настройки, но менять их — только браузерная сессия,
+	// как пароль и 2FA: токен бота не должен открывать владельцу личку
+	getUserID := middleware.GetUserIDFromContextOrSession
+	if r.Method == http.MethodPatch {
+		getUserID = middleware.GetUserIDFromSession
+	}
+	userID, err := getUserID(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.repos.Users.GetPrivacySettings(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load privacy settings", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPatch {
+		// decoding over the current settings keeps omitted fields unchanged
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(settings); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		if ok, msg := utils.ValidatePrivacySettings(*settings); !ok {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if err := h.repos.Users.UpdatePrivacySettings(r.Context(), userID, settings); err != nil {
+			http.Error(w, "failed to update privacy settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// applyProfilePrivacy blanks the fields p's owner hides from the viewer
+// (viewerID 0 = guest). Owners always see their own profile in full.
+func applyProfilePrivacy(p *models.UserProfile, s *models.PrivacySettings, viewerID int) {
+	if viewerID == p.ID {
+		return
+	}
+
+	visible := func(level string) bool {
+		switch level {
+		case models.VisibilityPublic:
+			return true
+		case models.VisibilityMembers:
+			return viewerID > 0
+		}
+		return false
+	}
+
+	if !visible(s.NameVisibility) {
+		p.FirstName, p.LastName = "", ""
+		p.HiddenFields = append(p.HiddenFields, "first_name", "last_name")
+	}
+	if !visible(s.AgeVisibility) {
+		p.Age = 0
+		p.HiddenFields = append(p.HiddenFields, "age")
+	}
+	if !visible(s.GenderVisibility) {
+		p.Gender = ""
+		p.HiddenFields = append(p.HiddenFields, "gender")
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestProfilePrivacy(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	h.db.Exec("UPDATE users SET first_name = 'Alice', last_name = 'Liddell' WHERE id = ?", alice)
+
+	setPrivacy := func(body string) {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/me/privacy", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.Privacy(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PATCH privacy status = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	view := func(viewer int) models.UserProfile {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/"+strconv.Itoa(alice)+"/profile", nil)
+		if viewer != 0 {
+			req = withUser(req, h, viewer)
+		}
+		rec := httptest.NewRecorder()
+		h.UserProfile(rec, req)
+		var p models.UserProfile
+		json.NewDecoder(rec.Body).Decode(&p)
+		return p
+	}
+
+	// Default: members only
+	if p := view(0); p.FirstName != "" || p.Age != 0 || p.Gender != "" || len(p.HiddenFields) != 4 {
+		t.Errorf("guest sees %+v", p)
+	}
+	if p := view(bob); p.FirstName != "Alice" || p.Age != 30 {
+		t.Errorf("member sees %+v", p)
+	}
+
+	setPrivacy(`{"name_visibility": "public", "age_visibility": "nobody"}`)
+
+	if p := view(0); p.FirstName != "Alice" || p.LastName != "Liddell" || p.Gender != "" {
+		t.Errorf("guest sees %+v", p)
+	}
+	if p := view(bob); p.Age != 0 || p.Gender != "other" {
+		t.Errorf("member sees %+v", p)
+	}
+	if p := view(alice); p.Age != 30 || len(p.HiddenFields) != 0 {
+		t.Errorf("owner sees %+v", p)
+	}
+
+	for _, body := range []string{`{"age_visibility": "friends"}`, `{"dm_policy": "nobody"}`, `{"email": "x"}`} {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/me/privacy", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.Privacy(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+// dialWS opens a chat connection as userID
+func dialWS(t *testing.T, h *testHandler, server *httptest.Server, userID int) *websocket.Conn {
+	t.Helper()
+	req := withUser(httptest.NewRequest(http.MethodGet, "/ws", nil), h, userID)
+
+	header := http.Header{}
+	header.Set("Cookie", req.Header.Get("Cookie"))
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// nextFrame returns the next frame of one of the given types, skipping presence noise
+func nextFrame(t *testing.T, conn *websocket.Conn, types ...string) WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %v: %v", types, err)
+		}
+		for _, typ := range types {
+			if msg["type"] == typ {
+				return msg
+			}
+		}
+	}
+}
+
+func TestPrivacyChangesNeedSession(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	raw := middleware.APITokenPrefix + "poster"
+	if _, err := database.CreateAPIToken(context.Background(), h.db, alice, "bot", utils.HashToken(raw), []string{middleware.ScopeRead, middleware.ScopePost}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/me/privacy", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+	h.Privacy(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET with a token: status %d, want 200", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/api/me/privacy", strings.NewReader(`{"dm_policy": "nobody"}`))
+	req.Header.Set("Authorization", "Bearer "+raw)
+	h.Privacy(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("PATCH with a post-scoped token: status %d, want 401", rec.Code)
+	}
+	if s, _ := database.GetPrivacySettings(context.Background(), h.db, alice); s.DMPolicy != models.DMEveryone {
+		t.Errorf("dm_policy = %q after the rejected PATCH", s.DMPolicy)
+	}
+}
+
+func TestDMPolicyContacts(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.RequireVerifiedEmail = false
+	})
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	h.db.Exec("UPDATE users SET dm_policy = 'contacts' WHERE id = ?", alice)
+
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+
+	aliceConn := dialWS(t, h, server, alice)
+	bobConn := dialWS(t, h, server, bob)
+
+	send := func(conn *websocket.Conn, to int, content string) {
+		if err := conn.WriteJSON(WSMessage{"type": "message", "to": to, "content": content}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// A stranger is rejected
+	send(bobConn, alice, "hi alice")
+	if msg := nextFrame(t, bobConn, "message", "error"); msg["type"] != "error" {
+		t.Fatalf("stranger's message went through: %v", msg)
+	}
+
+	// alice writes first, which makes bob a contact
+	send(aliceConn, bob, "hi bob")
+	if msg := nextFrame(t, bobConn, "message", "error"); msg["type"] != "message" || msg["content"] != "hi bob" {
+		t.Fatalf("bob got %v", msg)
+	}
+	nextFrame(t, aliceConn, "message") // echo of alice's own message
+
+	send(bobConn, alice, "hello again")
+	if msg := nextFrame(t, aliceConn, "message", "error"); msg["content"] != "hello again" {
+		t.Fatalf("alice got %v", msg)
+	}
+}
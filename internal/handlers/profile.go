@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== PROFILES =====================
+//
+
+// how many posts / comments the profile page shows
+const profileRecentLimit = 5
+
+// GET /api/users/{id}/profile
+// Real name, age and gender are filtered by the owner's privacy settings.
+func (h *Handler) UserProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[3] != "profile" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.repos.Profiles.GetProfile(r.Context(), id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := h.repos.Users.GetPrivacySettings(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	viewerID, _ := middleware.GetUserIDFromContextOrSession(r, h.repos)
+	applyProfilePrivacy(profile, settings, viewerID)
+
+	if profile.Stats, err = h.repos.Profiles.GetStats(r.Context(), id); err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	if profile.RecentPosts, err = h.repos.Profiles.RecentPosts(r.Context(), id, profileRecentLimit); err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	if profile.RecentComments, err = h.repos.Profiles.RecentComments(r.Context(), id, profileRecentLimit); err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// PATCH /api/me
+// Body: any of first_name, last_name, age, gender, bio, avatar_url; omitted fields stay as they are.
+// Only a browser session may edit the profile, API tokens are refused.
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		FirstName *string `json:"first_name"`
+		LastName  *string `json:"last_name"`
+		Age       *int    `json:"age"`
+		Gender    *string `json:"gender"`
+		Bio       *string `json:"bio"`
+		AvatarURL *string `json:"avatar_url"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.repos.Profiles.GetProfile(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	if req.FirstName != nil {
+		profile.FirstName = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		profile.LastName = strings.TrimSpace(*req.LastName)
+	}
+	if req.Age != nil {
+		profile.Age = *req.Age
+	}
+	if req.Gender != nil {
+		profile.Gender = *req.Gender
+	}
+	if req.Bio != nil {
+		profile.Bio = strings.TrimSpace(*req.Bio)
+	}
+	if req.AvatarURL != nil {
+		profile.AvatarURL = strings.TrimSpace(*req.AvatarURL)
+	}
+
+	if ok, msg := utils.ValidateProfileData(profile.FirstName, profile.LastName, profile.Gender, profile.Bio, profile.AvatarURL, profile.Age); !ok {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repos.Profiles.UpdateProfile(r.Context(), profile); err != nil {
+		http.Error(w, "failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"first_name": profile.FirstName,
+		"last_name":  profile.LastName,
+		"age":        profile.Age,
+		"gender":     profile.Gender,
+		"bio":        profile.Bio,
+		"avatar_url": profile.AvatarURL,
+	})
+}
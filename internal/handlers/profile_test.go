@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+func TestUserProfile(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+
+	var postIDs []int64
+	for i := 0; i < 7; i++ {
+		res, err := h.db.Exec("INSERT INTO posts (user_id, title, content) VALUES (?, ?, 'some content here')", alice, "Post "+strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := res.LastInsertId()
+		postIDs = append(postIDs, id)
+	}
+	h.db.Exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'nice')", postIDs[0], alice)
+	h.db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'like')", postIDs[1], alice)
+	h.db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'dislike')", postIDs[2], alice)
+	h.db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'love')", postIDs[3], alice) // neither a like nor a dislike
+	h.db.Exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'like')", postIDs[1], bob)
+
+	rec := httptest.NewRecorder()
+	h.UserProfile(rec, httptest.NewRequest(http.MethodGet, "/api/users/"+strconv.Itoa(alice)+"/profile", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "alice@example.com") {
+		t.Error("public profile leaks the e-mail address")
+	}
+
+	var p models.UserProfile
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	want := models.UserStats{Posts: 7, Comments: 1, LikesGiven: 1, DislikesGiven: 1}
+	if p.Username != "alice" || p.Stats != want {
+		t.Errorf("profile = %+v, stats want %+v", p, want)
+	}
+	if len(p.RecentPosts) != profileRecentLimit {
+		t.Errorf("recent posts = %d, want %d", len(p.RecentPosts), profileRecentLimit)
+	}
+	if len(p.RecentComments) != 1 || p.RecentComments[0].PostTitle != "Post 0" {
+		t.Errorf("recent comments = %+v", p.RecentComments)
+	}
+
+	for path, code := range map[string]int{
+		"/api/users/999/profile": http.StatusNotFound,
+		"/api/users/abc/profile": http.StatusBadRequest,
+		"/api/users/1/other":     http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.UserProfile(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, code)
+		}
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.Me(rec, req)
+		return rec
+	}
+
+	rec := patch(`{"bio": "  Go developer  ", "avatar_url": "https://example.com/me.png", "first_name": "Alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Omitted fields keep their values
+	rec = patch(`{"last_name": "Liddell"}`)
+	var p models.UserProfile
+	json.NewDecoder(rec.Body).Decode(&p)
+	if p.Bio != "Go developer" || p.AvatarURL != "https://example.com/me.png" || p.FirstName != "Alice" ||
+		p.LastName != "Liddell" || p.Age != 30 || p.Gender != "other" {
+		t.Errorf("profile after partial update = %+v", p)
+	}
+
+	for _, body := range []string{
+		`{"age": 7}`,
+		`{"gender": "robot"}`,
+		`{"bio": "` + strings.Repeat("x", 501) + `"}`,
+		`{"avatar_url": "javascript:alert(1)"}`,
+		`{"avatar_url": "https://example.com/page.html"}`,
+		`{"first_name": "` + strings.Repeat("x", 51) + `"}`,
+		`{"email": "new@example.com"}`,
+		`not json`,
+	} {
+		if rec := patch(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.Me(rec, httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(`{"bio": "x"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous PATCH status = %d, want 401", rec.Code)
+	}
+
+	// a token that may post still can't rewrite the profile
+	raw := middleware.APITokenPrefix + "poster"
+	if _, err := database.CreateAPIToken(context.Background(), h.db, alice, "bot", utils.HashToken(raw), []string{middleware.ScopeRead, middleware.ScopePost}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(`{"bio": "pwned"}`))
+	req.Header.Set("Authorization", "Bearer "+raw)
+	h.Me(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("PATCH with a post-scoped token: status = %d, want 401", rec.Code)
+	}
+	if p, _ := database.GetUserProfile(context.Background(), h.db, alice); p.Bio != "Go developer" {
+		t.Errorf("bio = %q after the rejected PATCH", p.Bio)
+	}
+}
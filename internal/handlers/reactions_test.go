@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+)
+
+func TestNamedReactions(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.Reactions = []config.Reaction{{Name: "like", Emoji: "👍"}, {Name: "dislike", Emoji: "👎"}, {Name: "love", Emoji: "❤️"}}
+	})
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	ctx := context.Background()
+	postID, _ := database.CreatePost(ctx, h.db, alice, "Post", "content")
+	commentID, _ := database.CreateComment(ctx, h.db, postID, alice, "comment")
+
+	type state struct {
+		Likes      int            `json:"likes"`
+		Dislikes   int            `json:"dislikes"`
+		Reactions  map[string]int `json:"reactions"`
+		MyReaction string         `json:"my_reaction"`
+	}
+	react := func(handler http.HandlerFunc, path string, user int, body string) (state, *httptest.ResponseRecorder) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, withUser(httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)), h, user))
+		var s state
+		json.Unmarshal(rec.Body.Bytes(), &s)
+		return s, rec
+	}
+
+	post := fmt.Sprintf(`{"post_id": %d, "reaction": "love"}`, postID)
+	if s, rec := react(h.ReactPost, "/api/posts/react", alice, post); rec.Code != http.StatusOK || s.MyReaction != "love" || s.Reactions["love"] != 1 {
+		t.Fatalf("love: status %d, %+v", rec.Code, s)
+	}
+	// the old endpoints are shortcuts for "like" and "dislike"
+	if s, rec := react(h.LikePost, "/api/posts/like", bob, fmt.Sprintf(`{"post_id": %d}`, postID)); rec.Code != http.StatusOK ||
+		s.Likes != 1 || s.MyReaction != "like" || fmt.Sprint(s.Reactions) != "map[like:1 love:1]" {
+		t.Fatalf("like: status %d, %+v", rec.Code, s)
+	}
+	if s, _ := react(h.ReactPost, "/api/posts/react", alice, post); s.MyReaction != "" || fmt.Sprint(s.Reactions) != "map[like:1]" {
+		t.Fatalf("repeated love: %+v, want it taken back", s)
+	}
+
+	comment := func(name string) string { return fmt.Sprintf(`{"comment_id": %d, "reaction": %q}`, commentID, name) }
+	if _, rec := react(h.ReactComment, "/api/comments/react", bob, comment("wow")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reaction outside the set: status %d, want 400", rec.Code)
+	}
+	if _, rec := react(h.ReactComment, "/api/comments/react", bob, `{"comment_id": 999, "reaction": "love"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing comment: status %d, want 404", rec.Code)
+	}
+	if s, rec := react(h.ReactComment, "/api/comments/react", bob, comment("dislike")); rec.Code != http.StatusOK || s.Dislikes != 1 || s.MyReaction != "dislike" {
+		t.Fatalf("comment dislike: status %d, %+v", rec.Code, s)
+	}
+
+	// listings carry the counts and the viewer's own reaction
+	rec := httptest.NewRecorder()
+	h.Comments(rec, withUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/comments?post_id=%d", postID), nil), h, bob))
+	var comments []models.Comment
+	json.NewDecoder(rec.Body).Decode(&comments)
+	if len(comments) != 1 || comments[0].MyReaction != "dislike" || comments[0].Reactions["dislike"] != 1 {
+		t.Fatalf("comments as bob = %+v", comments)
+	}
+	rec = httptest.NewRecorder()
+	h.GetPost(rec, withUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/posts/%d", postID), nil), h, bob))
+	var p models.Post
+	json.NewDecoder(rec.Body).Decode(&p)
+	if p.MyReaction != "like" || p.Likes != 1 {
+		t.Fatalf("post as bob = %+v", p)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Reactions(rec, httptest.NewRequest(http.MethodGet, "/api/reactions", nil))
+	if !strings.Contains(rec.Body.String(), `{"name":"love","emoji":"❤️"}`) {
+		t.Errorf("GET /api/reactions = %s", rec.Body.String())
+	}
+}
+
+func TestReactionUpdatesReachOwnTabs(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	postID, _ := database.CreatePost(context.Background(), h.db, alice, "Post", "content")
+
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+	aliceConn := dialWS(t, h, server, alice)
+	bobTab := dialWS(t, h, server, bob)
+	nextFrame(t, aliceConn, "init")
+	nextFrame(t, bobTab, "init")
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(fmt.Sprintf(`{"post_id": %d, "reaction": "love"}`, postID))
+	h.ReactPost(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/posts/react", body), h, bob))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("react: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// bob's tab gets the broadcast and its own copy, in either order
+	var own WSMessage
+	for i := 0; i < 2 && own == nil; i++ {
+		if msg := nextFrame(t, bobTab, "post_reaction"); msg["my_reaction"] != nil {
+			own = msg
+		}
+	}
+	if own == nil || own["my_reaction"] != "love" || own["likes"] != float64(0) {
+		t.Fatalf("bob's own update = %v", own)
+	}
+
+	msg := nextFrame(t, aliceConn, "post_reaction")
+	if _, ok := msg["my_reaction"]; ok || msg["user_id"] != nil {
+		t.Errorf("broadcast to alice = %v, want no personal fields", msg)
+	}
+	if reactions, _ := msg["reactions"].(map[string]interface{}); reactions["love"] != float64(1) {
+		t.Errorf("broadcast counts = %v", msg["reactions"])
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"real-time-forum/internal/models"
+)
+
+func TestPostTagsAndFilters(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	h.db.Exec("UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ?", alice)
+
+	// categories 1 and 2 are seeded by the migrations
+	create := func(body string) (int, *httptest.ResponseRecorder) {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/posts/create", strings.NewReader(body)), h, alice)
+		rec := httptest.NewRecorder()
+		h.CreatePost(rec, req)
+		var res struct{ ID int }
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return res.ID, rec
+	}
+	goJobs, rec := create(`{"title": "Go jobs", "content": "Some content that is long enough", "categories": ["1"], "tags": ["#Go", "Remote Work", "go"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body.String())
+	}
+	goTalk, _ := create(`{"title": "Go talk", "content": "Some content that is long enough", "categories": ["2"], "tags": ["go"]}`)
+	other, _ := create(`{"title": "Other post", "content": "Some content that is long enough", "categories": ["1"]}`)
+
+	if _, rec := create(`{"title": "Too many", "content": "Some content that is long enough", "categories": ["1"], "tags": ["a1", "a2", "a3", "a4", "a5", "a6"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("6 tags: status %d, want 400", rec.Code)
+	}
+	if _, rec := create(`{"title": "Bad tag post", "content": "Some content that is long enough", "categories": ["1"], "tags": ["?!"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid tag: status %d, want 400", rec.Code)
+	}
+
+	list := func(query string) []int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GetPosts(rec, httptest.NewRequest(http.MethodGet, "/api/posts?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET ?%s: status %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var posts []models.Post
+		json.NewDecoder(rec.Body).Decode(&posts)
+		ids := []int{}
+		for _, p := range posts {
+			if p.ID == goJobs && strings.Join(p.Tags, ",") != "go,remote-work" {
+				t.Fatalf("tags = %q, want go, remote-work", p.Tags)
+			}
+			ids = append(ids, p.ID)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+	same := func(got []int, want ...int) bool {
+		sort.Ints(want)
+		return fmt.Sprint(got) == fmt.Sprint(want)
+	}
+
+	if got := list("tags=GO"); !same(got, goJobs, goTalk) {
+		t.Fatalf("tags=GO -> %v", got)
+	}
+	if got := list("tags=remote-work,missing"); !same(got, goJobs) {
+		t.Fatalf("any of the tags -> %v", got)
+	}
+	if got := list("tags=go&categories=1"); !same(got, goJobs) {
+		t.Fatalf("mode=and -> %v", got)
+	}
+	if got := list("tags=go&categories=1&mode=or"); !same(got, goJobs, goTalk, other) {
+		t.Fatalf("mode=or -> %v", got)
+	}
+	rec = httptest.NewRecorder()
+	h.GetPosts(rec, httptest.NewRequest(http.MethodGet, "/api/posts?tags=go&mode=xor", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad mode: status %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Tags(rec, httptest.NewRequest(http.MethodGet, "/api/tags?prefix=%23G", nil))
+	var tags []models.Tag
+	json.NewDecoder(rec.Body).Decode(&tags)
+	if len(tags) != 1 || tags[0].Name != "go" || tags[0].Count != 2 {
+		t.Fatalf("autocomplete = %+v, want go(2)", tags)
+	}
+}
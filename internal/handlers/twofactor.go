@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== TWO-FACTOR AUTH =====================
+//
+// Like password changes and API token management, these endpoints accept
+// only a browser session: a leaked API token must not weaken the account.
+
+const recoveryCodeCount = 10
+
+// GET /api/2fa
+func (h *Handler) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	state, err := h.repos.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load 2fa state", http.StatusInternalServerError)
+		return
+	}
+	left, _ := h.repos.TwoFactor.CountRecoveryCodes(r.Context(), userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":             state.Enabled,
+		"recovery_codes_left": left,
+	})
+}
+
+// POST /api/2fa/setup
+// Generates a new secret; it only becomes active after /api/2fa/enable.
+func (h *Handler) TwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	state, err := h.repos.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load 2fa state", http.StatusInternalServerError)
+		return
+	}
+	if state.Enabled {
+		http.Error(w, "2fa already enabled", http.StatusConflict)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, "failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+	if err := h.repos.TwoFactor.SetPendingTOTPSecret(r.Context(), userID, secret); err != nil {
+		http.Error(w, "failed to save secret", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(h.cfg.SiteName, user.Email, secret),
+	})
+}
+
+// POST /api/2fa/enable
+// Body: {"code": "123456"}. Returns the recovery codes; they are shown only once.
+func (h *Handler) TwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.repos.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load 2fa state", http.StatusInternalServerError)
+		return
+	}
+	if state.Enabled {
+		http.Error(w, "2fa already enabled", http.StatusConflict)
+		return
+	}
+	if state.Secret == "" {
+		http.Error(w, "run 2fa setup first", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(state.Secret, req.Code, time.Now(), state.LastStep)
+	if !ok {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		http.Error(w, "failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashToken(c)
+	}
+
+	if err := h.repos.TwoFactor.EnableTOTP(r.Context(), userID, step, hashes); err != nil {
+		http.Error(w, "failed to enable 2fa", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+// POST /api/2fa/disable
+// Body: {"password": "...", "code": "123456"} or {"password": "...", "recovery_code": "..."}
+func (h *Handler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if utils.VerifyPassword(user.PasswordHash, req.Password) != nil {
+		http.Error(w, "password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, "failed to check code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "invalid code", http.StatusForbidden)
+		return
+	}
+
+	if err := h.repos.TwoFactor.DisableTOTP(r.Context(), userID); err != nil {
+		http.Error(w, "failed to disable 2fa", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/login/2fa
+// Body: {"challenge": "...", "code": "123456"} or {"challenge": "...", "recovery_code": "..."}
+// Completes a login that answered {"status": "2fa_required"}.
+func (h *Handler) LoginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Challenge    string `json:"challenge"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	challengeHash := utils.HashToken(req.Challenge)
+	userID, err := h.repos.Tokens.PeekToken(r.Context(), database.TokenLogin2FA, challengeHash)
+	if err != nil {
+		http.Error(w, "invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	// коды подбираются так же, как пароли — тот же лимитер и те же ключи
+	limiterKeys := []string{middleware.IPKey(r), middleware.AccountKey(userID)}
+	if wait, err := h.loginLimiter.RetryAfter(r.Context(), limiterKeys...); err != nil {
+		http.Error(w, "login error", http.StatusInternalServerError)
+		return
+	} else if wait > 0 {
+		middleware.SetRetryAfter(w, wait)
+		http.Error(w, "too many login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, "login error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		if wait, lerr := h.loginLimiter.Fail(r.Context(), limiterKeys...); lerr == nil && wait > 0 {
+			middleware.SetRetryAfter(w, wait)
+		}
+		http.Error(w, "invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.repos.Tokens.ConsumeToken(r.Context(), database.TokenLogin2FA, challengeHash); err != nil {
+		http.Error(w, "invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	h.loginLimiter.Reset(r.Context(), limiterKeys[1])
+
+	if err := h.startSession(r.Context(), w, userID); errors.Is(err, errAccountBanned) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "session error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// checkSecondFactor accepts either a fresh TOTP code or an unused recovery code
+func (h *Handler) checkSecondFactor(ctx context.Context, userID int, code, recoveryCode string) (bool, error) {
+	state, err := h.repos.TwoFactor.GetTOTPState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !state.Enabled {
+		return false, nil
+	}
+
+	if recoveryCode != "" {
+		return h.repos.TwoFactor.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	step, ok := utils.ValidateTOTP(state.Secret, code, time.Now(), state.LastStep)
+	if !ok {
+		return false, nil
+	}
+	return h.repos.TwoFactor.AdvanceTOTPStep(ctx, userID, step)
+}
+
+// issueLoginChallenge creates the short-lived token that links the password
+// step of a login to its second factor
+func (h *Handler) issueLoginChallenge(ctx context.Context, userID int) (string, error) {
+	challenge, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(h.cfg.TwoFactorChallengeTTL)
+	if err := h.repos.Tokens.CreateToken(ctx, userID, database.TokenLogin2FA, utils.HashToken(challenge), expiresAt); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/utils"
+)
+
+// enableTOTP turns on 2FA for userID and returns its secret and recovery codes
+func enableTOTP(t *testing.T, h *testHandler, userID int) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := utils.GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashToken(c)
+	}
+	if err := database.SetPendingTOTPSecret(ctx, h.db, userID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.EnableTOTP(ctx, h.db, userID, 0, hashes); err != nil {
+		t.Fatal(err)
+	}
+	return secret, codes
+}
+
+// passwordStep logs in with the right password and returns the 2fa challenge
+func passwordStep(t *testing.T, h *testHandler) string {
+	t.Helper()
+	rec := tryLogin(h, "alice", "correct horse")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" && c.Value != "" {
+			t.Fatal("session created before the second factor")
+		}
+	}
+	var resp struct {
+		Status    string `json:"status"`
+		Challenge string `json:"challenge"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Status != "2fa_required" || resp.Challenge == "" {
+		t.Fatalf("login answered %+v, want 2fa_required with a challenge", resp)
+	}
+	return resp.Challenge
+}
+
+func secondFactor(h *testHandler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.LoginSecondFactor(rec, httptest.NewRequest(http.MethodPost, "/api/login/2fa", strings.NewReader(body)))
+	return rec
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	setPassword(t, h, alice, "correct horse")
+	secret, _ := enableTOTP(t, h, alice)
+
+	challenge := passwordStep(t, h)
+
+	// пароль сам по себе сессию не даёт
+	if rec := secondFactor(h, fmt.Sprintf(`{"challenge": %q, "code": ""}`, challenge)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("empty code: status %d, want 401", rec.Code)
+	}
+
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	rec := secondFactor(h, fmt.Sprintf(`{"challenge": %q, "code": %q}`, challenge, code))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("2fa: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := sessionUserID(t, h, rec); got != alice {
+		t.Errorf("session user = %d, want %d", got, alice)
+	}
+
+	// challenge одноразовый
+	if rec := secondFactor(h, fmt.Sprintf(`{"challenge": %q, "code": %q}`, challenge, code)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused challenge: status %d, want 401", rec.Code)
+	}
+}
+
+func TestLoginWithRecoveryCode(t *testing.T) {
+	h := setupTestHandler(t, nil)
+	alice := createTestUser(t, h, "alice")
+	setPassword(t, h, alice, "correct horse")
+	_, codes := enableTOTP(t, h, alice)
+
+	// код можно ввести без дефиса и заглавными
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	rec := secondFactor(h, fmt.Sprintf(`{"challenge": %q, "recovery_code": %q}`, passwordStep(t, h), typed))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("recovery code: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := sessionUserID(t, h, rec); got != alice {
+		t.Errorf("session user = %d, want %d", got, alice)
+	}
+
+	rec = secondFactor(h, fmt.Sprintf(`{"challenge": %q, "recovery_code": %q}`, passwordStep(t, h), codes[0]))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("used recovery code: status %d, want 401", rec.Code)
+	}
+	if left, _ := database.CountRecoveryCodes(context.Background(), h.db, alice); left != len(codes)-1 {
+		t.Errorf("recovery codes left = %d, want %d", left, len(codes)-1)
+	}
+}
+
+func TestSecondFactorIsThrottled(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.LoginMaxAttempts = 2
+		cfg.LoginLockoutBase = time.Minute
+	})
+	alice := createTestUser(t, h, "alice")
+	setPassword(t, h, alice, "correct horse")
+	secret, _ := enableTOTP(t, h, alice)
+
+	challenge := passwordStep(t, h)
+	// настоящий, но давно истёкший код
+	stale, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now())-100)
+	bad := fmt.Sprintf(`{"challenge": %q, "code": %q}`, challenge, stale)
+
+	for i := 0; i < 2; i++ {
+		if rec := secondFactor(h, bad); rec.Code != http.StatusUnauthorized || rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("free bad code #%d: status %d, Retry-After %q", i+1, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if rec := secondFactor(h, bad); rec.Code != http.StatusUnauthorized || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("bad code over the limit: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// пока аккаунт заблокирован, не проходит и верный код
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	rec := secondFactor(h, fmt.Sprintf(`{"challenge": %q, "code": %q}`, challenge, code))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("valid code while locked: status %d, want 429 with Retry-After", rec.Code)
+	}
+	// и новый вход паролем тоже
+	if rec := tryLogin(h, "alice", "correct horse"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("password login while locked: status %d, want 429", rec.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/middleware"
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
+)
+
+//
+// ===================== EMAIL VERIFICATION =====================
+//
+
+// GET /api/verify?token=...
+// Browsers following the e-mail link are redirected back to the SPA.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	wantsHTML := strings.Contains(r.Header.Get("Accept"), "text/html")
+
+	userID, err := h.repos.Tokens.ConsumeToken(r.Context(), database.TokenEmailVerify, utils.HashToken(token))
+	if err == database.ErrInvalidToken {
+		if wantsHTML {
+			http.Redirect(w, r, "/?verified=0", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repos.Users.MarkEmailVerified(r.Context(), userID); err != nil {
+		http.Error(w, "failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	if wantsHTML {
+		http.Redirect(w, r, "/?verified=1", http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"verified": true})
+}
+
+// POST /api/verify/resend
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContextOrSession(r, h.repos)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if verified, err := h.repos.Users.IsEmailVerified(r.Context(), userID); err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return
+	} else if verified {
+		http.Error(w, "email already verified", http.StatusConflict)
+		return
+	}
+
+	last, err := h.repos.Tokens.LatestTokenAt(r.Context(), userID, database.TokenEmailVerify)
+	if err != nil {
+		http.Error(w, "failed to resend", http.StatusInternalServerError)
+		return
+	}
+	if wait := h.cfg.VerifyResendInterval - time.Since(last); !last.IsZero() && wait > 0 {
+		middleware.SetRetryAfter(w, wait)
+		http.Error(w, "verification email was sent recently", http.StatusTooManyRequests)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+		http.Error(w, "failed to resend", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendVerificationEmail issues a fresh token (older ones stop working) and mails the link
+func (h *Handler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	h.repos.Tokens.InvalidateTokens(ctx, user.ID, database.TokenEmailVerify)
+
+	expiresAt := time.Now().Add(h.cfg.EmailVerifyTTL)
+	if err := h.repos.Tokens.CreateToken(ctx, user.ID, database.TokenEmailVerify, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/verify?token=%s", h.cfg.BaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf(
+		"Hi %s,\n\nWelcome to %s! Please confirm your e-mail address by opening this link (valid for %s):\n\n%s\n",
+		user.Username, h.cfg.SiteName, h.cfg.EmailVerifyTTL, link,
+	)
+	if err := h.mailer.Send(user.Email, "Confirm your e-mail address", body); err != nil {
+		log.Printf("ERROR: failed to send verification mail to user %d: %v", user.ID, err)
+		return err
+	}
+	return nil
+}
+
+// requireVerifiedEmail answers 403 and returns false when unverified users
+// are restricted and userID has not confirmed their address yet.
+func (h *Handler) requireVerifiedEmail(ctx context.Context, w http.ResponseWriter, userID int) bool {
+	if !h.cfg.RequireVerifiedEmail {
+		return true
+	}
+
+	verified, err := h.repos.Users.IsEmailVerified(ctx, userID)
+	if err != nil {
+		http.Error(w, "failed to load user", http.StatusInternalServerError)
+		return false
+	}
+	if !verified {
+		http.Error(w, "email not verified", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/utils"
+)
+
+var verifyLink = regexp.MustCompile(`/api/verify\?token=(\S+)`)
+
+func resendVerification(h *testHandler, userID int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ResendVerification(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/verify/resend", nil), h, userID))
+	return rec
+}
+
+func verifyEmail(h *testHandler, token, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/verify?token="+url.QueryEscape(token), nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.VerifyEmail(rec, req)
+	return rec
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	h := setupTestHandler(t, nil)
+	mail := withRecordingMailer(h)
+	alice := createTestUser(t, h, "alice")
+
+	if rec := resendVerification(h, alice); rec.Code != http.StatusAccepted {
+		t.Fatalf("resend: status %d: %s", rec.Code, rec.Body.String())
+	}
+	sent := mail.messages()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	m := verifyLink.FindStringSubmatch(sent[0].Body)
+	if m == nil {
+		t.Fatalf("no verification link in %q", sent[0].Body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+
+	if rec := verifyEmail(h, token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("verify: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if ok, _ := database.IsEmailVerified(ctx, h.db, alice); !ok {
+		t.Fatal("email not marked verified")
+	}
+
+	// ссылка одноразовая; браузер возвращается в SPA с результатом
+	if rec := verifyEmail(h, token, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused token: status %d, want 400", rec.Code)
+	}
+	if rec := verifyEmail(h, token, "text/html"); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/?verified=0" {
+		t.Errorf("reused token in a browser: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	bob := createTestUser(t, h, "bob")
+	if err := database.CreateUserToken(ctx, h.db, bob, database.TokenEmailVerify, utils.HashToken("expired"), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateUserToken: %v", err)
+	}
+	if rec := verifyEmail(h, "expired", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expired token: status %d, want 400", rec.Code)
+	}
+	if ok, _ := database.IsEmailVerified(ctx, h.db, bob); ok {
+		t.Error("expired token verified the email")
+	}
+}
+
+func TestResendVerificationThrottled(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.VerifyResendInterval = time.Minute
+	})
+	mail := withRecordingMailer(h)
+	alice := createTestUser(t, h, "alice")
+
+	if rec := resendVerification(h, alice); rec.Code != http.StatusAccepted {
+		t.Fatalf("first resend: status %d", rec.Code)
+	}
+	rec := resendVerification(h, alice)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second resend: status %d, Retry-After %q; want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if n := len(mail.messages()); n != 1 {
+		t.Errorf("sent %d messages, want 1", n)
+	}
+
+	h.db.Exec("UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ?", alice)
+	if rec := resendVerification(h, alice); rec.Code != http.StatusConflict {
+		t.Errorf("resend for a verified address: status %d, want 409", rec.Code)
+	}
+}
+
+func TestUnverifiedUserCannotPostOrWrite(t *testing.T) {
+	h := setupTestHandler(t, func(cfg *config.Config) {
+		cfg.RequireVerifiedEmail = true
+	})
+	alice := createTestUser(t, h, "alice")
+	bob := createTestUser(t, h, "bob")
+	categories, _ := database.GetAllCategories(context.Background(), h.db, false)
+
+	body := `{"title": "A valid title", "content": "Some content that is long enough", "categories": ["` + strconv.Itoa(categories[0].ID) + `"]}`
+	rec := httptest.NewRecorder()
+	h.CreatePost(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/posts/create", strings.NewReader(body)), h, alice))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("post by an unverified user: status %d, want 403", rec.Code)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+	conn := dialWS(t, h, server, alice)
+	if err := conn.WriteJSON(WSMessage{"type": "message", "to": bob, "content": "hi bob"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if msg := nextFrame(t, conn, "message", "error"); msg["type"] != "error" || msg["message"] != "email not verified" {
+		t.Fatalf("alice got %v, want an email not verified error", msg)
+	}
+	var stored int
+	h.db.QueryRow("SELECT COUNT(*) FROM messages WHERE from_user = ?", alice).Scan(&stored)
+	if stored != 0 {
+		t.Fatalf("%d messages from an unverified user were stored", stored)
+	}
+
+	// после подтверждения то же соединение может писать
+	h.db.Exec("UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ?", alice)
+	if err := conn.WriteJSON(WSMessage{"type": "message", "to": bob, "content": "hi again"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if msg := nextFrame(t, conn, "message", "error"); msg["type"] != "message" {
+		t.Fatalf("alice got %v after verifying, want her message echoed", msg)
+	}
+}
@@ -1,17 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"real-time-forum/internal/database"
+	"real-time-forum/internal/config"
 	"real-time-forum/internal/middleware"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/services"
 
 	"github.com/gorilla/websocket"
 )
@@ -22,6 +27,11 @@ type Client struct {
 	userID int
 	conn   *websocket.Conn
 	send   chan WSMessage
+
+	// inbound frame quota; only touched by this client's readerLoop
+	bucket middleware.TokenBucket
+	// cached once the user is known to have a verified e-mail
+	verified bool
 }
 
 type Hub struct {
@@ -34,17 +44,34 @@ type Hub struct {
 
 	nextGuestID int
 	disconnect  chan int
+
+	upgrader        websocket.Upgrader
+	allowedOrigins  []string
+	messageLimit    config.RateLimit
+	requireVerified bool
+
+	chat services.ChatService
 }
 
-func NewHub() *Hub {
-	return &Hub{
-		clients:     make(map[int]map[*Client]struct{}),
-		presence:    make(map[int]models.User),
-		broadcast:   make(chan WSMessage, 64),
-		presenceCh:  make(chan WSMessage, 64),
-		nextGuestID: -1,
-		disconnect:  make(chan int),
+func NewHub(cfg *config.Config) *Hub {
+	h := &Hub{
+		clients:        make(map[int]map[*Client]struct{}),
+		presence:       make(map[int]models.User),
+		broadcast:      make(chan WSMessage, 64),
+		presenceCh:     make(chan WSMessage, 64),
+		nextGuestID:    -1,
+		disconnect:     make(chan int),
+		allowedOrigins: cfg.AllowedOrigins,
+		messageLimit:   cfg.WSMessageLimit,
+
+		requireVerified: cfg.RequireVerifiedEmail,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
 	}
+	return h
 }
 
 /* ===================== CLIENT MGMT ===================== */
@@ -131,6 +158,19 @@ func (h *Hub) Broadcast(msg WSMessage) {
 	}
 }
 
+// SendToUser delivers msg to every connection (tab) of one user.
+func (h *Hub) SendToUser(userID int, msg WSMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients[userID] {
+		select {
+		case c.send <- msg:
+		default:
+			// не блокируемся
+		}
+	}
+}
+
 func (h *Hub) BroadcastPresence(userID int, nickname string, status string) {
 	msg := WSMessage{
 		"type":    "presence",
@@ -195,17 +235,37 @@ func (h *Hub) dispatch(msg WSMessage) {
 
 /* ===================== WS SETUP ===================== */
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true },
+// checkOrigin accepts same-origin browsers, configured extra origins and
+// non-browser clients that send no Origin header at all.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range h.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	log.Printf("ws origin rejected: %s", origin)
+	return false
 }
 
-func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, db *sql.DB) {
-	userID, err := middleware.GetUserIDFromSession(r, db)
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, store *repos.Repos) {
+	userID, err := middleware.GetUserIDFromContextOrSession(r, store)
 	authenticated := err == nil
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("ws upgrade error:", err)
 		return
@@ -213,7 +273,7 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 
 	nickname := ""
 	if authenticated {
-		if user, _ := database.GetUserByID(db, userID); user != nil {
+		if user, _ := store.Users.GetByID(r.Context(), userID); user != nil {
 			nickname = user.Username
 		}
 	} else {
@@ -231,10 +291,9 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 	log.Printf("WS connect user=%d", userID)
 
 	if authenticated && first {
-		db.Exec(
-			"INSERT OR REPLACE INTO presence (user_id, status, nickname, updated_at) VALUES (?, 'online', ?, datetime('now'))",
-			userID, nickname,
-		)
+		if err := store.Presence.SetOnline(r.Context(), userID, nickname); err != nil {
+			log.Printf("ERROR: failed to set user %d online: %v", userID, err)
+		}
 
 		h.BroadcastPresence(userID, nickname, "online")
 
@@ -257,7 +316,9 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 	}
 
 	go h.writerLoop(client)
-	h.readerLoop(client, db)
+	// соединение живёт, пока работает этот обработчик, так что r.Context()
+	// отменяется только при остановке сервера
+	h.readerLoop(r.Context(), client, store)
 }
 
 /* ===================== LOOPS ===================== */
@@ -288,11 +349,14 @@ func (h *Hub) writerLoop(c *Client) {
 	}
 }
 
-func (h *Hub) readerLoop(c *Client, db *sql.DB) {
+func (h *Hub) readerLoop(ctx context.Context, c *Client, store *repos.Repos) {
 	defer func() {
 		offline := h.RemoveClient(c)
 		if c.userID > 0 && offline {
-			db.Exec("UPDATE presence SET status='offline', updated_at=datetime('now') WHERE user_id=?", c.userID)
+			// the user goes offline even when the server is shutting down
+			if err := store.Presence.SetOffline(context.WithoutCancel(ctx), c.userID); err != nil {
+				log.Printf("ERROR: failed to set user %d offline: %v", c.userID, err)
+			}
 			h.BroadcastPresence(c.userID, "", "offline")
 		}
 		c.conn.Close()
@@ -310,6 +374,16 @@ func (h *Hub) readerLoop(c *Client, db *sql.DB) {
 			return
 		}
 
+		if h.messageLimit.Requests > 0 {
+			if ok, _, retry := c.bucket.Take(h.messageLimit, time.Now()); !ok {
+				select {
+				case c.send <- WSMessage{"type": "rate_limited", "retry_after_ms": retry.Milliseconds()}:
+				default:
+				}
+				continue
+			}
+		}
+
 		if c.userID <= 0 {
 			select {
 			case c.send <- WSMessage{"type": "error", "message": "Unauthorized"}:
@@ -325,12 +399,25 @@ func (h *Hub) readerLoop(c *Client, db *sql.DB) {
 				continue
 			}
 
-			if _, err := database.GetUserByID(db, toID); err != nil {
+			if !h.canSendMessages(ctx, c, store) {
+				select {
+				case c.send <- WSMessage{"type": "error", "message": "email not verified"}:
+				default:
+				}
 				continue
 			}
 
-			id, createdAt, err := database.InsertMessage(db, c.userID, toID, content)
-			if err != nil {
+			id, createdAt, err := h.chat.Send(ctx, c.userID, toID, content)
+			switch {
+			case errors.Is(err, services.ErrRecipientBlocked), errors.Is(err, services.ErrMessagesNotAllowed):
+				select {
+				case c.send <- WSMessage{"type": "error", "message": err.Error(), "to": toID}:
+				default:
+				}
+				continue
+			case err != nil:
+				// неизвестный получатель или отправитель в его чёрном списке:
+				// заблокировавший не должен даже узнать о попытке
 				continue
 			}
 
@@ -343,14 +430,7 @@ func (h *Hub) readerLoop(c *Client, db *sql.DB) {
 				"created_at": createdAt,
 			}
 
-			h.mu.RLock()
-			for rc := range h.clients[toID] {
-				select {
-				case rc.send <- payload:
-				default:
-				}
-			}
-			h.mu.RUnlock()
+			h.SendToUser(toID, payload)
 
 			select {
 			case c.send <- payload:
@@ -362,6 +442,19 @@ func (h *Hub) readerLoop(c *Client, db *sql.DB) {
 
 /* ===================== UTILS ===================== */
 
+// canSendMessages applies the verified e-mail restriction to chat messages
+func (h *Hub) canSendMessages(ctx context.Context, c *Client, store *repos.Repos) bool {
+	if !h.requireVerified || c.verified {
+		return true
+	}
+	verified, err := store.Users.IsEmailVerified(ctx, c.userID)
+	if err != nil {
+		return false
+	}
+	c.verified = verified
+	return verified
+}
+
 func parseUserID(v interface{}) (int, bool) {
 	switch x := v.(type) {
 	case float64:
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Mailer delivers outgoing e-mail (password resets, verification links, ...)
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// New returns the mailer selected by kind: "file" writes messages to dir,
+// anything else logs them.
+func New(kind, dir string) Mailer {
+	if kind == "file" {
+		return &FileMailer{Dir: dir}
+	}
+	return LogMailer{}
+}
+
+// LogMailer prints messages to the server log; handy for local development
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("MAIL to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+// FileMailer writes every message as an .eml file into Dir
+type FileMailer struct {
+	Dir string
+
+	mu  sync.Mutex
+	seq int
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (m *FileMailer) Send(to, subject, body string) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %v", err)
+	}
+
+	m.mu.Lock()
+	m.seq++
+	seq := m.seq
+	m.mu.Unlock()
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%03d-%s.eml", now.Format("20060102-150405"), seq, unsafeFileChars.ReplaceAllString(to, "_"))
+	msg := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		to, subject, now.Format(time.RFC1123Z), body)
+
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(msg), 0600)
+}
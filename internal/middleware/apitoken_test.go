@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/utils"
+)
+
+func TestGetUserIDFromContextOrSessionWithAPIToken(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	res, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('bot@example.com', 'bot', 'x')")
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	userID := int(id)
+
+	addToken := func(raw string, scopes []string, expiresAt time.Time) {
+		if _, err := database.CreateAPIToken(context.Background(), db, userID, raw, utils.HashToken(raw), scopes, expiresAt); err != nil {
+			t.Fatalf("CreateAPIToken: %v", err)
+		}
+	}
+	addToken("rtf_reader", []string{ScopeRead}, time.Now().Add(time.Hour))
+	addToken("rtf_poster", []string{ScopeRead, ScopePost}, time.Now().Add(time.Hour))
+	addToken("rtf_chat", []string{ScopeMessage}, time.Now().Add(time.Hour))
+	addToken("rtf_expired", []string{ScopeRead, ScopePost, ScopeMessage}, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		err    error
+	}{
+		{"read scope on GET", http.MethodGet, "/api/posts", "Bearer rtf_reader", nil},
+		{"read scope on POST", http.MethodPost, "/api/posts/create", "Bearer rtf_reader", ErrInsufficientScope},
+		{"post scope on POST", http.MethodPost, "/api/posts/create", "bearer rtf_poster", nil},
+		{"message scope on websocket", http.MethodGet, "/ws", "Bearer rtf_chat", nil},
+		{"message scope on message history", http.MethodGet, "/api/messages", "Bearer rtf_chat", nil},
+		{"read scope on message history", http.MethodGet, "/api/messages", "Bearer rtf_reader", ErrInsufficientScope},
+		{"expired token", http.MethodGet, "/api/posts", "Bearer rtf_expired", ErrInvalidAPIToken},
+		{"unknown token", http.MethodGet, "/api/posts", "Bearer rtf_nope", ErrInvalidAPIToken},
+		{"other scheme", http.MethodGet, "/api/posts", "Basic Ym90Ongp", ErrInvalidAPIToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", tt.auth)
+
+			got, err := GetUserIDFromContextOrSession(req, testStore(db))
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil && got != userID {
+				t.Errorf("user = %d, want %d", got, userID)
+			}
+		})
+	}
+
+	tokens, err := database.ListAPITokens(context.Background(), db, userID)
+	if err != nil {
+		t.Fatalf("ListAPITokens: %v", err)
+	}
+	for _, tok := range tokens {
+		used := tok.LastUsedAt != nil
+		if want := tok.Name == "rtf_reader" || tok.Name == "rtf_poster" || tok.Name == "rtf_chat"; used != want {
+			t.Errorf("token %s: last_used_at set = %v, want %v", tok.Name, used, want)
+		}
+	}
+}
+
+func TestSessionIgnoredWhenBearerPresent(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('a@example.com', 'alice', 'x')"); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if err := CreateSession(context.Background(), rec, testStore(db), 1); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	// A bogus bearer header must not fall back to the cookie: such requests skip CSRF checks
+	req := httptest.NewRequest(http.MethodPost, "/api/password/change", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	req.Header.Set("Authorization", "Bearer junk")
+
+	if _, err := GetUserIDFromSession(req, testStore(db)); err == nil {
+		t.Error("GetUserIDFromSession accepted a cookie on a bearer request")
+	}
+	if _, err := GetUserIDFromContextOrSession(req, testStore(db)); err == nil {
+		t.Error("GetUserIDFromContextOrSession accepted a cookie on a bearer request")
+	}
+}
+
+func TestRequireAuthBearerErrors(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('bot@example.com', 'bot', 'x')"); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if _, err := database.CreateAPIToken(context.Background(), db, 1, "r", utils.HashToken("rtf_reader"), []string{ScopeRead}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	h := RequireAuth(func(w http.ResponseWriter, r *http.Request) {}, testStore(db))
+
+	for auth, want := range map[string]int{
+		"Bearer rtf_reader": http.StatusForbidden, // /api/users needs the message scope
+		"Bearer rtf_nope":   http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", auth, rec.Code, want)
+		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate", auth)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := CSRFProtect(ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie string
+		header string
+		auth   string
+		want   int
+	}{
+		{"GET passes without token", http.MethodGet, "/api/posts", "", "", "", http.StatusOK},
+		{"non-API POST passes", http.MethodPost, "/static/x", "", "", "", http.StatusOK},
+		{"POST without token", http.MethodPost, "/api/posts/create", "", "", "", http.StatusForbidden},
+		{"POST with cookie only", http.MethodPost, "/api/posts/create", "abc", "", "", http.StatusForbidden},
+		{"POST with mismatched header", http.MethodPost, "/api/posts/create", "abc", "abd", "", http.StatusForbidden},
+		{"POST with matching token", http.MethodPost, "/api/posts/create", "abc", "abc", "", http.StatusOK},
+		{"DELETE with matching token", http.MethodDelete, "/api/me", "abc", "abc", "", http.StatusOK},
+		{"POST with bearer token", http.MethodPost, "/api/posts/create", "", "", "Bearer rtf_x", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureCSRFTokenReusesCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	rec := httptest.NewRecorder()
+	token, err := EnsureCSRFToken(rec, req)
+	if err != nil || token == "" {
+		t.Fatalf("EnsureCSRFToken() = %q, %v", token, err)
+	}
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected csrf cookie to be issued")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token})
+	rec = httptest.NewRecorder()
+	again, _ := EnsureCSRFToken(rec, req)
+	if again != token {
+		t.Errorf("expected existing token to be reused, got %q", again)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Errorf("expected no new cookie when one is present")
+	}
+}
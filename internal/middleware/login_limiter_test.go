@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/repos"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupLimiterDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return db
+}
+
+// testStore wraps a test database into the repositories the middleware takes
+func testStore(db *sql.DB) *repos.Repos {
+	return repos.NewSQLiteRepos(repos.NewSQLiteAdapter(db))
+}
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	policy := LoginPolicy{FreeAttempts: 2, BaseLockout: 10 * time.Second, MaxLockout: 35 * time.Second, Window: time.Hour}
+	l := NewLoginLimiter(repos.NewSQLiteAdapter(db), policy, policy)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	key := AccountKey(7)
+	wantLockouts := []time.Duration{0, 0, 10 * time.Second, 20 * time.Second, 35 * time.Second, 35 * time.Second}
+	for i, want := range wantLockouts {
+		got, err := l.Fail(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Fail #%d: %v", i+1, err)
+		}
+		if got != want {
+			t.Errorf("Fail #%d lockout = %v, want %v", i+1, got, want)
+		}
+	}
+
+	wait, err := l.RetryAfter(context.Background(), IPKey(httptest.NewRequest(http.MethodGet, "/", nil)), key)
+	if err != nil {
+		t.Fatalf("RetryAfter: %v", err)
+	}
+	if wait != 35*time.Second {
+		t.Errorf("RetryAfter = %v, want 35s", wait)
+	}
+
+	// lockout expires on its own
+	now = now.Add(36 * time.Second)
+	if wait, _ := l.RetryAfter(context.Background(), key); wait != 0 {
+		t.Errorf("RetryAfter after expiry = %v, want 0", wait)
+	}
+
+	// failures outside the window start a fresh count
+	now = now.Add(2 * time.Hour)
+	if got, _ := l.Fail(context.Background(), key); got != 0 {
+		t.Errorf("Fail after window lockout = %v, want 0", got)
+	}
+
+	if err := l.Reset(context.Background(), key); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if a, _ := database.GetLoginAttempt(context.Background(), db, key); a != nil {
+		t.Errorf("expected counter to be cleared, got %+v", a)
+	}
+}
+
+func TestLoginLimiterSurvivesRestart(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	policy := LoginPolicy{FreeAttempts: 0, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}
+	if _, err := NewLoginLimiter(repos.NewSQLiteAdapter(db), policy, policy).Fail(context.Background(), "ip:10.0.0.1"); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+
+	// a fresh limiter over the same database sees the lockout
+	wait, err := NewLoginLimiter(repos.NewSQLiteAdapter(db), policy, policy).RetryAfter(context.Background(), "ip:10.0.0.1")
+	if err != nil {
+		t.Fatalf("RetryAfter: %v", err)
+	}
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("RetryAfter = %v, want (0, 1m]", wait)
+	}
+}
+
+func TestLoginLimiterConcurrentFailures(t *testing.T) {
+	// файл, а не :memory:, чтобы соединений было несколько, как в проде
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "forum.db")+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	policy := LoginPolicy{FreeAttempts: 3, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}
+	l := NewLoginLimiter(repos.NewSQLiteAdapter(db), policy, policy)
+
+	const attempts = 20
+	key := AccountKey(1)
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.Fail(context.Background(), key); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Fail: %v", err)
+	}
+
+	a, err := database.GetLoginAttempt(context.Background(), db, key)
+	if err != nil || a == nil {
+		t.Fatalf("GetLoginAttempt = %v, %v", a, err)
+	}
+	if a.Failures != attempts {
+		t.Errorf("failures = %d, want %d", a.Failures, attempts)
+	}
+	// самая длинная блокировка не затирается более короткой
+	if want := policy.lockout(attempts); a.LockedUntil.Sub(a.LastFailureAt) < want-time.Second {
+		t.Errorf("locked for %v after %d failures, want %v", a.LockedUntil.Sub(a.LastFailureAt), attempts, want)
+	}
+}
@@ -3,21 +3,27 @@ package middleware
 import (
 	"context"
 	"crypto/rand"
-	"database/sql"
 	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
+
+	"real-time-forum/internal/repos"
 )
 
 type contextKey string
 
 const UserIDKey contextKey = "userID"
 
-// GetUserIDFromSession gets user ID from session cookie
-func GetUserIDFromSession(r *http.Request, db *sql.DB) (int, error) {
+// GetUserIDFromSession gets user ID from session cookie.
+// Requests carrying a bearer token are never authenticated by cookie: they
+// skip the CSRF check, so the cookie must not count for them.
+func GetUserIDFromSession(r *http.Request, store *repos.Repos) (int, error) {
+	if r.Header.Get("Authorization") != "" {
+		return 0, errBearerNotSupported
+	}
+
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		log.Printf("DEBUG: No session cookie found: %v", err)
@@ -26,35 +32,17 @@ func GetUserIDFromSession(r *http.Request, db *sql.DB) (int, error) {
 
 	log.Printf("DEBUG: Checking session with ID: %s", cookie.Value)
 
-	// Берём unix timestamp из expires_at - это надёжнее для сравнения
-	query := "SELECT user_id, strftime('%s', expires_at) FROM sessions WHERE id = ?"
-	var userID int
-	var expiresUnix sql.NullString
-	err = db.QueryRow(query, cookie.Value).Scan(&userID, &expiresUnix)
+	userID, expiresAt, err := store.Sessions.GetSession(r.Context(), cookie.Value)
 	if err != nil {
 		log.Printf("DEBUG: Session not found or error: %v", err)
 		return 0, fmt.Errorf("invalid session")
 	}
 
-	log.Printf("DEBUG: Session found for user %d, expires: %s", userID, expiresUnix.String)
-
-	if !expiresUnix.Valid || expiresUnix.String == "" {
-		// некорректный expires_at — удалим сессию на всякий случай
-		db.Exec("DELETE FROM sessions WHERE id = ?", cookie.Value)
-		return 0, fmt.Errorf("invalid session")
-	}
-
-	// парсим unix seconds
-	expSec, parseErr := strconv.ParseInt(expiresUnix.String, 10, 64)
-	if parseErr != nil {
-		// на случай непредвиденного формата — удаляем
-		db.Exec("DELETE FROM sessions WHERE id = ?", cookie.Value)
-		return 0, fmt.Errorf("invalid session")
-	}
+	log.Printf("DEBUG: Session found for user %d, expires: %v", userID, expiresAt)
 
-	if time.Now().Unix() > expSec {
-		// сессия просрочена
-		db.Exec("DELETE FROM sessions WHERE id = ?", cookie.Value)
+	// просроченная сессия (или с некорректным expires_at) — удаляем
+	if !time.Now().Before(expiresAt) {
+		store.Sessions.DeleteSession(r.Context(), cookie.Value)
 		return 0, fmt.Errorf("session expired")
 	}
 
@@ -62,63 +50,76 @@ func GetUserIDFromSession(r *http.Request, db *sql.DB) (int, error) {
 }
 
 // GetUserIDFromContextOrSession tries to get userID from request context first (set by RequireAuth).
-// If not present, falls back to GetUserIDFromSession (which checks session cookie and DB).
-func GetUserIDFromContextOrSession(r *http.Request, db *sql.DB) (int, error) {
+// If not present, it accepts a personal API token with the scope the request needs
+// (see RequiredScope), and otherwise falls back to GetUserIDFromSession.
+// Endpoints that must stay browser-only call GetUserIDFromSession directly.
+func GetUserIDFromContextOrSession(r *http.Request, store *repos.Repos) (int, error) {
 	if v := r.Context().Value(UserIDKey); v != nil {
 		if id, ok := v.(int); ok && id != 0 {
 			return id, nil
 		}
 	}
-	return GetUserIDFromSession(r, db)
+	if r.Header.Get("Authorization") != "" {
+		token, err := AuthenticateAPIToken(r, store, RequiredScope(r))
+		if err != nil {
+			return 0, err
+		}
+		return token.UserID, nil
+	}
+	return GetUserIDFromSession(r, store)
 }
 
 // IsUserLoggedIn checks if user is logged in
-func IsUserLoggedIn(r *http.Request, db *sql.DB) bool {
-	_, err := GetUserIDFromSession(r, db)
+func IsUserLoggedIn(r *http.Request, store *repos.Repos) bool {
+	_, err := GetUserIDFromSession(r, store)
 	return err == nil
 }
 
 // RequireAuth is middleware that requires authentication
-func RequireAuth(next http.HandlerFunc, db *sql.DB) http.HandlerFunc {
+func RequireAuth(next http.HandlerFunc, store *repos.Repos) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, err := GetUserIDFromSession(r, db)
+		userID, err := GetUserIDFromContextOrSession(r, store)
 		if err != nil {
+			if r.Header.Get("Authorization") != "" {
+				writeBearerError(w, err)
+				return
+			}
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
-		// кладём userID в контекст
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-
-		// передаём дальше с новым контекстом
-		next.ServeHTTP(w, r.WithContext(ctx))
+		// кладём userID в контекст и передаём дальше с новым контекстом
+		next.ServeHTTP(w, r.WithContext(contextWithUserID(r, userID)))
 	}
 }
 
-// CreateSession создаёт новую сессию и удаляет все старые сессии этого пользователя
-func CreateSession(w http.ResponseWriter, db *sql.DB, userID int) error {
-	log.Printf("DEBUG: CreateSession started for user %d", userID)
-	// Стартуем транзакцию для атомарности операций
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("ERROR: Failed to start transaction for user %d: %v", userID, err)
-		return fmt.Errorf("failed to start transaction: %v", err)
-	}
-	defer tx.Rollback() // безопасный откат если что-то пойдет не так
+// RequireAdmin is middleware that only lets users with the admin flag through
+func RequireAdmin(next http.HandlerFunc, store *repos.Repos) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := GetUserIDFromSession(r, store)
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-	// Удаляем ВСЕ существующие сессии этого пользователя
-	log.Printf("DEBUG: Deleting all old sessions for user %d", userID)
-	result, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
-	if err != nil {
-		log.Printf("ERROR: Failed to delete old sessions for user %d: %v", userID, err)
-		return fmt.Errorf("failed to delete old sessions: %v", err)
-	}
+		isAdmin, err := store.Admin.IsAdmin(r.Context(), userID)
+		if err != nil || !isAdmin {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 
-	if rows, _ := result.RowsAffected(); rows > 0 {
-		log.Printf("DEBUG: Deleted %d old sessions for user %d", rows, userID)
-	} else {
-		log.Printf("DEBUG: No old sessions found for user %d", userID)
+		next.ServeHTTP(w, r.WithContext(contextWithUserID(r, userID)))
 	}
+}
+
+// contextWithUserID returns r's context carrying the authenticated user ID
+func contextWithUserID(r *http.Request, userID int) context.Context {
+	return context.WithValue(r.Context(), UserIDKey, userID)
+}
+
+// CreateSession создаёт новую сессию и удаляет все старые сессии этого пользователя
+func CreateSession(ctx context.Context, w http.ResponseWriter, store *repos.Repos, userID int) error {
+	log.Printf("DEBUG: CreateSession started for user %d", userID)
 
 	// генерируем случайный ID для новой сессии
 	b := make([]byte, 16)
@@ -132,23 +133,12 @@ func CreateSession(w http.ResponseWriter, db *sql.DB, userID int) error {
 	// срок действия 1 день
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	// вставляем новую сессию
-	log.Printf("DEBUG: Inserting new session for user %d", userID)
-	_, err = tx.Exec(
-		"INSERT INTO sessions (id, user_id, expires_at, created_at) VALUES (?, ?, datetime(?), datetime('now'))",
-		sessionID, userID, expiresAt.Format("2006-01-02 15:04:05"),
-	)
-	if err != nil {
-		log.Printf("ERROR: Failed to insert session for user %d: %v", userID, err)
+	// старые сессии удаляются в той же транзакции, что и вставка новой
+	if err := store.Sessions.ReplaceSessions(ctx, userID, sessionID, expiresAt); err != nil {
+		log.Printf("ERROR: Failed to create session for user %d: %v", userID, err)
 		return fmt.Errorf("failed to create session: %v", err)
 	}
 
-	// Коммитим транзакцию
-	if err := tx.Commit(); err != nil {
-		log.Printf("ERROR: Failed to commit transaction for user %d: %v", userID, err)
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
-
 	// устанавливаем cookie
 	cookie := &http.Cookie{
 		Name:     "session_id",
@@ -166,21 +156,20 @@ func CreateSession(w http.ResponseWriter, db *sql.DB, userID int) error {
 	return nil
 }
 
-// CleanupExpiredSessions удаляет все просроченные сессии
-func CleanupExpiredSessions(db *sql.DB) error {
-	result, err := db.Exec("DELETE FROM sessions WHERE datetime(expires_at) <= datetime('now')")
+// CleanupExpiredSessions удаляет все просроченные сессии и возвращает их число
+func CleanupExpiredSessions(ctx context.Context, store *repos.Repos) (int64, error) {
+	rows, err := store.Sessions.DeleteExpiredSessions(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	if rows, _ := result.RowsAffected(); rows > 0 {
+	if rows > 0 {
 		log.Printf("Cleaned up %d expired sessions", rows)
 	}
-	return nil
+	return rows, nil
 }
 
 // LogoutUser удаляет текущую сессию пользователя
-func LogoutUser(w http.ResponseWriter, r *http.Request, db *sql.DB) error {
+func LogoutUser(w http.ResponseWriter, r *http.Request, store *repos.Repos) error {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		// Если куки нет, считаем, что пользователь уже вышел
@@ -189,11 +178,11 @@ func LogoutUser(w http.ResponseWriter, r *http.Request, db *sql.DB) error {
 	}
 
 	// Удаляем сессию из базы данных
-	result, err := db.Exec("DELETE FROM sessions WHERE id = ?", cookie.Value)
+	deleted, err := store.Sessions.DeleteSession(r.Context(), cookie.Value)
 	if err != nil {
 		log.Printf("ERROR: Failed to delete session from DB: %v", err)
 		// Продолжаем удалять куки, даже если БД дала сбой, чтобы очистить браузер
-	} else if rows, _ := result.RowsAffected(); rows > 0 {
+	} else if deleted {
 		log.Printf("User logged out, session deleted from DB")
 	}
 
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"real-time-forum/internal/config"
+	"real-time-forum/internal/repos"
+)
+
+// TokenBucket is a single token bucket. The zero value is a full bucket.
+// It is not safe for concurrent use; callers guard it themselves.
+type TokenBucket struct {
+	tokens  float64
+	last    time.Time
+	started bool
+}
+
+// Take refills the bucket for the time elapsed since the last call and tries
+// to consume one token. It returns whether the token was granted, the whole
+// tokens left and how long until the next token becomes available.
+func (b *TokenBucket) Take(limit config.RateLimit, now time.Time) (bool, int, time.Duration) {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds() // tokens per second
+
+	if !b.started {
+		b.tokens = capacity
+		b.started = true
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, int(b.tokens), 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, 0, wait
+}
+
+// untilFull returns how long the bucket needs to refill completely
+func (b *TokenBucket) untilFull(limit config.RateLimit) time.Duration {
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+	return time.Duration((float64(limit.Requests) - b.tokens) / rate * float64(time.Second))
+}
+
+// RateLimiter keeps one token bucket per route and user (or per IP for guests).
+type RateLimiter struct {
+	store *repos.Repos
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*limitedBucket
+	lastSweep time.Time
+}
+
+// limitedBucket remembers the refill period so idle buckets can be swept
+type limitedBucket struct {
+	TokenBucket
+	per time.Duration
+}
+
+func NewRateLimiter(store *repos.Repos) *RateLimiter {
+	return &RateLimiter{
+		store:   store,
+		now:     time.Now,
+		buckets: make(map[string]*limitedBucket),
+	}
+}
+
+// Wrap limits state-changing requests to next with the quota of the named route.
+// Reads (GET/HEAD/OPTIONS) pass through untouched.
+func (l *RateLimiter) Wrap(route string, limit config.RateLimit, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limit.Requests <= 0 || isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		subject := IPKey(r)
+		if userID, err := GetUserIDFromContextOrSession(r, l.store); err == nil {
+			subject = AccountKey(userID)
+		}
+
+		ok, remaining, retry, reset := l.allow(route+"|"+subject, limit)
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if !ok {
+			SetRetryAfter(w, retry)
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// allow takes a token from the bucket stored under key. reset is the moment the
+// bucket will be full again.
+func (l *RateLimiter) allow(key string, limit config.RateLimit) (bool, int, time.Duration, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &limitedBucket{per: limit.Per}
+		l.buckets[key] = b
+	}
+
+	ok, remaining, retry := b.Take(limit, now)
+	return ok, remaining, retry, now.Add(b.untilFull(limit))
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// so the map does not grow with every visitor. Caller holds l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > b.per {
+			delete(l.buckets, key)
+		}
+	}
+}
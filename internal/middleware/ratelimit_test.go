@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/config"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	limit := config.RateLimit{Requests: 2, Per: 2 * time.Second} // 1 token/sec
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var b TokenBucket
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := b.Take(limit, now); !ok {
+			t.Fatalf("take #%d denied, bucket should start full", i+1)
+		}
+	}
+
+	ok, remaining, wait := b.Take(limit, now)
+	if ok || remaining != 0 {
+		t.Fatalf("expected empty bucket to deny, got ok=%v remaining=%d", ok, remaining)
+	}
+	if wait != time.Second {
+		t.Errorf("wait = %v, want 1s", wait)
+	}
+
+	// half a token later still denied, a full token later allowed
+	if ok, _, _ := b.Take(limit, now.Add(500*time.Millisecond)); ok {
+		t.Errorf("expected deny after 500ms")
+	}
+	if ok, _, _ := b.Take(limit, now.Add(1500*time.Millisecond)); !ok {
+		t.Errorf("expected allow after refill")
+	}
+}
+
+func TestRateLimiterWrap(t *testing.T) {
+	db := setupLimiterDB(t)
+	defer db.Close()
+
+	l := NewRateLimiter(testStore(db))
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	h := l.Wrap("posts_create", config.RateLimit{Requests: 2, Per: time.Minute}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	do := func(method, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/posts/create", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "10.0.0.1:1000"); rec.Code != http.StatusCreated || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("first request: code=%d remaining=%q", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	do(http.MethodPost, "10.0.0.1:1001")
+
+	rec := do(http.MethodPost, "10.0.0.1:1002")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request code = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" || rec.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("unexpected headers: %v", rec.Header())
+	}
+
+	// other clients and reads are not affected
+	if rec := do(http.MethodPost, "10.0.0.2:1000"); rec.Code != http.StatusCreated {
+		t.Errorf("other IP code = %d, want 201", rec.Code)
+	}
+	if rec := do(http.MethodGet, "10.0.0.1:1003"); rec.Code != http.StatusCreated {
+		t.Errorf("GET code = %d, want passthrough", rec.Code)
+	}
+}
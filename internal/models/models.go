@@ -15,38 +15,123 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// UserStats aggregates a user's activity for the profile page
+type UserStats struct {
+	Posts         int `json:"posts"`
+	Comments      int `json:"comments"`
+	LikesGiven    int `json:"likes_given"`
+	DislikesGiven int `json:"dislikes_given"`
+}
+
+// UserProfile is the public view of a user (no e-mail, no password data)
+type UserProfile struct {
+	ID             int       `json:"id"`
+	Username       string    `json:"username"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Age            int       `json:"age"`
+	Gender         string    `json:"gender"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	Stats          UserStats `json:"stats"`
+	RecentPosts    []Post    `json:"recent_posts"`
+	RecentComments []Comment `json:"recent_comments"`
+	// names of fields withheld from this viewer by the owner's privacy settings
+	HiddenFields []string `json:"hidden_fields,omitempty"`
+}
+
+// Visibility levels of profile fields
+const (
+	VisibilityPublic  = "public"  // anyone, including guests
+	VisibilityMembers = "members" // logged-in users
+	VisibilityNobody  = "nobody"  // only the owner
+)
+
+// Direct message policies
+const (
+	DMEveryone = "everyone"
+	DMContacts = "contacts" // only users the owner has written to before
+)
+
+// PrivacySettings controls who sees personal profile fields and who may send DMs
+type PrivacySettings struct {
+	NameVisibility   string `json:"name_visibility"` // first and last name
+	AgeVisibility    string `json:"age_visibility"`
+	GenderVisibility string `json:"gender_visibility"`
+	DMPolicy         string `json:"dm_policy"`
+	// hide posts and comments of blocked users from listings
+	HideBlockedContent bool `json:"hide_blocked_content"`
+}
+
+// BlockedUser is an entry of a user's block list
+type BlockedUser struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
 // Post represents a forum post
 type Post struct {
-	ID           int       `json:"id"`
-	UserID       int       `json:"user_id"`
-	Username     string    `json:"username"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	Categories   []string  `json:"categories"`
-	Likes        int       `json:"likes"`
-	Dislikes     int       `json:"dislikes"`
-	CommentCount int       `json:"comment_count"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int            `json:"id"`
+	UserID       int            `json:"user_id"`
+	Username     string         `json:"username"`
+	Title        string         `json:"title"`
+	Content      string         `json:"content"`
+	Categories   []string       `json:"categories"`
+	Tags         []string       `json:"tags"`
+	Likes        int            `json:"likes"`
+	Dislikes     int            `json:"dislikes"`
+	Reactions    map[string]int `json:"reactions"`   // count per reaction name
+	MyReaction   string         `json:"my_reaction"` // the viewer's reaction, "" for none or a guest
+	CommentCount int            `json:"comment_count"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// SetReactions stores the per-reaction counts and the likes/dislikes derived from them
+func (p *Post) SetReactions(counts map[string]int) {
+	p.Reactions = counts
+	p.Likes, p.Dislikes = counts[ReactionLike], counts[ReactionDislike]
 }
 
 // Comment represents a comment on a post
 type Comment struct {
-	ID        int       `json:"id"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Username  string    `json:"username"`
-	Content   string    `json:"content"`
-	Likes     int       `json:"likes"`
-	Dislikes  int       `json:"dislikes"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int            `json:"id"`
+	PostID     int            `json:"post_id"`
+	PostTitle  string         `json:"post_title,omitempty"` // filled only in profile listings
+	UserID     int            `json:"user_id"`
+	Username   string         `json:"username"`
+	Content    string         `json:"content"`
+	Likes      int            `json:"likes"`
+	Dislikes   int            `json:"dislikes"`
+	Reactions  map[string]int `json:"reactions"`   // count per reaction name
+	MyReaction string         `json:"my_reaction"` // the viewer's reaction, "" for none or a guest
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// SetReactions stores the per-reaction counts and the likes/dislikes derived from them
+func (c *Comment) SetReactions(counts map[string]int) {
+	c.Reactions = counts
+	c.Likes, c.Dislikes = counts[ReactionLike], counts[ReactionDislike]
 }
 
-// Category represents a post category
+// Category represents a post category; categories form a tree through ParentID
 type Category struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description"`
+	ParentID    *int       `json:"parent_id"`
+	SortOrder   int        `json:"sort_order"`
+	Archived    bool       `json:"archived"` // archived categories take no new posts
+	CreatedAt   time.Time  `json:"created_at"`
+	Children    []Category `json:"children,omitempty"`
+}
+
+// Tag is a free-form post label with the number of posts using it
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
 }
 
 // Session represents a user session
@@ -65,11 +150,80 @@ type ChatUser struct {
 	LastMessageAt string `json:"last_message_at,omitempty"`
 }
 
-// LikeDislike represents a like or dislike action
-type LikeDislike struct {
+// Reactions that have their own counters (Likes, Dislikes) and filters
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+)
+
+// Reaction is a user's reaction to a post or a comment
+type Reaction struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
 	TargetID  int       `json:"target_id"` // Post ID or Comment ID
-	IsLike    bool      `json:"is_like"`   // true for like, false for dislike
+	Reaction  string    `json:"reaction"`  // name from the configured set, e.g. "like"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Message is a private chat message
+type Message struct {
+	ID        int       `json:"id"`
+	From      int       `json:"from"`
+	To        int       `json:"to"`
+	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Identity is an external account (e.g. an OIDC provider) linked to a user
+type Identity struct {
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// LoginAttempt tracks failed logins for an IP address or an account
+type LoginAttempt struct {
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+}
+
+// APIToken is a personal access token for scripts and bots; only its hash is stored
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AccountSummary is a user as operators see it in forumctl
+type AccountSummary struct {
+	ID                  int        `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	IsAdmin             bool       `json:"is_admin"`
+	EmailVerified       bool       `json:"email_verified"`
+	BannedAt            *time.Time `json:"banned_at"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// ForumStats are the row counts reported by forumctl stats
+type ForumStats struct {
+	Users          int `json:"users"`
+	Admins         int `json:"admins"`
+	BannedUsers    int `json:"banned_users"`
+	Posts          int `json:"posts"`
+	Comments       int `json:"comments"`
+	Messages       int `json:"messages"`
+	Categories     int `json:"categories"`
+	Tags           int `json:"tags"`
+	ActiveSessions int `json:"active_sessions"`
+	OnlineUsers    int `json:"online_users"`
+}
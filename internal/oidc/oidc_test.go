@@ -0,0 +1,182 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/oidc/oidctest"
+)
+
+const testRedirect = "http://forum.test/api/auth/oidc/callback"
+
+func setupProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	t.Helper()
+	idp := oidctest.NewServer("forum", "s3cret")
+	t.Cleanup(idp.Close)
+
+	p := NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "forum",
+		ClientSecret: "s3cret",
+		RedirectURL:  testRedirect,
+		Scopes:       []string{"openid", "email"},
+	}, idp.Client())
+	return idp, p
+}
+
+// authorize follows the provider's login page and returns the issued code
+func authorize(t *testing.T, p *Provider, state, nonce, challenge string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	if !strings.HasPrefix(loc.String(), testRedirect) {
+		t.Fatalf("redirected to %s", loc)
+	}
+	if loc.Query().Get("state") != state {
+		t.Fatalf("state not echoed back")
+	}
+	return loc.Query().Get("code")
+}
+
+func TestExchangeSuccess(t *testing.T) {
+	idp, p := setupProvider(t)
+	idp.SetUser(oidctest.User{Subject: "abc-123", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice"})
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := authorize(t, p, "state-1", "nonce-1", challenge)
+
+	claims, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "abc-123" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Codes are single use
+	if _, err := p.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Error("second exchange of the same code succeeded")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp, p := setupProvider(t)
+	idp.SetUser(oidctest.User{Subject: "abc"})
+
+	_, challenge, _ := NewPKCE()
+	otherVerifier, _, _ := NewPKCE()
+	code := authorize(t, p, "s", "n", challenge)
+
+	if _, err := p.Exchange(context.Background(), code, otherVerifier, "n"); err == nil {
+		t.Fatal("exchange with a foreign code_verifier succeeded")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	idp, p := setupProvider(t)
+	idp.SetUser(oidctest.User{Subject: "abc"})
+
+	verifier, challenge, _ := NewPKCE()
+	code := authorize(t, p, "s", "nonce-a", challenge)
+
+	if _, err := p.Exchange(context.Background(), code, verifier, "nonce-b"); !errors.Is(err, ErrNonce) {
+		t.Fatalf("err = %v, want ErrNonce", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp, p := setupProvider(t)
+	user := oidctest.User{Subject: "abc"}
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]interface{})
+		ok     bool
+	}{
+		{"valid", func(map[string]interface{}) {}, true},
+		{"audience array", func(c map[string]interface{}) { c["aud"] = []string{"other", "forum"} }, true},
+		{"wrong audience", func(c map[string]interface{}) { c["aud"] = "other" }, false},
+		{"wrong issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example" }, false},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, false},
+		{"issued in future", func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() }, false},
+		{"no subject", func(c map[string]interface{}) { c["sub"] = "" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.Claims(user, "n")
+			tt.mutate(claims)
+			_, err := p.VerifyIDToken(context.Background(), idp.SignIDToken(claims), "n")
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	idp, p := setupProvider(t)
+	token := idp.SignIDToken(idp.Claims(oidctest.User{Subject: "abc"}, "n"))
+
+	// Tampered payload
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := p.VerifyIDToken(context.Background(), tampered, "n"); err == nil {
+		t.Error("tampered token accepted")
+	}
+
+	// "none" algorithm
+	none := "eyJhbGciOiJub25lIn0." + parts[1] + "."
+	if _, err := p.VerifyIDToken(context.Background(), none, "n"); err == nil {
+		t.Error("unsigned token accepted")
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	idp, p := setupProvider(t)
+	if _, err := p.VerifyIDToken(context.Background(), idp.SignIDToken(idp.Claims(oidctest.User{Subject: "a"}, "n")), "n"); err != nil {
+		t.Fatalf("before rotation: %v", err)
+	}
+
+	idp.RotateKey("key-2")
+	if _, err := p.VerifyIDToken(context.Background(), idp.SignIDToken(idp.Claims(oidctest.User{Subject: "a"}, "n")), "n"); err != nil {
+		t.Fatalf("after rotation: %v", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer("forum", "s3cret")
+	defer idp.Close()
+
+	p := NewProvider(Config{Issuer: idp.Issuer() + "/other", ClientID: "forum"}, idp.Client())
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Fatal("discovery accepted a foreign issuer")
+	}
+}
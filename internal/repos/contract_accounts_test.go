@@ -0,0 +1,369 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+)
+
+func TestContract_UserAccount(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		users := b.repos.Users
+		alice := b.user("Alice")
+
+		if u, err := users.GetByEmail(ctx, "ALICE@example.com"); err != nil || u.ID != alice {
+			t.Errorf("GetByEmail = %+v, %v", u, err)
+		}
+		for _, login := range []string{"alice", "alice@EXAMPLE.com"} {
+			if id, err := users.IDByLogin(ctx, login); err != nil || id != alice {
+				t.Errorf("IDByLogin(%s) = %d, %v; want %d", login, id, err, alice)
+			}
+		}
+		if _, err := users.IDByLogin(ctx, "nobody"); err != sql.ErrNoRows {
+			t.Errorf("IDByLogin(unknown) err = %v, want sql.ErrNoRows", err)
+		}
+
+		if err := users.UpdatePassword(ctx, alice, "new-hash"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		if u, _ := users.GetByID(ctx, alice); u.PasswordHash != "new-hash" {
+			t.Errorf("password hash = %q after UpdatePassword", u.PasswordHash)
+		}
+
+		if ok, _ := users.IsEmailVerified(ctx, alice); ok {
+			t.Error("a new user is verified")
+		}
+		users.MarkEmailVerified(ctx, alice)
+		first, deletion, err := b.repos.Accounts.AccountInfo(ctx, alice)
+		if err != nil || first == nil || deletion != nil {
+			t.Fatalf("AccountInfo = %v, %v, %v; want verified, no deletion", first, deletion, err)
+		}
+		users.MarkEmailVerified(ctx, alice)
+		if again, _, _ := b.repos.Accounts.AccountInfo(ctx, alice); !again.Equal(*first) {
+			t.Errorf("verification time moved from %v to %v", *first, *again)
+		}
+
+		settings := &models.PrivacySettings{
+			NameVisibility: models.VisibilityMembers, AgeVisibility: models.VisibilityMembers,
+			GenderVisibility: models.VisibilityMembers, DMPolicy: models.DMContacts, HideBlockedContent: false,
+		}
+		if err := users.UpdatePrivacySettings(ctx, alice, settings); err != nil {
+			t.Fatalf("UpdatePrivacySettings: %v", err)
+		}
+		if p, _ := users.GetPrivacySettings(ctx, alice); *p != *settings {
+			t.Errorf("privacy = %+v, want %+v", *p, *settings)
+		}
+	})
+}
+
+func TestContract_Profiles(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		profiles := b.repos.Profiles
+		alice, bob := b.user("alice"), b.user("bob")
+
+		p, err := profiles.GetProfile(ctx, alice)
+		if err != nil || p.ID != alice || p.Username != "alice" || p.CreatedAt.IsZero() {
+			t.Fatalf("GetProfile = %+v, %v", p, err)
+		}
+		p.FirstName, p.Age, p.Bio, p.AvatarURL = "Alice", 31, "Go developer", "/uploads/a.png"
+		if err := profiles.UpdateProfile(ctx, p); err != nil {
+			t.Fatalf("UpdateProfile: %v", err)
+		}
+		if got, _ := profiles.GetProfile(ctx, alice); got.FirstName != "Alice" || got.Age != 31 || got.Bio != "Go developer" || got.AvatarURL != "/uploads/a.png" {
+			t.Errorf("profile after UpdateProfile = %+v", got)
+		}
+		if _, err := profiles.GetProfile(ctx, 9999); err != sql.ErrNoRows {
+			t.Errorf("GetProfile(missing) err = %v, want sql.ErrNoRows", err)
+		}
+
+		older := b.post(alice, nil)
+		newer := b.post(alice, nil)
+		other := b.post(bob, nil)
+		b.exec("UPDATE posts SET created_at = ? WHERE id = ?", time.Now().Add(-time.Hour).UTC(), older)
+		b.exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'first')", other, alice)
+		b.exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'like')", other, alice)
+		b.exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'dislike')", newer, bob)
+
+		if s, err := profiles.GetStats(ctx, alice); err != nil || s != (models.UserStats{Posts: 2, Comments: 1, LikesGiven: 1}) {
+			t.Errorf("GetStats = %+v, %v", s, err)
+		}
+		posts, err := profiles.RecentPosts(ctx, alice, 1)
+		if err != nil || len(posts) != 1 || posts[0].ID != newer || posts[0].Dislikes != 1 {
+			t.Errorf("RecentPosts = %+v, %v; want the newest post with its dislike", posts, err)
+		}
+		comments, err := profiles.RecentComments(ctx, alice, 5)
+		if err != nil || len(comments) != 1 || comments[0].PostID != other || comments[0].PostTitle != "Title" {
+			t.Errorf("RecentComments = %+v, %v", comments, err)
+		}
+		if posts, err := profiles.RecentPosts(ctx, 9999, 5); err != nil || posts == nil || len(posts) != 0 {
+			t.Errorf("RecentPosts(no posts) = %#v, %v; want an empty slice", posts, err)
+		}
+	})
+}
+
+func TestContract_Accounts(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		accounts := b.repos.Accounts
+		alice, bob := b.user("alice"), b.user("bob")
+
+		postID := b.post(alice, nil)
+		b.exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'mine')", postID, alice)
+		b.exec("INSERT INTO post_likes (post_id, user_id, reaction) VALUES (?, ?, 'love')", postID, alice)
+		b.exec("INSERT INTO messages (from_user, to_user, content) VALUES (?, ?, 'hi')", bob, alice)
+
+		if c, err := accounts.UserComments(ctx, alice); err != nil || len(c) != 1 || c[0].Content != "mine" {
+			t.Errorf("UserComments = %+v, %v", c, err)
+		}
+		if r, err := accounts.UserPostReactions(ctx, alice); err != nil || len(r) != 1 || r[0].TargetID != postID || r[0].Reaction != "love" {
+			t.Errorf("UserPostReactions = %+v, %v", r, err)
+		}
+		if r, err := accounts.UserCommentReactions(ctx, alice); err != nil || len(r) != 0 {
+			t.Errorf("UserCommentReactions = %+v, %v; want none", r, err)
+		}
+		if m, err := accounts.UserMessages(ctx, alice); err != nil || len(m) != 1 || m[0].From != bob || m[0].To != alice {
+			t.Errorf("UserMessages = %+v, %v", m, err)
+		}
+
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('s', ?, ?)", alice, time.Now().Add(time.Hour).UTC())
+		b.repos.APITokens.CreateAPIToken(ctx, alice, "bot", "hash", []string{"read"}, time.Now().Add(time.Hour))
+
+		if at, err := accounts.DeletionRequestedAt(ctx, alice); err != nil || at != nil {
+			t.Errorf("DeletionRequestedAt = %v, %v; want nil", at, err)
+		}
+		requested := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+		if err := accounts.RequestDeletion(ctx, alice, requested); err != nil {
+			t.Fatalf("RequestDeletion: %v", err)
+		}
+		if err := accounts.RequestDeletion(ctx, 9999, requested); err != sql.ErrNoRows {
+			t.Errorf("RequestDeletion(missing) err = %v, want sql.ErrNoRows", err)
+		}
+		if at, _ := accounts.DeletionRequestedAt(ctx, alice); at == nil || !at.Equal(requested) {
+			t.Errorf("DeletionRequestedAt = %v, want %v", at, requested)
+		}
+		if list, _ := b.repos.Sessions.ListSessions(ctx, alice); len(list) != 0 {
+			t.Error("RequestDeletion kept the sessions")
+		}
+		if list, _ := b.repos.APITokens.ListAPITokens(ctx, alice); len(list) != 0 {
+			t.Error("RequestDeletion kept the API tokens")
+		}
+
+		if ok, err := accounts.CancelDeletion(ctx, alice); err != nil || !ok {
+			t.Errorf("CancelDeletion = %v, %v; want true", ok, err)
+		}
+		if ok, _ := accounts.CancelDeletion(ctx, alice); ok {
+			t.Error("CancelDeletion cancelled twice")
+		}
+
+		accounts.RequestDeletion(ctx, alice, requested)
+		accounts.RequestDeletion(ctx, bob, time.Now())
+		if n, err := accounts.PurgeDeleted(ctx, time.Now().Add(-time.Hour)); err != nil || n != 1 {
+			t.Fatalf("PurgeDeleted = %d, %v; want 1", n, err)
+		}
+		if _, err := b.repos.Users.GetByID(ctx, alice); err != sql.ErrNoRows {
+			t.Errorf("purged user still loads: %v", err)
+		}
+		if _, err := b.repos.Users.GetByID(ctx, bob); err != nil {
+			t.Errorf("user within the grace period was purged: %v", err)
+		}
+		if post, err := b.repos.Posts.GetPost(ctx, 0, postID); err != nil || post.UserID != 0 {
+			t.Errorf("post of a purged user = %+v, %v; want it kept without an author", post, err)
+		}
+	})
+}
+
+func TestContract_Admin(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		admin := b.repos.Admin
+		alice, bob := b.user("alice"), b.user("bob")
+
+		if err := admin.SetAdmin(ctx, alice, true); err != nil {
+			t.Fatalf("SetAdmin: %v", err)
+		}
+		if err := admin.SetAdmin(ctx, 9999, true); err != sql.ErrNoRows {
+			t.Errorf("SetAdmin(missing) err = %v, want sql.ErrNoRows", err)
+		}
+		if ok, err := admin.IsAdmin(ctx, alice); err != nil || !ok {
+			t.Errorf("IsAdmin(alice) = %v, %v; want true", ok, err)
+		}
+		if ok, err := admin.IsAdmin(ctx, 9999); err != nil || ok {
+			t.Errorf("IsAdmin(missing) = %v, %v; want false", ok, err)
+		}
+
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('s', ?, ?)", bob, time.Now().Add(time.Hour).UTC())
+		if ok, err := admin.Ban(ctx, bob, time.Now()); err != nil || !ok {
+			t.Fatalf("Ban = %v, %v; want true", ok, err)
+		}
+		if ok, _ := admin.Ban(ctx, bob, time.Now()); ok {
+			t.Error("Ban banned twice")
+		}
+		if ok, _ := admin.IsBanned(ctx, bob); !ok {
+			t.Error("IsBanned(bob) = false after Ban")
+		}
+		if list, _ := b.repos.Sessions.ListSessions(ctx, bob); len(list) != 0 {
+			t.Error("Ban kept the sessions")
+		}
+
+		users, err := admin.ListUsers(ctx)
+		if err != nil || len(users) != 2 || users[0].ID != alice || !users[0].IsAdmin || users[1].BannedAt == nil {
+			t.Errorf("ListUsers = %+v, %v", users, err)
+		}
+
+		b.post(alice, []int{1}, "go")
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('a', ?, ?)", alice, time.Now().Add(time.Hour).UTC())
+		b.repos.Presence.SetOnline(ctx, alice, "alice")
+		s, err := admin.Stats(ctx)
+		want := models.ForumStats{Users: 2, Admins: 1, BannedUsers: 1, Posts: 1, Categories: 6, Tags: 1, ActiveSessions: 1, OnlineUsers: 1}
+		if err != nil || *s != want {
+			t.Errorf("Stats = %+v, %v; want %+v", s, err, want)
+		}
+
+		if ok, err := admin.Unban(ctx, bob); err != nil || !ok {
+			t.Errorf("Unban = %v, %v; want true", ok, err)
+		}
+		if ok, _ := admin.IsBanned(ctx, bob); ok {
+			t.Error("IsBanned(bob) = true after Unban")
+		}
+	})
+}
+
+func TestContract_Categories(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		cats := b.repos.Categories
+
+		parent, err := cats.CreateCategory(ctx, &models.Category{Name: "Go", Slug: "go", Description: "all things Go"})
+		if err != nil {
+			t.Fatalf("CreateCategory: %v", err)
+		}
+		child, err := cats.CreateCategory(ctx, &models.Category{Name: "Generics", Slug: "generics", ParentID: &parent})
+		if err != nil {
+			t.Fatalf("CreateCategory(child): %v", err)
+		}
+		if _, err := cats.CreateCategory(ctx, &models.Category{Name: "Go", Slug: "go-2"}); err != database.ErrCategoryExists {
+			t.Errorf("CreateCategory(duplicate name) err = %v, want ErrCategoryExists", err)
+		}
+		missing := 9999
+		if _, err := cats.CreateCategory(ctx, &models.Category{Name: "Orphan", Slug: "orphan", ParentID: &missing}); err != sql.ErrNoRows {
+			t.Errorf("CreateCategory(missing parent) err = %v, want sql.ErrNoRows", err)
+		}
+
+		c, err := cats.GetCategory(ctx, child)
+		if err != nil || c.Name != "Generics" || c.ParentID == nil || *c.ParentID != parent {
+			t.Fatalf("GetCategory = %+v, %v", c, err)
+		}
+
+		tree, err := cats.ListCategories(ctx, false)
+		if err != nil {
+			t.Fatalf("ListCategories: %v", err)
+		}
+		var goCat *models.Category
+		for i := range tree {
+			if tree[i].ID == parent {
+				goCat = &tree[i]
+			}
+		}
+		if len(tree) != 7 || goCat == nil || len(goCat.Children) != 1 || goCat.Children[0].ID != child {
+			t.Errorf("ListCategories = %+v; want the 6 defaults and Go with its child", tree)
+		}
+
+		p, _ := cats.GetCategory(ctx, parent)
+		p.ParentID = &child
+		if err := cats.UpdateCategory(ctx, p); err != database.ErrCategoryCycle {
+			t.Errorf("UpdateCategory(cycle) err = %v, want ErrCategoryCycle", err)
+		}
+		p.ParentID, p.Archived = nil, true
+		if err := cats.UpdateCategory(ctx, p); err != nil {
+			t.Fatalf("UpdateCategory: %v", err)
+		}
+		if tree, _ := cats.ListCategories(ctx, false); len(tree) != 6 {
+			t.Errorf("%d top-level categories without archived, want 6", len(tree))
+		}
+		if tree, _ := cats.ListCategories(ctx, true); len(tree) != 7 {
+			t.Errorf("%d top-level categories with archived, want 7", len(tree))
+		}
+
+		b.post(b.user("alice"), []int{1})
+		if children, posts, err := cats.CountCategoryUsage(ctx, parent); err != nil || children != 1 || posts != 0 {
+			t.Errorf("CountCategoryUsage(Go) = %d, %d, %v; want 1 child", children, posts, err)
+		}
+		if children, posts, _ := cats.CountCategoryUsage(ctx, 1); children != 0 || posts != 1 {
+			t.Errorf("CountCategoryUsage(1) = %d, %d; want 1 post", children, posts)
+		}
+
+		if err := cats.DeleteCategory(ctx, child); err != nil {
+			t.Fatalf("DeleteCategory: %v", err)
+		}
+		if err := cats.DeleteCategory(ctx, child); err != sql.ErrNoRows {
+			t.Errorf("DeleteCategory(missing) err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestContract_BlockListAndChatUsers(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		alice, bob, carol, dave := b.user("alice"), b.user("bob"), b.user("Carol"), b.user("dave")
+
+		if err := b.repos.Blocks.Block(ctx, alice, dave); err != nil {
+			t.Fatalf("Block: %v", err)
+		}
+		if err := b.repos.Blocks.Block(ctx, alice, dave); err != nil {
+			t.Errorf("second Block: %v", err)
+		}
+		list, err := b.repos.Blocks.ListBlocked(ctx, alice)
+		if err != nil || len(list) != 1 || list[0].ID != dave || list[0].Username != "dave" || list[0].BlockedAt.IsZero() {
+			t.Errorf("ListBlocked = %+v, %v", list, err)
+		}
+
+		b.exec("INSERT INTO messages (from_user, to_user, content, created_at) VALUES (?, ?, 'old', ?)", alice, bob, time.Now().Add(-time.Hour).UTC())
+		b.exec("INSERT INTO messages (from_user, to_user, content, created_at) VALUES (?, ?, 'new', ?)", carol, alice, time.Now().UTC())
+		b.repos.Presence.SetOnline(ctx, bob, "bob")
+
+		users, err := b.repos.Messages.ChatUsers(ctx, alice)
+		if err != nil || len(users) != 2 {
+			t.Fatalf("ChatUsers = %+v, %v; want bob and Carol, not alice or the blocked dave", users, err)
+		}
+		if users[0].ID != carol || users[1].ID != bob || users[1].Status != "online" || users[0].Status != "offline" || users[0].LastMessageAt == "" {
+			t.Errorf("ChatUsers = %+v; want Carol (latest message) before bob", users)
+		}
+
+		if ok, err := b.repos.Blocks.Unblock(ctx, alice, dave); err != nil || !ok {
+			t.Errorf("Unblock = %v, %v; want true", ok, err)
+		}
+		if ok, _ := b.repos.Blocks.Unblock(ctx, alice, dave); ok {
+			t.Error("Unblock removed a missing block")
+		}
+		if users, _ := b.repos.Messages.ChatUsers(ctx, alice); len(users) != 3 || users[2].ID != dave {
+			t.Errorf("ChatUsers after Unblock = %+v; want dave last, with no messages", users)
+		}
+	})
+}
+
+func TestContract_SearchTags(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		alice := b.user("alice")
+		b.post(alice, nil, "golang", "go")
+		b.post(alice, nil, "golang")
+		b.post(alice, nil, "rust")
+
+		tags, err := b.repos.Posts.SearchTags(ctx, "go", 10)
+		if err != nil || len(tags) != 2 || tags[0] != (models.Tag{Name: "golang", Count: 2}) || tags[1] != (models.Tag{Name: "go", Count: 1}) {
+			t.Errorf("SearchTags(go) = %+v, %v", tags, err)
+		}
+		if tags, _ := b.repos.Posts.SearchTags(ctx, "go", 1); len(tags) != 1 {
+			t.Errorf("SearchTags limit 1 = %+v", tags)
+		}
+		if tags, err := b.repos.Posts.SearchTags(ctx, "py", 10); err != nil || len(tags) != 0 {
+			t.Errorf("SearchTags(py) = %+v, %v; want none", tags, err)
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+)
+
+func TestContract_Sessions(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		sessions := b.repos.Sessions
+		alice, bob := b.user("alice"), b.user("bob")
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		if err := sessions.ReplaceSessions(ctx, alice, "a1", expires); err != nil {
+			t.Fatalf("ReplaceSessions: %v", err)
+		}
+		if err := sessions.ReplaceSessions(ctx, alice, "a2", expires); err != nil {
+			t.Fatalf("ReplaceSessions: %v", err)
+		}
+		if _, _, err := sessions.GetSession(ctx, "a1"); err != sql.ErrNoRows {
+			t.Errorf("GetSession(replaced) err = %v, want sql.ErrNoRows", err)
+		}
+		userID, at, err := sessions.GetSession(ctx, "a2")
+		if err != nil || userID != alice || !at.Equal(expires) {
+			t.Errorf("GetSession = %d, %v, %v; want %d, %v", userID, at, err, alice, expires)
+		}
+
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('b1', ?, ?)", bob, time.Now().Add(time.Hour).UTC())
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('b2', ?, ?)", bob, time.Now().Add(time.Hour).UTC())
+		b.exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('old', ?, ?)", bob, time.Now().Add(-time.Hour).UTC())
+
+		list, err := sessions.ListSessions(ctx, bob)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("ListSessions = %+v, %v; want the 2 unexpired sessions", list, err)
+		}
+		if n, err := sessions.DeleteExpiredSessions(ctx); err != nil || n != 1 {
+			t.Errorf("DeleteExpiredSessions = %d, %v; want 1", n, err)
+		}
+		if err := sessions.DeleteOtherSessions(ctx, bob, "b1"); err != nil {
+			t.Fatalf("DeleteOtherSessions: %v", err)
+		}
+		if list, _ := sessions.ListSessions(ctx, bob); len(list) != 1 || list[0].ID != "b1" {
+			t.Errorf("sessions after DeleteOtherSessions = %+v, want only b1", list)
+		}
+
+		if ok, err := sessions.DeleteSession(ctx, "b1"); err != nil || !ok {
+			t.Errorf("DeleteSession = %v, %v; want true", ok, err)
+		}
+		if ok, _ := sessions.DeleteSession(ctx, "b1"); ok {
+			t.Error("DeleteSession reported a missing session as deleted")
+		}
+		if n, err := sessions.DeleteUserSessions(ctx, alice); err != nil || n != 1 {
+			t.Errorf("DeleteUserSessions = %d, %v; want 1", n, err)
+		}
+	})
+}
+
+func TestContract_Tokens(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		tokens := b.repos.Tokens
+		alice := b.user("alice")
+
+		if at, err := tokens.LatestTokenAt(ctx, alice, database.TokenEmailVerify); err != nil || !at.IsZero() {
+			t.Errorf("LatestTokenAt(none) = %v, %v; want the zero time", at, err)
+		}
+		if err := tokens.CreateToken(ctx, alice, database.TokenEmailVerify, "h1", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("CreateToken: %v", err)
+		}
+		if at, err := tokens.LatestTokenAt(ctx, alice, database.TokenEmailVerify); err != nil || time.Since(at) > time.Minute {
+			t.Errorf("LatestTokenAt = %v, %v; want about now", at, err)
+		}
+
+		if id, err := tokens.PeekToken(ctx, database.TokenEmailVerify, "h1"); err != nil || id != alice {
+			t.Errorf("PeekToken = %d, %v; want %d", id, err, alice)
+		}
+		if _, err := tokens.ConsumeToken(ctx, database.TokenPasswordReset, "h1"); err != database.ErrInvalidToken {
+			t.Errorf("ConsumeToken(wrong purpose) err = %v, want ErrInvalidToken", err)
+		}
+		if id, err := tokens.ConsumeToken(ctx, database.TokenEmailVerify, "h1"); err != nil || id != alice {
+			t.Errorf("ConsumeToken = %d, %v; want %d", id, err, alice)
+		}
+		if _, err := tokens.ConsumeToken(ctx, database.TokenEmailVerify, "h1"); err != database.ErrInvalidToken {
+			t.Errorf("second ConsumeToken err = %v, want ErrInvalidToken", err)
+		}
+
+		tokens.CreateToken(ctx, alice, database.TokenPasswordReset, "expired", time.Now().Add(-time.Minute))
+		if _, err := tokens.PeekToken(ctx, database.TokenPasswordReset, "expired"); err != database.ErrInvalidToken {
+			t.Errorf("PeekToken(expired) err = %v, want ErrInvalidToken", err)
+		}
+
+		tokens.CreateToken(ctx, alice, database.TokenPasswordReset, "h2", time.Now().Add(time.Hour))
+		if err := tokens.InvalidateTokens(ctx, alice, database.TokenPasswordReset); err != nil {
+			t.Fatalf("InvalidateTokens: %v", err)
+		}
+		if _, err := tokens.ConsumeToken(ctx, database.TokenPasswordReset, "h2"); err != database.ErrInvalidToken {
+			t.Errorf("ConsumeToken(invalidated) err = %v, want ErrInvalidToken", err)
+		}
+	})
+}
+
+func TestContract_TwoFactor(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		tf := b.repos.TwoFactor
+		alice := b.user("alice")
+
+		if st, err := tf.GetTOTPState(ctx, alice); err != nil || st.Secret != "" || st.Enabled {
+			t.Errorf("GetTOTPState(new user) = %+v, %v", st, err)
+		}
+		if err := tf.SetPendingTOTPSecret(ctx, alice, "SECRET"); err != nil {
+			t.Fatalf("SetPendingTOTPSecret: %v", err)
+		}
+		if st, _ := tf.GetTOTPState(ctx, alice); st.Secret != "SECRET" || st.Enabled {
+			t.Errorf("pending state = %+v, want the secret, not enabled", st)
+		}
+		if err := tf.EnableTOTP(ctx, alice, 100, []string{"c1", "c2", "c3"}); err != nil {
+			t.Fatalf("EnableTOTP: %v", err)
+		}
+		if st, _ := tf.GetTOTPState(ctx, alice); !st.Enabled || st.LastStep != 100 {
+			t.Errorf("enabled state = %+v, want enabled at step 100", st)
+		}
+
+		if ok, _ := tf.AdvanceTOTPStep(ctx, alice, 100); ok {
+			t.Error("AdvanceTOTPStep accepted a replayed step")
+		}
+		if ok, err := tf.AdvanceTOTPStep(ctx, alice, 101); err != nil || !ok {
+			t.Errorf("AdvanceTOTPStep(101) = %v, %v; want true", ok, err)
+		}
+
+		if ok, err := tf.UseRecoveryCode(ctx, alice, "c2"); err != nil || !ok {
+			t.Errorf("UseRecoveryCode = %v, %v; want true", ok, err)
+		}
+		if ok, _ := tf.UseRecoveryCode(ctx, alice, "c2"); ok {
+			t.Error("a recovery code worked twice")
+		}
+		if n, err := tf.CountRecoveryCodes(ctx, alice); err != nil || n != 2 {
+			t.Errorf("CountRecoveryCodes = %d, %v; want 2", n, err)
+		}
+
+		if err := tf.DisableTOTP(ctx, alice); err != nil {
+			t.Fatalf("DisableTOTP: %v", err)
+		}
+		if st, _ := tf.GetTOTPState(ctx, alice); st.Secret != "" || st.Enabled || st.LastStep != 0 {
+			t.Errorf("state after DisableTOTP = %+v", st)
+		}
+		if n, _ := tf.CountRecoveryCodes(ctx, alice); n != 0 {
+			t.Errorf("%d recovery codes left after DisableTOTP", n)
+		}
+	})
+}
+
+func TestContract_APITokens(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		api := b.repos.APITokens
+		alice, bob := b.user("alice"), b.user("bob")
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		id, err := api.CreateAPIToken(ctx, alice, "bot", "hash", []string{"read", "post"}, expires)
+		if err != nil {
+			t.Fatalf("CreateAPIToken: %v", err)
+		}
+		api.CreateAPIToken(ctx, alice, "other", "hash2", []string{"read"}, expires)
+
+		token, err := api.GetAPITokenByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("GetAPITokenByHash: %v", err)
+		}
+		if token.ID != id || token.UserID != alice || token.Name != "bot" ||
+			strings.Join(token.Scopes, ",") != "read,post" || !token.ExpiresAt.Equal(expires) || token.LastUsedAt != nil {
+			t.Errorf("GetAPITokenByHash = %+v", token)
+		}
+		if _, err := api.GetAPITokenByHash(ctx, "nope"); err != sql.ErrNoRows {
+			t.Errorf("GetAPITokenByHash(missing) err = %v, want sql.ErrNoRows", err)
+		}
+
+		now := time.Now()
+		if err := api.TouchAPIToken(ctx, id, now, time.Minute); err != nil {
+			t.Fatalf("TouchAPIToken: %v", err)
+		}
+		api.TouchAPIToken(ctx, id, now.Add(time.Second), time.Minute)
+		if token, _ := api.GetAPITokenByHash(ctx, "hash"); token.LastUsedAt == nil || token.LastUsedAt.Sub(now).Abs() > time.Millisecond {
+			t.Errorf("last_used_at = %v, want %v (the second touch is within the resolution)", token.LastUsedAt, now)
+		}
+
+		if list, err := api.ListAPITokens(ctx, alice); err != nil || len(list) != 2 || list[0].Name != "other" {
+			t.Errorf("ListAPITokens = %+v, %v; want both, newest first", list, err)
+		}
+		if ok, _ := api.DeleteAPIToken(ctx, bob, id); ok {
+			t.Error("DeleteAPIToken removed another user's token")
+		}
+		if ok, err := api.DeleteAPIToken(ctx, alice, id); err != nil || !ok {
+			t.Errorf("DeleteAPIToken = %v, %v; want true", ok, err)
+		}
+		if n, err := api.DeleteUserAPITokens(ctx, alice); err != nil || n != 1 {
+			t.Errorf("DeleteUserAPITokens = %d, %v; want 1", n, err)
+		}
+	})
+}
+
+func TestContract_LoginAttempts(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		attempts := b.repos.LoginAttempts
+		now := time.Now().Truncate(time.Second)
+
+		if a, err := attempts.GetLoginAttempt(ctx, "ip:1"); err != nil || a != nil {
+			t.Errorf("GetLoginAttempt(none) = %+v, %v; want nil", a, err)
+		}
+		for want := 1; want <= 3; want++ {
+			if n, err := attempts.RecordLoginFailure(ctx, "ip:1", now, now.Add(-time.Hour)); err != nil || n != want {
+				t.Fatalf("RecordLoginFailure = %d, %v; want %d", n, err, want)
+			}
+		}
+
+		until := now.Add(time.Minute)
+		if err := attempts.ExtendLoginLockout(ctx, "ip:1", until); err != nil {
+			t.Fatalf("ExtendLoginLockout: %v", err)
+		}
+		attempts.ExtendLoginLockout(ctx, "ip:1", now.Add(time.Second))
+		a, err := attempts.GetLoginAttempt(ctx, "ip:1")
+		if err != nil || a.Failures != 3 || !a.LastFailureAt.Equal(now) || !a.LockedUntil.Equal(until) {
+			t.Errorf("GetLoginAttempt = %+v, %v; want 3 failures locked until %v", a, err, until)
+		}
+
+		// a failure after the window starts the counter over and lifts the lockout
+		later := now.Add(2 * time.Hour)
+		if n, err := attempts.RecordLoginFailure(ctx, "ip:1", later, later.Add(-time.Hour)); err != nil || n != 1 {
+			t.Errorf("RecordLoginFailure after the window = %d, %v; want 1", n, err)
+		}
+		if a, _ := attempts.GetLoginAttempt(ctx, "ip:1"); !a.LockedUntil.IsZero() {
+			t.Errorf("locked until %v after the counter started over", a.LockedUntil)
+		}
+
+		attempts.RecordLoginFailure(ctx, "user:1", now, now)
+		if n, err := attempts.ClearLoginAttempts(ctx, "ip:1", "user:1", "user:2"); err != nil || n != 2 {
+			t.Errorf("ClearLoginAttempts = %d, %v; want 2", n, err)
+		}
+		if n, err := attempts.ClearLoginAttempts(ctx); err != nil || n != 0 {
+			t.Errorf("ClearLoginAttempts() = %d, %v; want 0", n, err)
+		}
+	})
+}
+
+func TestContract_Identities(t *testing.T) {
+	runContract(t, func(t *testing.T, b *backend) {
+		ctx := context.Background()
+		ids := b.repos.Identities
+		alice := b.user("alice")
+
+		st := database.OIDCState{StateHash: "s1", Nonce: "n", CodeVerifier: "v", LinkUserID: alice, ExpiresAt: time.Now().Add(time.Minute).Truncate(time.Second)}
+		if err := ids.SaveOIDCState(ctx, st); err != nil {
+			t.Fatalf("SaveOIDCState: %v", err)
+		}
+		ids.SaveOIDCState(ctx, database.OIDCState{StateHash: "s2", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(-time.Minute)})
+
+		got, err := ids.ConsumeOIDCState(ctx, "s1")
+		if err != nil || got.Nonce != "n" || got.CodeVerifier != "v" || got.LinkUserID != alice || !got.ExpiresAt.Equal(st.ExpiresAt) {
+			t.Errorf("ConsumeOIDCState = %+v, %v; want %+v", got, err, st)
+		}
+		for _, hash := range []string{"s1", "s2", "unknown"} {
+			if _, err := ids.ConsumeOIDCState(ctx, hash); err != database.ErrInvalidToken {
+				t.Errorf("ConsumeOIDCState(%s) err = %v, want ErrInvalidToken", hash, err)
+			}
+		}
+
+		if err := ids.LinkIdentity(ctx, alice, "corp", "sub-a", "alice@corp.example"); err != nil {
+			t.Fatalf("LinkIdentity: %v", err)
+		}
+		if err := ids.LinkIdentity(ctx, alice, "corp", "sub-a", "alice@corp.example"); err != database.ErrIdentityLinked {
+			t.Errorf("second LinkIdentity err = %v, want ErrIdentityLinked", err)
+		}
+		if id, err := ids.UserIDByIdentity(ctx, "corp", "sub-a"); err != nil || id != alice {
+			t.Errorf("UserIDByIdentity = %d, %v; want %d", id, err, alice)
+		}
+		if _, err := ids.UserIDByIdentity(ctx, "corp", "sub-x"); err != sql.ErrNoRows {
+			t.Errorf("UserIDByIdentity(unlinked) err = %v, want sql.ErrNoRows", err)
+		}
+		if err := ids.TouchIdentity(ctx, "corp", "sub-a", "new@corp.example"); err != nil {
+			t.Fatalf("TouchIdentity: %v", err)
+		}
+
+		bob, err := ids.CreateExternalUser(ctx, database.ExternalUser{
+			Provider: "corp", Subject: "sub-b", Email: "bob@corp.example", EmailVerified: true, Username: "bob", FirstName: "Bob",
+		})
+		if err != nil {
+			t.Fatalf("CreateExternalUser: %v", err)
+		}
+		if u, err := b.repos.Users.GetByID(ctx, bob); err != nil || u.Username != "bob" || u.PasswordHash != database.NoPassword || u.FirstName != "Bob" {
+			t.Errorf("external user = %+v, %v", u, err)
+		}
+		if ok, _ := b.repos.Users.IsEmailVerified(ctx, bob); !ok {
+			t.Error("external user with a verified e-mail is not verified")
+		}
+		if _, err := ids.CreateExternalUser(ctx, database.ExternalUser{Provider: "corp", Subject: "sub-a", Email: "c@corp.example", Username: "carol"}); err != database.ErrIdentityLinked {
+			t.Errorf("CreateExternalUser(linked subject) err = %v, want ErrIdentityLinked", err)
+		}
+		if ok, _ := b.repos.Users.UsernameExists(ctx, "carol"); ok {
+			t.Error("a failed CreateExternalUser left its user behind")
+		}
+
+		identities, err := b.repos.Accounts.UserIdentities(ctx, alice)
+		if err != nil || len(identities) != 1 || identities[0].Email != "new@corp.example" || identities[0].LastLoginAt == nil {
+			t.Errorf("UserIdentities = %+v, %v", identities, err)
+		}
+	})
+}
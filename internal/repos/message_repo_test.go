@@ -1,6 +1,7 @@
 package repos
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -22,6 +23,7 @@ func setupDB(t *testing.T) *sql.DB {
 }
 
 func TestMessageRepo_InsertAndQuery(t *testing.T) {
+	ctx := context.Background()
 	db := setupDB(t)
 	defer db.Close()
 
@@ -40,12 +42,12 @@ func TestMessageRepo_InsertAndQuery(t *testing.T) {
 	idB, _ := res2.LastInsertId()
 
 	// use repo
-	_, _, err = adapter.Insert(int(idA), int(idB), "hello")
+	_, _, err = adapter.Insert(ctx, int(idA), int(idB), "hello")
 	if err != nil {
 		t.Fatalf("Insert failed: %v", err)
 	}
 
-	msgs, err := adapter.GetBetween(int(idA), int(idB), 0, 10)
+	msgs, err := adapter.GetBetween(ctx, int(idA), int(idB), 0, 10)
 	if err != nil {
 		t.Fatalf("GetBetween failed: %v", err)
 	}
@@ -53,7 +55,7 @@ func TestMessageRepo_InsertAndQuery(t *testing.T) {
 		t.Fatalf("expected 1 message, got %d", len(msgs))
 	}
 
-	count, err := adapter.CountBetween(int(idA), int(idB))
+	count, err := adapter.CountBetween(ctx, int(idA), int(idB))
 	if err != nil {
 		t.Fatalf("CountBetween failed: %v", err)
 	}
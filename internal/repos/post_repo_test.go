@@ -0,0 +1,81 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"real-time-forum/internal/database"
+)
+
+// setupFileDB is setupDB on a file: a transaction takes its own connection,
+// and with :memory: every connection would see a different, empty database
+func setupFileDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "forum.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (email, username, password_hash) VALUES ('a@a', 'a', 'x')"); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return db
+}
+
+func TestPostRepo_CreatePost(t *testing.T) {
+	ctx := context.Background()
+	db := setupFileDB(t)
+	adapter := NewSQLiteAdapter(db)
+
+	id, err := adapter.CreatePost(ctx, 1, "Title", "Content", []int{1, 2}, []string{"go"})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	post, err := adapter.GetPost(ctx, 0, id)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if len(post.Categories) != 2 || len(post.Tags) != 1 || post.Tags[0] != "go" {
+		t.Fatalf("post = %+v, want 2 categories and tag go", post)
+	}
+
+	mine, err := adapter.ListPosts(ctx, PostFilter{AuthorID: 1, CategoryIDs: []int{2}})
+	if err != nil || len(mine) != 1 {
+		t.Fatalf("ListPosts(author, category) = %d posts, %v", len(mine), err)
+	}
+}
+
+func TestPostRepo_CreatePostIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	db := setupFileDB(t)
+	adapter := NewSQLiteAdapter(db)
+	db.Exec("UPDATE categories SET archived = 1 WHERE id = 2")
+
+	for name, ids := range map[string][]int{
+		"archived category": {1, 2},
+		"missing category":  {1, 9999},
+	} {
+		if _, err := adapter.CreatePost(ctx, 1, "Title", "Content", ids, []string{"go"}); err != ErrInvalidCategory {
+			t.Fatalf("%s: err = %v, want ErrInvalidCategory", name, err)
+		}
+	}
+
+	// a failure after the post row is written must undo it too
+	db.Exec("CREATE TRIGGER fail_tags BEFORE INSERT ON post_tags BEGIN SELECT RAISE(ABORT, 'boom'); END")
+	if _, err := adapter.CreatePost(ctx, 1, "Title", "Content", []int{1}, []string{"go"}); err == nil {
+		t.Fatal("CreatePost succeeded despite the failing tag insert")
+	}
+
+	for _, table := range []string{"posts", "post_categories", "post_tags", "tags"} {
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n)
+		if n != 0 {
+			t.Errorf("%s has %d rows after failed creates, want 0", table, n)
+		}
+	}
+}
@@ -0,0 +1,581 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// PostgresAdapter implements every repository interface on PostgreSQL. The
+// schema comes from MigratePostgres; results match SQLiteAdapter, which the
+// contract tests check.
+type PostgresAdapter struct {
+	DB *sql.DB
+}
+
+func NewPostgresAdapter(db *sql.DB) *PostgresAdapter {
+	return &PostgresAdapter{DB: db}
+}
+
+const pgUserColumns = "id, email, username, password_hash, age, gender, first_name, last_name, created_at"
+
+// UserRepo
+func (p *PostgresAdapter) GetByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return p.scanUser(p.DB.QueryRowContext(ctx, "SELECT "+pgUserColumns+" FROM users WHERE id = $1", id))
+}
+
+func (p *PostgresAdapter) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return p.scanUser(p.DB.QueryRowContext(ctx, "SELECT "+pgUserColumns+" FROM users WHERE LOWER(username) = LOWER($1)", username))
+}
+
+func (p *PostgresAdapter) GetByLogin(ctx context.Context, identifier string) (*models.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return p.scanUser(p.DB.QueryRowContext(ctx,
+		"SELECT "+pgUserColumns+" FROM users WHERE LOWER(email) = LOWER($1) OR LOWER(username) = LOWER($1)",
+		identifier,
+	))
+}
+
+func (p *PostgresAdapter) scanUser(row *sql.Row) (*models.User, error) {
+	var u models.User
+	err := row.Scan(&u.ID, &u.Email, &u.Username, &u.PasswordHash, &u.Age, &u.Gender, &u.FirstName, &u.LastName, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (p *PostgresAdapter) EmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := p.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))", email).Scan(&exists)
+	return exists, err
+}
+
+func (p *PostgresAdapter) UsernameExists(ctx context.Context, username string) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := p.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists)
+	return exists, err
+}
+
+func (p *PostgresAdapter) CreateUser(ctx context.Context, u *models.User) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := p.DB.QueryRowContext(ctx, `
+		INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		u.Email, u.Username, u.PasswordHash,
+		u.Age, u.Gender, u.FirstName, u.LastName,
+	).Scan(&id)
+	return id, err
+}
+
+func (p *PostgresAdapter) GetPrivacySettings(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var s models.PrivacySettings
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT name_visibility, age_visibility, gender_visibility, dm_policy, hide_blocked_content FROM users WHERE id = $1",
+		userID,
+	).Scan(&s.NameVisibility, &s.AgeVisibility, &s.GenderVisibility, &s.DMPolicy, &s.HideBlockedContent)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// PostRepo
+func (p *PostgresAdapter) CreatePost(ctx context.Context, userID int, title, content string, categoryIDs []int, tags []string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var postID int
+	err := database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		for _, id := range categoryIDs {
+			ok, err := pgCategoryAcceptsPosts(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrInvalidCategory
+			}
+		}
+
+		if err := tx.QueryRowContext(ctx,
+			"INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id",
+			userID, title, content,
+		).Scan(&postID); err != nil {
+			return err
+		}
+		for _, id := range categoryIDs {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO post_categories (post_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				postID, id,
+			); err != nil {
+				return err
+			}
+		}
+		for _, name := range tags {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO tags (name) VALUES ($1) ON CONFLICT DO NOTHING", name); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE name = $2 ON CONFLICT DO NOTHING",
+				postID, name,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return postID, nil
+}
+
+// pgCategoryAcceptsPosts mirrors database.CategoryAcceptsPosts: the category
+// and all of its ancestors must exist and not be archived
+func pgCategoryAcceptsPosts(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	seen := map[int]bool{}
+	for !seen[id] {
+		seen[id] = true
+
+		var (
+			archived bool
+			parent   sql.NullInt64
+		)
+		err := tx.QueryRowContext(ctx, "SELECT archived, parent_id FROM categories WHERE id = $1", id).Scan(&archived, &parent)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if archived {
+			return false, nil
+		}
+		if !parent.Valid {
+			return true, nil
+		}
+		id = int(parent.Int64)
+	}
+	return false, nil
+}
+
+// pgPostQuery loads posts with all their counters in one round trip; $1 is
+// the viewer whose own reaction fills MyReaction
+const pgPostQuery = `
+	SELECT p.id, COALESCE(p.user_id, 0), COALESCE(u.username, '[deleted]'), p.title, p.content, p.created_at,
+		COALESCE((SELECT reaction FROM post_likes WHERE post_id = p.id AND user_id = $1), ''),
+		ARRAY(SELECT c.name FROM categories c JOIN post_categories pc ON c.id = pc.category_id WHERE pc.post_id = p.id ORDER BY c.id),
+		ARRAY(SELECT t.name FROM tags t JOIN post_tags pt ON t.id = pt.tag_id WHERE pt.post_id = p.id ORDER BY t.name),
+		p.reaction_counts,
+		(SELECT COUNT(*) FROM comments WHERE post_id = p.id)
+	FROM posts p
+	LEFT JOIN users u ON p.user_id = u.id`
+
+func (p *PostgresAdapter) GetPost(ctx context.Context, viewerID, id int) (*models.Post, error) {
+	posts, err := p.queryPosts(ctx, pgPostQuery+" WHERE p.id = $2", viewerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &posts[0], nil
+}
+
+func (p *PostgresAdapter) ListPosts(ctx context.Context, f PostFilter) ([]models.Post, error) {
+	var where []string
+	args := []interface{}{f.ViewerID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.AuthorID > 0 {
+		where = append(where, "p.user_id = "+arg(f.AuthorID))
+	}
+	if f.LikedBy > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM post_likes l WHERE l.post_id = p.id AND l.reaction = 'like' AND l.user_id = "+arg(f.LikedBy)+")")
+	}
+	if len(f.CategoryIDs) > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.category_id = ANY("+arg(pq.Array(f.CategoryIDs))+"))")
+	}
+
+	query := pgPostQuery
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return p.queryPosts(ctx, query+" ORDER BY p.created_at DESC, p.id DESC", args...)
+}
+
+func (p *PostgresAdapter) queryPosts(ctx context.Context, query string, args ...interface{}) ([]models.Post, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var categories, tags pq.StringArray
+		var reactions []byte
+		if err := rows.Scan(
+			&post.ID, &post.UserID, &post.Username, &post.Title, &post.Content, &post.CreatedAt, &post.MyReaction,
+			&categories, &tags, &reactions, &post.CommentCount,
+		); err != nil {
+			return nil, err
+		}
+		counts, err := decodeReactions(reactions)
+		if err != nil {
+			return nil, err
+		}
+		post.SetReactions(counts)
+		if len(categories) > 0 {
+			post.Categories = categories
+		}
+		post.Tags = append([]string{}, tags...)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (p *PostgresAdapter) PostIDsByTags(ctx context.Context, tags []string) (map[int]bool, error) {
+	return p.queryIDs(ctx, `
+		SELECT DISTINCT pt.post_id
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE t.name = ANY($1)`,
+		pq.Array(tags),
+	)
+}
+
+func (p *PostgresAdapter) PostIDsByCategories(ctx context.Context, categoryIDs []int) (map[int]bool, error) {
+	return p.queryIDs(ctx, "SELECT DISTINCT post_id FROM post_categories WHERE category_id = ANY($1)", pq.Array(categoryIDs))
+}
+
+func (p *PostgresAdapter) queryIDs(ctx context.Context, query string, args ...interface{}) (map[int]bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// CommentRepo
+func (p *PostgresAdapter) CreateComment(ctx context.Context, postID, userID int, content string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := p.DB.QueryRowContext(ctx,
+		"INSERT INTO comments (post_id, user_id, content) VALUES ($1, $2, $3) RETURNING id",
+		postID, userID, content,
+	).Scan(&id)
+	return id, err
+}
+
+// pgCommentQuery is pgPostQuery for comments; $1 is the viewer
+const pgCommentQuery = `
+	SELECT c.id, c.post_id, COALESCE(c.user_id, 0), COALESCE(u.username, '[deleted]'), c.content, c.created_at,
+		COALESCE((SELECT reaction FROM comment_likes WHERE comment_id = c.id AND user_id = $1), ''),
+		c.reaction_counts
+	FROM comments c
+	LEFT JOIN users u ON c.user_id = u.id`
+
+func (p *PostgresAdapter) GetComment(ctx context.Context, id int) (*models.Comment, error) {
+	comments, err := p.queryComments(ctx, pgCommentQuery+" WHERE c.id = $2", 0, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &comments[0], nil
+}
+
+func (p *PostgresAdapter) ListComments(ctx context.Context, viewerID, postID int) ([]models.Comment, error) {
+	return p.queryComments(ctx, pgCommentQuery+" WHERE c.post_id = $2 ORDER BY c.created_at, c.id", viewerID, postID)
+}
+
+func (p *PostgresAdapter) queryComments(ctx context.Context, query string, args ...interface{}) ([]models.Comment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var c models.Comment
+		var reactions []byte
+		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.MyReaction, &reactions); err != nil {
+			return nil, err
+		}
+		counts, err := decodeReactions(reactions)
+		if err != nil {
+			return nil, err
+		}
+		c.SetReactions(counts)
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (p *PostgresAdapter) CountComments(ctx context.Context, postID int) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := p.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE post_id = $1", postID).Scan(&count)
+	return count, err
+}
+
+// ReactionRepo
+func (p *PostgresAdapter) TogglePostReaction(ctx context.Context, userID, postID int, reaction string) (map[string]int, string, error) {
+	return p.toggleReaction(ctx, "post_likes", "post_id", "posts", userID, postID, reaction)
+}
+
+func (p *PostgresAdapter) ToggleCommentReaction(ctx context.Context, userID, commentID int, reaction string) (map[string]int, string, error) {
+	return p.toggleReaction(ctx, "comment_likes", "comment_id", "comments", userID, commentID, reaction)
+}
+
+// toggleReaction works like utils.TogglePostReaction: the same reaction twice
+// removes it, another one replaces it. The change and the read of the
+// counters the trigger left on target run in one transaction.
+func (p *PostgresAdapter) toggleReaction(ctx context.Context, table, column, target string, userID, targetID int, reaction string) (map[string]int, string, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		mine string
+		raw  []byte
+	)
+	err := database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			"DELETE FROM "+table+" WHERE "+column+" = $1 AND user_id = $2 AND reaction = $3",
+			targetID, userID, reaction,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO "+table+" ("+column+", user_id, reaction) VALUES ($1, $2, $3) "+
+					"ON CONFLICT ("+column+", user_id) DO UPDATE SET reaction = EXCLUDED.reaction",
+				targetID, userID, reaction,
+			)
+			if err != nil {
+				return err
+			}
+			mine = reaction
+		}
+		return tx.QueryRowContext(ctx, "SELECT reaction_counts FROM "+target+" WHERE id = $1", targetID).Scan(&raw)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	counts, err := decodeReactions(raw)
+	return counts, mine, err
+}
+
+// decodeReactions reads a reaction_counts object ({"name": count})
+func decodeReactions(raw []byte) (map[string]int, error) {
+	counts := map[string]int{}
+	if err := json.Unmarshal(raw, &counts); err != nil {
+		return nil, fmt.Errorf("decode reactions: %v", err)
+	}
+	return counts, nil
+}
+
+// BlockRepo
+func (p *PostgresAdapter) IsBlocked(ctx context.Context, blocker, blocked int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2)",
+		blocker, blocked,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (p *PostgresAdapter) BlockedIDs(ctx context.Context, blocker int) (map[int]bool, error) {
+	return p.queryIDs(ctx, "SELECT blocked_id FROM blocks WHERE blocker_id = $1", blocker)
+}
+
+// MessageRepo
+func (p *PostgresAdapter) Insert(ctx context.Context, from int, to int, content string) (int64, string, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	var createdAt time.Time
+	err := p.DB.QueryRowContext(ctx,
+		"INSERT INTO messages (from_user, to_user, content) VALUES ($1, $2, $3) RETURNING id, created_at",
+		from, to, content,
+	).Scan(&id, &createdAt)
+	if err != nil {
+		return 0, "", err
+	}
+	// тот же формат, что отдаёт SQLite: клиенты его уже разбирают
+	return id, createdAt.UTC().Truncate(time.Second).Format(time.RFC3339), nil
+}
+
+func (p *PostgresAdapter) GetBetween(ctx context.Context, a int, b int, offset int, limit int) ([]models.Comment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT id, from_user, content, created_at
+		FROM messages
+		WHERE (from_user = $1 AND to_user = $2) OR (from_user = $2 AND to_user = $1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4`,
+		a, b, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []models.Comment
+	for rows.Next() {
+		var m models.Comment
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func (p *PostgresAdapter) CountBetween(ctx context.Context, a int, b int) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM messages WHERE (from_user = $1 AND to_user = $2) OR (from_user = $2 AND to_user = $1)",
+		a, b,
+	).Scan(&count)
+	return count, err
+}
+
+func (p *PostgresAdapter) CanSendMessage(ctx context.Context, from, to int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var policy string
+	if err := p.DB.QueryRowContext(ctx, "SELECT dm_policy FROM users WHERE id = $1", to).Scan(&policy); err != nil {
+		return false, err
+	}
+	if policy != models.DMContacts {
+		return true, nil
+	}
+
+	var talked bool
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM messages WHERE from_user = $1 AND to_user = $2)",
+		to, from,
+	).Scan(&talked)
+	return talked, err
+}
+
+// PresenceService
+func (p *PostgresAdapter) SetOnline(ctx context.Context, userID int, nickname string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, `
+		INSERT INTO presence (user_id, status, nickname, updated_at) VALUES ($1, 'online', $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET status = 'online', nickname = EXCLUDED.nickname, updated_at = NOW()`,
+		userID, nickname,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) SetOffline(ctx context.Context, userID int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, "UPDATE presence SET status = 'offline', updated_at = NOW() WHERE user_id = $1", userID)
+	return err
+}
+
+func (p *PostgresAdapter) ListOnline(ctx context.Context) ([]map[string]interface{}, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, "SELECT user_id, nickname FROM presence WHERE status = 'online'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []map[string]interface{}
+	for rows.Next() {
+		var id int
+		var nick sql.NullString
+		if err := rows.Scan(&id, &nick); err != nil {
+			return nil, err
+		}
+		item := map[string]interface{}{"user_id": fmt.Sprintf("%d", id)}
+		if nick.Valid {
+			item["nickname"] = nick.String
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,789 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Accounts, profiles, moderation, categories, blocks and the chat roster
+// on PostgreSQL; the SQLite side is the functions of package database.
+
+// UserRepo
+func (p *PostgresAdapter) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return p.scanUser(p.DB.QueryRowContext(ctx, "SELECT "+pgUserColumns+" FROM users WHERE LOWER(email) = LOWER($1)", email))
+}
+
+func (p *PostgresAdapter) IDByLogin(ctx context.Context, identifier string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT id FROM users WHERE LOWER(email) = LOWER($1) OR LOWER(username) = LOWER($1)",
+		identifier,
+	).Scan(&id)
+	return id, err
+}
+
+func (p *PostgresAdapter) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+	return err
+}
+
+func (p *PostgresAdapter) IsEmailVerified(ctx context.Context, userID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var verified bool
+	err := p.DB.QueryRowContext(ctx, "SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1", userID).Scan(&verified)
+	return verified, err
+}
+
+func (p *PostgresAdapter) MarkEmailVerified(ctx context.Context, userID int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, "UPDATE users SET email_verified_at = NOW() WHERE id = $1 AND email_verified_at IS NULL", userID)
+	return err
+}
+
+func (p *PostgresAdapter) UpdatePrivacySettings(ctx context.Context, userID int, s *models.PrivacySettings) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE users SET name_visibility = $1, age_visibility = $2, gender_visibility = $3, dm_policy = $4, hide_blocked_content = $5 WHERE id = $6",
+		s.NameVisibility, s.AgeVisibility, s.GenderVisibility, s.DMPolicy, s.HideBlockedContent, userID,
+	)
+	return err
+}
+
+// ProfileRepo
+func (p *PostgresAdapter) GetProfile(ctx context.Context, userID int) (*models.UserProfile, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var u models.UserProfile
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT id, username, first_name, last_name, age, gender, bio, avatar_url, created_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Age, &u.Gender, &u.Bio, &u.AvatarURL, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (p *PostgresAdapter) UpdateProfile(ctx context.Context, u *models.UserProfile) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, `
+		UPDATE users
+		SET first_name = $1, last_name = $2, age = $3, gender = $4, bio = $5, avatar_url = $6
+		WHERE id = $7`,
+		u.FirstName, u.LastName, u.Age, u.Gender, u.Bio, u.AvatarURL, u.ID,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) GetStats(ctx context.Context, userID int) (models.UserStats, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var s models.UserStats
+	err := p.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM posts WHERE user_id = $1),
+			(SELECT COUNT(*) FROM comments WHERE user_id = $1),
+			(SELECT COUNT(*) FROM post_likes WHERE user_id = $1 AND reaction = 'like')
+				+ (SELECT COUNT(*) FROM comment_likes WHERE user_id = $1 AND reaction = 'like'),
+			(SELECT COUNT(*) FROM post_likes WHERE user_id = $1 AND reaction = 'dislike')
+				+ (SELECT COUNT(*) FROM comment_likes WHERE user_id = $1 AND reaction = 'dislike')`,
+		userID,
+	).Scan(&s.Posts, &s.Comments, &s.LikesGiven, &s.DislikesGiven)
+	return s, err
+}
+
+func (p *PostgresAdapter) RecentPosts(ctx context.Context, userID, limit int) ([]models.Post, error) {
+	posts, err := p.queryPosts(ctx, pgPostQuery+" WHERE p.user_id = $2 ORDER BY p.created_at DESC, p.id DESC LIMIT $3", 0, userID, limit)
+	if posts == nil && err == nil {
+		posts = []models.Post{}
+	}
+	return posts, err
+}
+
+func (p *PostgresAdapter) RecentComments(ctx context.Context, userID, limit int) ([]models.Comment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT c.id, c.post_id, p.title, COALESCE(c.user_id, 0), COALESCE(u.username, '[deleted]'), c.content, c.created_at
+		FROM comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.PostTitle, &c.UserID, &c.Username, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// AccountRepo
+func (p *PostgresAdapter) RequestDeletion(ctx context.Context, userID int, now time.Time) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "UPDATE users SET deletion_requested_at = $1 WHERE id = $2", now.UTC(), userID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+		return p.revokeAccess(ctx, tx, userID)
+	})
+}
+
+// revokeAccess signs the user out everywhere and revokes the API tokens
+func (p *PostgresAdapter) revokeAccess(ctx context.Context, tx *sql.Tx, userID int) error {
+	for _, q := range []string{
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM api_tokens WHERE user_id = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostgresAdapter) CancelDeletion(ctx context.Context, userID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx,
+		"UPDATE users SET deletion_requested_at = NULL WHERE id = $1 AND deletion_requested_at IS NOT NULL",
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *PostgresAdapter) DeletionRequestedAt(ctx context.Context, userID int) (*time.Time, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var at sql.NullTime
+	if err := p.DB.QueryRowContext(ctx, "SELECT deletion_requested_at FROM users WHERE id = $1", userID).Scan(&at); err != nil {
+		return nil, err
+	}
+	if !at.Valid {
+		return nil, nil
+	}
+	return &at.Time, nil
+}
+
+// PurgeDeleted relies on the foreign keys like database.DeleteUser; on
+// PostgreSQL they are always enforced
+func (p *PostgresAdapter) PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, "DELETE FROM users WHERE deletion_requested_at <= $1 RETURNING id", cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	purged := 0
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return purged, err
+		}
+		log.Printf("account %d deleted", id)
+		purged++
+	}
+	return purged, rows.Err()
+}
+
+func (p *PostgresAdapter) AccountInfo(ctx context.Context, userID int) (emailVerifiedAt, deletionRequestedAt *time.Time, err error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var verified, deletion sql.NullTime
+	err = p.DB.QueryRowContext(ctx,
+		"SELECT email_verified_at, deletion_requested_at FROM users WHERE id = $1", userID,
+	).Scan(&verified, &deletion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if verified.Valid {
+		emailVerifiedAt = &verified.Time
+	}
+	if deletion.Valid {
+		deletionRequestedAt = &deletion.Time
+	}
+	return emailVerifiedAt, deletionRequestedAt, nil
+}
+
+func (p *PostgresAdapter) UserComments(ctx context.Context, userID int) ([]models.Comment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), c.content, c.created_at, COALESCE(u.username, '[deleted]')
+		FROM comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return database.ScanComments(rows)
+}
+
+func (p *PostgresAdapter) UserPostReactions(ctx context.Context, userID int) ([]models.Reaction, error) {
+	return p.userReactions(ctx, "SELECT id, user_id, post_id, reaction, created_at FROM post_likes WHERE user_id = $1 ORDER BY id", userID)
+}
+
+func (p *PostgresAdapter) UserCommentReactions(ctx context.Context, userID int) ([]models.Reaction, error) {
+	return p.userReactions(ctx, "SELECT id, user_id, comment_id, reaction, created_at FROM comment_likes WHERE user_id = $1 ORDER BY id", userID)
+}
+
+func (p *PostgresAdapter) userReactions(ctx context.Context, query string, userID int) ([]models.Reaction, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Reaction{}
+	for rows.Next() {
+		var l models.Reaction
+		if err := rows.Scan(&l.ID, &l.UserID, &l.TargetID, &l.Reaction, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresAdapter) UserMessages(ctx context.Context, userID int) ([]models.Message, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT id, from_user, to_user, content, created_at
+		FROM messages
+		WHERE from_user = $1 OR to_user = $1
+		ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Message{}
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.From, &m.To, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresAdapter) UserIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Identity{}
+	for rows.Next() {
+		var (
+			i         models.Identity
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &lastLogin); err != nil {
+			return nil, err
+		}
+		if lastLogin.Valid {
+			t := lastLogin.Time
+			i.LastLoginAt = &t
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
+
+// AdminRepo
+func (p *PostgresAdapter) ListUsers(ctx context.Context) ([]models.AccountSummary, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT id, username, email, is_admin, email_verified_at IS NOT NULL,
+		       banned_at, deletion_requested_at, created_at
+		FROM users
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.AccountSummary
+	for rows.Next() {
+		var (
+			u                models.AccountSummary
+			banned, deletion sql.NullTime
+		)
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.IsAdmin, &u.EmailVerified, &banned, &deletion, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		if banned.Valid {
+			u.BannedAt = &banned.Time
+		}
+		if deletion.Valid {
+			u.DeletionRequestedAt = &deletion.Time
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (p *PostgresAdapter) Ban(ctx context.Context, userID int, now time.Time) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var banned bool
+	err := database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		var at sql.NullTime
+		if err := tx.QueryRowContext(ctx, "SELECT banned_at FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&at); err != nil {
+			return err
+		}
+		if !at.Valid {
+			if _, err := tx.ExecContext(ctx, "UPDATE users SET banned_at = $1 WHERE id = $2", now.UTC(), userID); err != nil {
+				return err
+			}
+			banned = true
+		}
+		return p.revokeAccess(ctx, tx, userID)
+	})
+	return banned, err
+}
+
+func (p *PostgresAdapter) Unban(ctx context.Context, userID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "UPDATE users SET banned_at = NULL WHERE id = $1 AND banned_at IS NOT NULL", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *PostgresAdapter) IsBanned(ctx context.Context, userID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var banned bool
+	err := p.DB.QueryRowContext(ctx, "SELECT banned_at IS NOT NULL FROM users WHERE id = $1", userID).Scan(&banned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return banned, err
+}
+
+func (p *PostgresAdapter) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var isAdmin bool
+	err := p.DB.QueryRowContext(ctx, "SELECT is_admin FROM users WHERE id = $1", userID).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isAdmin, err
+}
+
+func (p *PostgresAdapter) SetAdmin(ctx context.Context, userID int, admin bool) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "UPDATE users SET is_admin = $1 WHERE id = $2", admin, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (p *PostgresAdapter) Stats(ctx context.Context) (*models.ForumStats, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var s models.ForumStats
+	err := p.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE is_admin),
+			(SELECT COUNT(*) FROM users WHERE banned_at IS NOT NULL),
+			(SELECT COUNT(*) FROM posts),
+			(SELECT COUNT(*) FROM comments),
+			(SELECT COUNT(*) FROM messages),
+			(SELECT COUNT(*) FROM categories),
+			(SELECT COUNT(*) FROM tags),
+			(SELECT COUNT(*) FROM sessions WHERE expires_at > NOW()),
+			(SELECT COUNT(*) FROM presence WHERE status = 'online')`,
+	).Scan(&s.Users, &s.Admins, &s.BannedUsers, &s.Posts, &s.Comments, &s.Messages,
+		&s.Categories, &s.Tags, &s.ActiveSessions, &s.OnlineUsers)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CategoryRepo
+const pgCategoryColumns = "id, name, slug, COALESCE(description, ''), parent_id, sort_order, archived, created_at"
+
+func (p *PostgresAdapter) ListCategories(ctx context.Context, includeArchived bool) ([]models.Category, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// LOWER вместо COLLATE NOCASE из SQLite
+	rows, err := p.DB.QueryContext(ctx, "SELECT "+pgCategoryColumns+" FROM categories ORDER BY sort_order, LOWER(name), id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []models.Category
+	for rows.Next() {
+		c, err := pgScanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		if c.Archived && !includeArchived {
+			continue
+		}
+		all = append(all, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return database.BuildCategoryTree(all), nil
+}
+
+func (p *PostgresAdapter) GetCategory(ctx context.Context, id int) (*models.Category, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return pgScanCategory(p.DB.QueryRowContext(ctx, "SELECT "+pgCategoryColumns+" FROM categories WHERE id = $1", id))
+}
+
+func (p *PostgresAdapter) CreateCategory(ctx context.Context, c *models.Category) (int, error) {
+	if c.ParentID != nil {
+		if _, err := p.GetCategory(ctx, *c.ParentID); err != nil {
+			return 0, err
+		}
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := p.DB.QueryRowContext(ctx,
+		"INSERT INTO categories (name, slug, description, parent_id, sort_order, archived) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.Archived,
+	).Scan(&id)
+	if err != nil {
+		return 0, pgCategoryError(err)
+	}
+	return id, nil
+}
+
+func (p *PostgresAdapter) UpdateCategory(ctx context.Context, c *models.Category) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	if c.ParentID != nil {
+		cycle, err := p.isCategoryInSubtree(ctx, *c.ParentID, c.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return database.ErrCategoryCycle
+		}
+	}
+
+	res, err := p.DB.ExecContext(ctx,
+		"UPDATE categories SET name = $1, slug = $2, description = $3, parent_id = $4, sort_order = $5, archived = $6 WHERE id = $7",
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.Archived, c.ID,
+	)
+	if err != nil {
+		return pgCategoryError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isCategoryInSubtree mirrors the SQLite walk up the parent chain from id
+func (p *PostgresAdapter) isCategoryInSubtree(ctx context.Context, id, root int) (bool, error) {
+	seen := map[int]bool{}
+	for {
+		if id == root || seen[id] {
+			return true, nil
+		}
+		seen[id] = true
+
+		var parent sql.NullInt64
+		if err := p.DB.QueryRowContext(ctx, "SELECT parent_id FROM categories WHERE id = $1", id).Scan(&parent); err != nil {
+			return false, err
+		}
+		if !parent.Valid {
+			return false, nil
+		}
+		id = int(parent.Int64)
+	}
+}
+
+func (p *PostgresAdapter) DeleteCategory(ctx context.Context, id int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (p *PostgresAdapter) CountCategoryUsage(ctx context.Context, id int) (children, posts int, err error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	err = p.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM categories WHERE parent_id = $1),
+			(SELECT COUNT(*) FROM post_categories WHERE category_id = $1)`,
+		id,
+	).Scan(&children, &posts)
+	return children, posts, err
+}
+
+type pgRowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func pgScanCategory(row pgRowScanner) (*models.Category, error) {
+	var (
+		c      models.Category
+		parent sql.NullInt64
+	)
+	if err := row.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &parent, &c.SortOrder, &c.Archived, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if parent.Valid {
+		id := int(parent.Int64)
+		c.ParentID = &id
+	}
+	return &c, nil
+}
+
+func pgCategoryError(err error) error {
+	if isUniqueViolation(err) {
+		return database.ErrCategoryExists
+	}
+	return err
+}
+
+// isUniqueViolation reports a unique_violation (SQLSTATE 23505)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// PostRepo
+func (p *PostgresAdapter) SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT t.name, COUNT(pt.post_id) AS uses
+		FROM tags t
+		JOIN post_tags pt ON t.id = pt.tag_id
+		WHERE LEFT(t.name, LENGTH($1)) = $1
+		GROUP BY t.id
+		ORDER BY uses DESC, t.name
+		LIMIT $2`,
+		prefix, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// BlockRepo
+func (p *PostgresAdapter) Block(ctx context.Context, blocker, blocked int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"INSERT INTO blocks (blocker_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		blocker, blocked,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) Unblock(ctx context.Context, blocker, blocked int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2", blocker, blocked)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *PostgresAdapter) ListBlocked(ctx context.Context, blocker int) ([]models.BlockedUser, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT u.id, u.username, b.created_at
+		FROM blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC`,
+		blocker,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.BlockedUser{}
+	for rows.Next() {
+		var b models.BlockedUser
+		if err := rows.Scan(&b.ID, &b.Username, &b.BlockedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// MessageRepo
+func (p *PostgresAdapter) ChatUsers(ctx context.Context, userID int) ([]models.ChatUser, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// last_message_at в том же виде, что datetime() в SQLite
+	rows, err := p.DB.QueryContext(ctx, `
+		SELECT
+			u.id,
+			u.username,
+			COALESCE(MAX(p.status), 'offline'),
+			TO_CHAR(MAX(m.created_at) AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+		FROM users u
+		LEFT JOIN presence p ON p.user_id = u.id
+		LEFT JOIN messages m ON ((m.from_user = u.id AND m.to_user = $1) OR (m.from_user = $1 AND m.to_user = u.id))
+		WHERE u.id != $1
+		  AND u.id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $1)
+		GROUP BY u.id
+		ORDER BY MAX(m.created_at) DESC NULLS LAST, LOWER(u.username)`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.ChatUser
+	for rows.Next() {
+		var (
+			user          models.ChatUser
+			lastMessageAt sql.NullString
+		)
+		if err := rows.Scan(&user.ID, &user.Username, &user.Status, &lastMessageAt); err != nil {
+			return nil, err
+		}
+		user.LastMessageAt = lastMessageAt.String
+		out = append(out, user)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,578 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Sessions, single-use tokens, 2FA, API tokens, login throttling and
+// identity providers on PostgreSQL. Errors match the SQLite functions of
+// package database (database.ErrInvalidToken, database.ErrIdentityLinked).
+
+// SessionRepo
+func (p *PostgresAdapter) GetSession(ctx context.Context, id string) (int, time.Time, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		userID    int
+		expiresAt time.Time
+	)
+	err := p.DB.QueryRowContext(ctx, "SELECT user_id, expires_at FROM sessions WHERE id = $1", id).Scan(&userID, &expiresAt)
+	return userID, expiresAt, err
+}
+
+func (p *PostgresAdapter) ReplaceSessions(ctx context.Context, userID int, id string, expiresAt time.Time) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO sessions (id, user_id, expires_at) VALUES ($1, $2, $3)",
+			id, userID, expiresAt.UTC(),
+		)
+		return err
+	})
+}
+
+func (p *PostgresAdapter) DeleteSession(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *PostgresAdapter) DeleteUserSessions(ctx context.Context, userID int) (int64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *PostgresAdapter) DeleteOtherSessions(ctx context.Context, userID int, keepID string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1 AND id != $2", userID, keepID)
+	return err
+}
+
+func (p *PostgresAdapter) ListSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx,
+		"SELECT id, user_id, expires_at, created_at FROM sessions WHERE user_id = $1 AND expires_at > NOW() ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.ExpiresAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *PostgresAdapter) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// TokenRepo
+func (p *PostgresAdapter) CreateToken(ctx context.Context, userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		tokenHash, userID, purpose, expiresAt.UTC(), time.Now().UTC(),
+	)
+	return err
+}
+
+// ConsumeToken checks and spends the token in one statement, so two
+// requests cannot both consume it
+func (p *PostgresAdapter) ConsumeToken(ctx context.Context, purpose, tokenHash string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var userID int
+	err := p.DB.QueryRowContext(ctx, `
+		UPDATE user_tokens SET used_at = $1
+		WHERE token_hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING user_id`,
+		now, tokenHash, purpose,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, database.ErrInvalidToken
+	}
+	return userID, err
+}
+
+func (p *PostgresAdapter) PeekToken(ctx context.Context, purpose, tokenHash string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var userID int
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT user_id FROM user_tokens WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3",
+		tokenHash, purpose, time.Now().UTC(),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, database.ErrInvalidToken
+	}
+	return userID, err
+}
+
+func (p *PostgresAdapter) InvalidateTokens(ctx context.Context, userID int, purpose string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE user_tokens SET used_at = $1 WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL",
+		time.Now().UTC(), userID, purpose,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) LatestTokenAt(ctx context.Context, userID int, purpose string) (time.Time, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var latest sql.NullTime
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT MAX(created_at) FROM user_tokens WHERE user_id = $1 AND purpose = $2",
+		userID, purpose,
+	).Scan(&latest)
+	return latest.Time, err
+}
+
+// TwoFactorRepo
+func (p *PostgresAdapter) GetTOTPState(ctx context.Context, userID int) (*database.TOTPState, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		secret    sql.NullString
+		enabledAt sql.NullTime
+		state     database.TOTPState
+	)
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1",
+		userID,
+	).Scan(&secret, &enabledAt, &state.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	state.Secret = secret.String
+	state.Enabled = enabledAt.Valid && secret.Valid
+	return &state, nil
+}
+
+func (p *PostgresAdapter) SetPendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE users SET totp_secret = $1, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $2 AND totp_enabled_at IS NULL",
+		secret, userID,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $1 WHERE id = $2",
+			step, userID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, UNNEST($2::TEXT[])",
+			userID, pq.Array(codeHashes),
+		)
+		return err
+	})
+}
+
+func (p *PostgresAdapter) DisableTOTP(ctx context.Context, userID int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1",
+			userID,
+		); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+func (p *PostgresAdapter) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx,
+		"UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1",
+		step, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (p *PostgresAdapter) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// один код, даже если хеши совпали у двух строк
+	res, err := p.DB.ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1 FOR UPDATE
+		)`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (p *PostgresAdapter) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// APITokenRepo
+const pgAPITokenColumns = "id, user_id, name, scopes, expires_at, last_used_at, created_at"
+
+func (p *PostgresAdapter) CreateAPIToken(ctx context.Context, userID int, name, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := p.DB.QueryRowContext(ctx,
+		"INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		userID, name, tokenHash, strings.Join(scopes, ","), expiresAt.UTC(),
+	).Scan(&id)
+	return id, err
+}
+
+func (p *PostgresAdapter) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return pgScanAPIToken(p.DB.QueryRowContext(ctx, "SELECT "+pgAPITokenColumns+" FROM api_tokens WHERE token_hash = $1", tokenHash))
+}
+
+func (p *PostgresAdapter) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, "SELECT "+pgAPITokenColumns+" FROM api_tokens WHERE user_id = $1 ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		t, err := pgScanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+func (p *PostgresAdapter) DeleteAPIToken(ctx context.Context, userID, tokenID int) (bool, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *PostgresAdapter) DeleteUserAPITokens(ctx context.Context, userID int) (int64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM api_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *PostgresAdapter) TouchAPIToken(ctx context.Context, tokenID int, now time.Time, resolution time.Duration) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE api_tokens SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)",
+		now.UTC(), tokenID, now.Add(-resolution).UTC(),
+	)
+	return err
+}
+
+func pgScanAPIToken(row pgRowScanner) (*models.APIToken, error) {
+	var (
+		t        models.APIToken
+		scopes   string
+		lastUsed sql.NullTime
+	)
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.ExpiresAt, &lastUsed, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	return &t, nil
+}
+
+// LoginAttemptRepo
+func (p *PostgresAdapter) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		a           models.LoginAttempt
+		lastFailure sql.NullTime
+		lockedUntil sql.NullTime
+	)
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1",
+		key,
+	).Scan(&a.Key, &a.Failures, &lastFailure, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.LastFailureAt = lastFailure.Time
+	a.LockedUntil = lockedUntil.Time
+	return &a, nil
+}
+
+func (p *PostgresAdapter) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var failures int
+	err := p.DB.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.last_failure_at < $3 THEN NULL ELSE login_attempts.locked_until END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`,
+		key, now.UTC(), since.UTC(),
+	).Scan(&failures)
+	return failures, err
+}
+
+func (p *PostgresAdapter) ExtendLoginLockout(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE login_attempts SET locked_until = $1 WHERE key = $2 AND (locked_until IS NULL OR locked_until < $1)",
+		until.UTC(), key,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) ClearLoginAttempts(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = ANY($1)", pq.Array(keys))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// IdentityRepo
+func (p *PostgresAdapter) SaveOIDCState(ctx context.Context, st database.OIDCState) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := p.DB.ExecContext(ctx, "DELETE FROM oidc_states WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	var linkUserID interface{}
+	if st.LinkUserID != 0 {
+		linkUserID = st.LinkUserID
+	}
+	_, err := p.DB.ExecContext(ctx,
+		"INSERT INTO oidc_states (state_hash, nonce, code_verifier, link_user_id, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		st.StateHash, st.Nonce, st.CodeVerifier, linkUserID, st.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+func (p *PostgresAdapter) ConsumeOIDCState(ctx context.Context, stateHash string) (*database.OIDCState, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		st         database.OIDCState
+		linkUserID sql.NullInt64
+	)
+	err := p.DB.QueryRowContext(ctx,
+		"DELETE FROM oidc_states WHERE state_hash = $1 RETURNING state_hash, nonce, code_verifier, link_user_id, expires_at",
+		stateHash,
+	).Scan(&st.StateHash, &st.Nonce, &st.CodeVerifier, &linkUserID, &st.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, database.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(st.ExpiresAt) {
+		return nil, database.ErrInvalidToken
+	}
+	st.LinkUserID = int(linkUserID.Int64)
+	return &st, nil
+}
+
+func (p *PostgresAdapter) UserIDByIdentity(ctx context.Context, provider, subject string) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var userID int
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2",
+		provider, subject,
+	).Scan(&userID)
+	return userID, err
+}
+
+func (p *PostgresAdapter) TouchIdentity(ctx context.Context, provider, subject, email string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx,
+		"UPDATE user_identities SET last_login_at = NOW(), email = $1 WHERE provider = $2 AND subject = $3",
+		email, provider, subject,
+	)
+	return err
+}
+
+func (p *PostgresAdapter) LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	return pgLinkIdentity(ctx, p.DB, userID, provider, subject, email)
+}
+
+func pgLinkIdentity(ctx context.Context, db database.DBTX, userID int, provider, subject, email string) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) VALUES ($1, $2, $3, $4, NOW())",
+		userID, provider, subject, email,
+	)
+	if isUniqueViolation(err) {
+		return database.ErrIdentityLinked
+	}
+	return err
+}
+
+func (p *PostgresAdapter) CreateExternalUser(ctx context.Context, u database.ExternalUser) (int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var verifiedAt interface{}
+	if u.EmailVerified {
+		verifiedAt = time.Now().UTC()
+	}
+
+	var id int
+	err := database.WithTx(ctx, p.DB, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (email, username, password_hash, first_name, last_name, email_verified_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+			u.Email, u.Username, database.NoPassword, u.FirstName, u.LastName, verifiedAt,
+		).Scan(&id); err != nil {
+			return err
+		}
+		return pgLinkIdentity(ctx, tx, id, u.Provider, u.Subject, u.Email)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
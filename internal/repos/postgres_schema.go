@@ -0,0 +1,292 @@
+package repos
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// postgresMigrations is the PostgreSQL counterpart of the SQLite schema in
+// package database. Steps are applied in order, each once, and recorded in
+// schema_migrations; append new steps, never edit applied ones.
+var postgresMigrations = []string{
+	// 1: initial schema
+	`
+CREATE TABLE users (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	username TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	age INTEGER NOT NULL DEFAULT 0,
+	gender TEXT NOT NULL DEFAULT '',
+	first_name TEXT NOT NULL DEFAULT '',
+	last_name TEXT NOT NULL DEFAULT '',
+	bio TEXT NOT NULL DEFAULT '',
+	avatar_url TEXT NOT NULL DEFAULT '',
+	is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+	email_verified_at TIMESTAMPTZ,
+	name_visibility TEXT NOT NULL DEFAULT 'members',
+	age_visibility TEXT NOT NULL DEFAULT 'members',
+	gender_visibility TEXT NOT NULL DEFAULT 'members',
+	dm_policy TEXT NOT NULL DEFAULT 'everyone',
+	hide_blocked_content BOOLEAN NOT NULL DEFAULT TRUE,
+	deletion_requested_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_users_email_lower ON users (LOWER(email));
+CREATE INDEX idx_users_username_lower ON users (LOWER(username));
+
+CREATE TABLE categories (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	slug TEXT NOT NULL UNIQUE,
+	description TEXT,
+	parent_id INTEGER REFERENCES categories (id) ON DELETE SET NULL,
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE posts (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES users (id) ON DELETE SET NULL, -- NULL once the author's account is deleted
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE comments (
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_comments_post ON comments (post_id);
+
+CREATE TABLE post_categories (
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, category_id)
+);
+
+CREATE TABLE post_likes (
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	is_like BOOLEAN NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (post_id, user_id)
+);
+
+CREATE TABLE comment_likes (
+	id SERIAL PRIMARY KEY,
+	comment_id INTEGER NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	is_like BOOLEAN NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (comment_id, user_id)
+);
+
+CREATE TABLE tags (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX idx_post_tags_tag ON post_tags (tag_id);
+
+CREATE TABLE blocks (
+	blocker_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	blocked_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (blocker_id, blocked_id)
+);
+CREATE INDEX idx_blocks_blocked ON blocks (blocked_id);
+
+CREATE TABLE messages (
+	id SERIAL PRIMARY KEY,
+	from_user INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	to_user INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_messages_pair ON messages (from_user, to_user);
+
+CREATE TABLE presence (
+	user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	nickname TEXT,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO categories (name, slug, description, sort_order) VALUES
+	('Job Search', 'job-search', 'Discussions about searching for jobs and career advice', 1),
+	('Job Offers', 'job-offers', 'Posts with job offers and recruitment', 2),
+	('Company Reviews', 'company-reviews', 'Reviews and feedback about companies', 3),
+	('Job Search Tips', 'job-search-tips', 'Tips and advice for job seekers', 4),
+	('Hiring Tips', 'hiring-tips', 'Advice for employers on hiring and recruitment', 5),
+	('Internships', 'internships', 'Internship opportunities and experiences', 6);
+`,
+	// 2: account bans (forumctl users ban)
+	`ALTER TABLE users ADD COLUMN banned_at TIMESTAMPTZ`,
+	// 3: named reactions instead of the like/dislike flag
+	`ALTER TABLE post_likes ADD COLUMN reaction TEXT;
+UPDATE post_likes SET reaction = CASE WHEN is_like THEN 'like' ELSE 'dislike' END;
+ALTER TABLE post_likes ALTER COLUMN reaction SET NOT NULL, DROP COLUMN is_like;
+ALTER TABLE comment_likes ADD COLUMN reaction TEXT;
+UPDATE comment_likes SET reaction = CASE WHEN is_like THEN 'like' ELSE 'dislike' END;
+ALTER TABLE comment_likes ALTER COLUMN reaction SET NOT NULL, DROP COLUMN is_like;`,
+	// 4: cached reaction counts, recounted by triggers on every change of the
+	// reaction tables; the trigger locks the target row first, so concurrent
+	// changes recount one after another and each sees the ones before it
+	`ALTER TABLE posts ADD COLUMN reaction_counts JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE comments ADD COLUMN reaction_counts JSONB NOT NULL DEFAULT '{}';
+
+CREATE FUNCTION count_post_reactions() RETURNS trigger AS $$
+DECLARE
+	target INTEGER := CASE WHEN TG_OP = 'DELETE' THEN OLD.post_id ELSE NEW.post_id END;
+BEGIN
+	PERFORM 1 FROM posts WHERE id = target FOR UPDATE;
+	UPDATE posts SET reaction_counts = (
+		SELECT COALESCE(jsonb_object_agg(reaction, n), '{}') FROM
+			(SELECT reaction, COUNT(*) AS n FROM post_likes WHERE post_id = target GROUP BY reaction) r
+	) WHERE id = target;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION count_comment_reactions() RETURNS trigger AS $$
+DECLARE
+	target INTEGER := CASE WHEN TG_OP = 'DELETE' THEN OLD.comment_id ELSE NEW.comment_id END;
+BEGIN
+	PERFORM 1 FROM comments WHERE id = target FOR UPDATE;
+	UPDATE comments SET reaction_counts = (
+		SELECT COALESCE(jsonb_object_agg(reaction, n), '{}') FROM
+			(SELECT reaction, COUNT(*) AS n FROM comment_likes WHERE comment_id = target GROUP BY reaction) r
+	) WHERE id = target;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_likes_count AFTER INSERT OR UPDATE OF reaction OR DELETE ON post_likes
+	FOR EACH ROW EXECUTE FUNCTION count_post_reactions();
+CREATE TRIGGER comment_likes_count AFTER INSERT OR UPDATE OF reaction OR DELETE ON comment_likes
+	FOR EACH ROW EXECUTE FUNCTION count_comment_reactions();
+
+UPDATE posts p SET reaction_counts = (
+	SELECT COALESCE(jsonb_object_agg(reaction, n), '{}') FROM
+		(SELECT reaction, COUNT(*) AS n FROM post_likes WHERE post_id = p.id GROUP BY reaction) r
+);
+UPDATE comments c SET reaction_counts = (
+	SELECT COALESCE(jsonb_object_agg(reaction, n), '{}') FROM
+		(SELECT reaction, COUNT(*) AS n FROM comment_likes WHERE comment_id = c.id GROUP BY reaction) r
+);`,
+	// 5: sessions, login throttling, single-use tokens, 2FA, identity
+	// providers and API tokens, so the whole server can run on PostgreSQL
+	`ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE sessions (
+	id TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_sessions_user ON sessions (user_id);
+
+CREATE TABLE login_attempts (
+	key TEXT PRIMARY KEY, -- "ip:<addr>", "user:<id>" or "account:<identifier>"
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMPTZ,
+	locked_until TIMESTAMPTZ
+);
+
+CREATE TABLE user_tokens (
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	purpose TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_user_tokens_user ON user_tokens (user_id, purpose);
+
+CREATE TABLE recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_recovery_codes_user ON recovery_codes (user_id);
+
+CREATE TABLE user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_login_at TIMESTAMPTZ,
+	UNIQUE (provider, subject)
+);
+CREATE INDEX idx_user_identities_user ON user_identities (user_id);
+
+CREATE TABLE oidc_states (
+	state_hash TEXT PRIMARY KEY,
+	nonce TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	link_user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE api_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_api_tokens_user ON api_tokens (user_id);`,
+}
+
+// MigratePostgres applies the pending postgresMigrations, each in its own transaction
+func MigratePostgres(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return fmt.Errorf("migration failed: %v", err)
+	}
+
+	var current int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("migration failed: %v", err)
+	}
+
+	for i := current; i < len(postgresMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		if _, err := tx.Exec(postgresMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+	}
+	return nil
+}
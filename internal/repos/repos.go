@@ -1,32 +1,389 @@
+// Package repos defines the storage interfaces the server, the services and
+// forumctl work on, with a SQLite and a PostgreSQL implementation that the
+// contract tests run through the same scenarios.
+//
+// The backend is picked with DB_DIALECT (see Open and New). The backup,
+// restore and seed commands copy SQLite files and stay SQLite-only.
 package repos
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"real-time-forum/internal/database"
 	"real-time-forum/internal/models"
 )
 
+// ErrInvalidCategory is returned when a post is filed under a category that
+// does not exist or is archived
+var ErrInvalidCategory = errors.New("invalid category")
+
 // UserRepo defines methods to access users
 type UserRepo interface {
-	GetByID(id int) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// GetByLogin finds a user by e-mail or username, ignoring case
+	GetByLogin(ctx context.Context, identifier string) (*models.User, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	// CreateUser stores u with its PasswordHash and returns the new ID
+	CreateUser(ctx context.Context, u *models.User) (int, error)
+	// GetByEmail finds a user by e-mail, ignoring case
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// IDByLogin resolves an e-mail or username, ignoring case
+	IDByLogin(ctx context.Context, identifier string) (int, error)
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	IsEmailVerified(ctx context.Context, userID int) (bool, error)
+	// MarkEmailVerified keeps the first verification time
+	MarkEmailVerified(ctx context.Context, userID int) error
+	GetPrivacySettings(ctx context.Context, userID int) (*models.PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, userID int, p *models.PrivacySettings) error
+}
+
+// ProfileRepo defines methods behind the profile page
+type ProfileRepo interface {
+	// GetProfile loads the public fields; stats and activity are separate
+	GetProfile(ctx context.Context, userID int) (*models.UserProfile, error)
+	UpdateProfile(ctx context.Context, p *models.UserProfile) error
+	GetStats(ctx context.Context, userID int) (models.UserStats, error)
+	RecentPosts(ctx context.Context, userID, limit int) ([]models.Post, error)
+	// RecentComments fills PostTitle
+	RecentComments(ctx context.Context, userID, limit int) ([]models.Comment, error)
+}
+
+// AccountRepo defines the account lifecycle and the personal data export
+type AccountRepo interface {
+	// RequestDeletion marks the account and revokes its sessions and API tokens
+	RequestDeletion(ctx context.Context, userID int, now time.Time) error
+	CancelDeletion(ctx context.Context, userID int) (bool, error)
+	DeletionRequestedAt(ctx context.Context, userID int) (*time.Time, error)
+	// PurgeDeleted removes accounts whose deletion was requested at or before cutoff
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error)
+	AccountInfo(ctx context.Context, userID int) (emailVerifiedAt, deletionRequestedAt *time.Time, err error)
+	UserComments(ctx context.Context, userID int) ([]models.Comment, error)
+	UserPostReactions(ctx context.Context, userID int) ([]models.Reaction, error)
+	UserCommentReactions(ctx context.Context, userID int) ([]models.Reaction, error)
+	UserMessages(ctx context.Context, userID int) ([]models.Message, error)
+	UserIdentities(ctx context.Context, userID int) ([]models.Identity, error)
+}
+
+// AdminRepo defines the moderation tools of forumctl and the admin API
+type AdminRepo interface {
+	ListUsers(ctx context.Context) ([]models.AccountSummary, error)
+	// Ban revokes sessions and API tokens; false when already banned
+	Ban(ctx context.Context, userID int, now time.Time) (bool, error)
+	Unban(ctx context.Context, userID int) (bool, error)
+	// IsBanned is false for unknown users
+	IsBanned(ctx context.Context, userID int) (bool, error)
+	// IsAdmin is false for unknown users
+	IsAdmin(ctx context.Context, userID int) (bool, error)
+	// SetAdmin returns sql.ErrNoRows for unknown users
+	SetAdmin(ctx context.Context, userID int, admin bool) error
+	Stats(ctx context.Context) (*models.ForumStats, error)
+}
+
+// SessionRepo defines methods to access login sessions
+type SessionRepo interface {
+	// GetSession returns the owner and expiry of a session (sql.ErrNoRows if unknown)
+	GetSession(ctx context.Context, id string) (userID int, expiresAt time.Time, err error)
+	// ReplaceSessions stores a new session and drops every other session of the user
+	ReplaceSessions(ctx context.Context, userID int, id string, expiresAt time.Time) error
+	DeleteSession(ctx context.Context, id string) (bool, error)
+	DeleteUserSessions(ctx context.Context, userID int) (int64, error)
+	// DeleteOtherSessions keeps only the session keepID
+	DeleteOtherSessions(ctx context.Context, userID int, keepID string) error
+	// ListSessions returns the unexpired sessions, newest first
+	ListSessions(ctx context.Context, userID int) ([]models.Session, error)
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+}
+
+// TokenRepo defines methods to access single-use tokens (password reset,
+// e-mail verification, the pending second login step); only hashes are stored
+// and invalid tokens give database.ErrInvalidToken
+type TokenRepo interface {
+	CreateToken(ctx context.Context, userID int, purpose, tokenHash string, expiresAt time.Time) error
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (int, error)
+	PeekToken(ctx context.Context, purpose, tokenHash string) (int, error)
+	InvalidateTokens(ctx context.Context, userID int, purpose string) error
+	// LatestTokenAt is the zero time when none was issued
+	LatestTokenAt(ctx context.Context, userID int, purpose string) (time.Time, error)
+}
+
+// TwoFactorRepo defines methods to access TOTP secrets and recovery codes
+type TwoFactorRepo interface {
+	GetTOTPState(ctx context.Context, userID int) (*database.TOTPState, error)
+	SetPendingTOTPSecret(ctx context.Context, userID int, secret string) error
+	// EnableTOTP activates the pending secret and replaces the recovery codes
+	EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	// AdvanceTOTPStep is false when this or a later step was already used
+	AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+// APITokenRepo defines methods to access personal API tokens
+type APITokenRepo interface {
+	CreateAPIToken(ctx context.Context, userID int, name, tokenHash string, scopes []string, expiresAt time.Time) (int, error)
+	// GetAPITokenByHash returns sql.ErrNoRows for unknown tokens; expiry is the caller's
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, tokenID int) (bool, error)
+	DeleteUserAPITokens(ctx context.Context, userID int) (int64, error)
+	// TouchAPIToken moves last_used_at only when it is older than resolution
+	TouchAPIToken(ctx context.Context, tokenID int, now time.Time, resolution time.Duration) error
+}
+
+// LoginAttemptRepo defines methods to access the login failure counters
+type LoginAttemptRepo interface {
+	// GetLoginAttempt returns nil when there is no counter
+	GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordLoginFailure atomically counts a failure; a counter last failed
+	// before since starts over
+	RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error)
+	// ExtendLoginLockout never shortens a longer lockout
+	ExtendLoginLockout(ctx context.Context, key string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, keys ...string) (int64, error)
+}
+
+// IdentityRepo defines methods behind sign-in with an identity provider
+type IdentityRepo interface {
+	SaveOIDCState(ctx context.Context, st database.OIDCState) error
+	// ConsumeOIDCState returns database.ErrInvalidToken for unknown or expired states
+	ConsumeOIDCState(ctx context.Context, stateHash string) (*database.OIDCState, error)
+	// UserIDByIdentity returns sql.ErrNoRows when the subject is not linked
+	UserIDByIdentity(ctx context.Context, provider, subject string) (int, error)
+	TouchIdentity(ctx context.Context, provider, subject, email string) error
+	// LinkIdentity returns database.ErrIdentityLinked when the subject is taken
+	LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error
+	// CreateExternalUser creates a user without a password together with its identity
+	CreateExternalUser(ctx context.Context, u database.ExternalUser) (int, error)
+}
+
+// CategoryRepo defines methods to access categories. Name and slug
+// conflicts give database.ErrCategoryExists, moving a category under itself
+// database.ErrCategoryCycle and unknown IDs sql.ErrNoRows.
+type CategoryRepo interface {
+	// ListCategories returns the tree of top-level categories with their Children
+	ListCategories(ctx context.Context, includeArchived bool) ([]models.Category, error)
+	GetCategory(ctx context.Context, id int) (*models.Category, error)
+	CreateCategory(ctx context.Context, c *models.Category) (int, error)
+	UpdateCategory(ctx context.Context, c *models.Category) error
+	DeleteCategory(ctx context.Context, id int) error
+	CountCategoryUsage(ctx context.Context, id int) (children, posts int, err error)
+}
+
+// PostFilter selects a post listing; the zero value means all posts
+type PostFilter struct {
+	AuthorID    int   // only posts written by this user
+	LikedBy     int   // only posts this user liked
+	CategoryIDs []int // only posts in at least one of these categories
+	ViewerID    int   // fills MyReaction with this user's reaction
+}
+
+// PostRepo defines methods to access posts
+type PostRepo interface {
+	// CreatePost stores the post with its categories and tags atomically
+	CreatePost(ctx context.Context, userID int, title, content string, categoryIDs []int, tags []string) (int, error)
+	// GetPost fills MyReaction for viewerID, 0 for none
+	GetPost(ctx context.Context, viewerID, id int) (*models.Post, error)
+	ListPosts(ctx context.Context, f PostFilter) ([]models.Post, error)
+	PostIDsByTags(ctx context.Context, tags []string) (map[int]bool, error)
+	PostIDsByCategories(ctx context.Context, categoryIDs []int) (map[int]bool, error)
+	// SearchTags returns up to limit used tags starting with prefix, most used first
+	SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error)
+}
+
+// CommentRepo defines methods to access comments
+type CommentRepo interface {
+	CreateComment(ctx context.Context, postID, userID int, content string) (int, error)
+	GetComment(ctx context.Context, id int) (*models.Comment, error)
+	// ListComments fills MyReaction for viewerID, 0 for none
+	ListComments(ctx context.Context, viewerID, postID int) ([]models.Comment, error)
+	CountComments(ctx context.Context, postID int) (int, error)
+}
+
+// ReactionRepo defines methods to toggle reactions. The toggles return the
+// target's new counts per reaction and the user's reaction after the toggle,
+// "" when it was taken back
+type ReactionRepo interface {
+	TogglePostReaction(ctx context.Context, userID, postID int, reaction string) (counts map[string]int, mine string, err error)
+	ToggleCommentReaction(ctx context.Context, userID, commentID int, reaction string) (counts map[string]int, mine string, err error)
+}
+
+// BlockRepo defines methods to access block lists
+type BlockRepo interface {
+	IsBlocked(ctx context.Context, blocker, blocked int) (bool, error)
+	BlockedIDs(ctx context.Context, blocker int) (map[int]bool, error)
+	// Block is a no-op when the block exists
+	Block(ctx context.Context, blocker, blocked int) error
+	Unblock(ctx context.Context, blocker, blocked int) (bool, error)
+	// ListBlocked returns the block list, most recent first
+	ListBlocked(ctx context.Context, blocker int) ([]models.BlockedUser, error)
 }
 
 // MessageRepo defines methods to access messages
 type MessageRepo interface {
-	Insert(from int, to int, content string) (int64, string, error)
-	GetBetween(a int, b int, offset int, limit int) ([]models.Comment, error)
-	CountBetween(a int, b int) (int, error)
+	Insert(ctx context.Context, from int, to int, content string) (int64, string, error)
+	GetBetween(ctx context.Context, a int, b int, offset int, limit int) ([]models.Comment, error)
+	CountBetween(ctx context.Context, a int, b int) (int, error)
+	// CanSendMessage applies the recipient's DM policy
+	CanSendMessage(ctx context.Context, from, to int) (bool, error)
+	// ChatUsers lists everyone but userID and the users they blocked, most
+	// recent conversation first
+	ChatUsers(ctx context.Context, userID int) ([]models.ChatUser, error)
 }
 
 // PresenceService provides presence-related operations
 type PresenceService interface {
-	SetOnline(userID int, nickname string) error
-	SetOffline(userID int) error
-	ListOnline() ([]map[string]interface{}, error)
+	SetOnline(ctx context.Context, userID int, nickname string) error
+	SetOffline(ctx context.Context, userID int) error
+	ListOnline(ctx context.Context) ([]map[string]interface{}, error)
 }
 
 // Repos groups repository interfaces for convenience
 type Repos struct {
-	Users    UserRepo
-	Messages MessageRepo
-	Presence PresenceService
+	Users         UserRepo
+	Profiles      ProfileRepo
+	Accounts      AccountRepo
+	Admin         AdminRepo
+	Sessions      SessionRepo
+	Tokens        TokenRepo
+	TwoFactor     TwoFactorRepo
+	APITokens     APITokenRepo
+	LoginAttempts LoginAttemptRepo
+	Identities    IdentityRepo
+	Categories    CategoryRepo
+	Posts         PostRepo
+	Comments      CommentRepo
+	Reactions     ReactionRepo
+	Blocks        BlockRepo
+	Messages      MessageRepo
+	Presence      PresenceService
+}
+
+// adapter is what both backends implement: every interface of Repos
+type adapter interface {
+	UserRepo
+	ProfileRepo
+	AccountRepo
+	AdminRepo
+	SessionRepo
+	TokenRepo
+	TwoFactorRepo
+	APITokenRepo
+	LoginAttemptRepo
+	IdentityRepo
+	CategoryRepo
+	PostRepo
+	CommentRepo
+	ReactionRepo
+	BlockRepo
+	MessageRepo
+	PresenceService
+}
+
+func newRepos(a adapter) *Repos {
+	return &Repos{
+		Users:         a,
+		Profiles:      a,
+		Accounts:      a,
+		Admin:         a,
+		Sessions:      a,
+		Tokens:        a,
+		TwoFactor:     a,
+		APITokens:     a,
+		LoginAttempts: a,
+		Identities:    a,
+		Categories:    a,
+		Posts:         a,
+		Comments:      a,
+		Reactions:     a,
+		Blocks:        a,
+		Messages:      a,
+		Presence:      a,
+	}
+}
+
+// NewSQLiteRepos returns Repos backed by a single SQLiteAdapter
+func NewSQLiteRepos(a *SQLiteAdapter) *Repos {
+	return newRepos(a)
+}
+
+// NewPostgresRepos returns Repos backed by a single PostgresAdapter
+func NewPostgresRepos(a *PostgresAdapter) *Repos {
+	return newRepos(a)
+}
+
+// Dialect names a storage backend; the values are the database/sql driver names
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite3"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Migrate brings the schema of db up to date for the given dialect
+func Migrate(db *sql.DB, d Dialect) error {
+	switch d {
+	case DialectSQLite:
+		return database.RunMigrations(db)
+	case DialectPostgres:
+		return MigratePostgres(db)
+	default:
+		return fmt.Errorf("unknown dialect %q", d)
+	}
+}
+
+// ParseDialect validates a DB_DIALECT value
+func ParseDialect(name string) (Dialect, error) {
+	switch d := Dialect(name); d {
+	case DialectSQLite, DialectPostgres:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown dialect %q (want %q or %q)", name, DialectSQLite, DialectPostgres)
+	}
+}
+
+// Open connects to the database of the dialect. For SQLite dsn is the path
+// of the database file and opts tune its two pools; for PostgreSQL dsn is a
+// lib/pq connection string and both pools are the same *sql.DB, since the
+// server itself handles concurrent writers
+func Open(d Dialect, dsn string, opts database.Options) (*database.Pools, error) {
+	switch d {
+	case DialectSQLite:
+		return database.InitDB(dsn, opts)
+	case DialectPostgres:
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %v", err)
+		}
+		db.SetMaxOpenConns(opts.MaxWriteConns + opts.MaxReadConns)
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to connect to database: %v", err)
+		}
+		log.Printf("Using PostgreSQL database")
+		return &database.Pools{Write: db, Read: db}, nil
+	default:
+		return nil, fmt.Errorf("unknown dialect %q", d)
+	}
+}
+
+// New migrates the database and returns the repositories of the dialect.
+// The SQLite adapter sends its reads to pools.Read
+func New(pools *database.Pools, d Dialect) (*Repos, error) {
+	if err := Migrate(pools.Write, d); err != nil {
+		return nil, err
+	}
+	switch d {
+	case DialectPostgres:
+		return NewPostgresRepos(NewPostgresAdapter(pools.Write)), nil
+	default:
+		return NewSQLiteRepos(&SQLiteAdapter{DB: pools.Write, Read: pools.Read}), nil
+	}
 }
@@ -1,55 +1,512 @@
 package repos
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
 	"real-time-forum/internal/database"
 	"real-time-forum/internal/models"
+	"real-time-forum/internal/utils"
 )
 
+// SQLiteAdapter writes through DB; lookups and listings go through Read
+// when it is set (the query-only pool of database.Pools), else through DB
 type SQLiteAdapter struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Read *sql.DB
 }
 
 func NewSQLiteAdapter(db *sql.DB) *SQLiteAdapter {
 	return &SQLiteAdapter{DB: db}
 }
 
+func (s *SQLiteAdapter) reader() *sql.DB {
+	if s.Read != nil {
+		return s.Read
+	}
+	return s.DB
+}
+
 // UserRepo
-func (s *SQLiteAdapter) GetByID(id int) (*models.User, error) {
-	return database.GetUserByID(s.DB, id)
+func (s *SQLiteAdapter) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return database.GetUserByID(ctx, s.reader(), id)
+}
+
+func (s *SQLiteAdapter) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return database.GetUserByUsername(ctx, s.reader(), username)
+}
+
+func (s *SQLiteAdapter) GetByLogin(ctx context.Context, identifier string) (*models.User, error) {
+	return database.GetUserByLogin(ctx, s.reader(), identifier)
+}
+
+func (s *SQLiteAdapter) EmailExists(ctx context.Context, email string) (bool, error) {
+	return database.EmailExists(ctx, s.reader(), email)
+}
+
+func (s *SQLiteAdapter) UsernameExists(ctx context.Context, username string) (bool, error) {
+	return database.UsernameExists(ctx, s.reader(), username)
+}
+
+func (s *SQLiteAdapter) CreateUser(ctx context.Context, u *models.User) (int, error) {
+	return database.CreateUser(ctx, s.DB, u)
+}
+
+func (s *SQLiteAdapter) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return database.GetUserByEmail(ctx, s.reader(), email)
+}
+
+func (s *SQLiteAdapter) IDByLogin(ctx context.Context, identifier string) (int, error) {
+	return database.GetUserIDByIdentifier(ctx, s.reader(), identifier)
+}
+
+func (s *SQLiteAdapter) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	return database.UpdateUserPassword(ctx, s.DB, userID, passwordHash)
+}
+
+func (s *SQLiteAdapter) IsEmailVerified(ctx context.Context, userID int) (bool, error) {
+	return database.IsEmailVerified(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) MarkEmailVerified(ctx context.Context, userID int) error {
+	return database.MarkEmailVerified(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) GetPrivacySettings(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	return database.GetPrivacySettings(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) UpdatePrivacySettings(ctx context.Context, userID int, p *models.PrivacySettings) error {
+	return database.UpdatePrivacySettings(ctx, s.DB, userID, p)
+}
+
+// ProfileRepo
+func (s *SQLiteAdapter) GetProfile(ctx context.Context, userID int) (*models.UserProfile, error) {
+	return database.GetUserProfile(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) UpdateProfile(ctx context.Context, p *models.UserProfile) error {
+	return database.UpdateUserProfile(ctx, s.DB, p)
+}
+
+func (s *SQLiteAdapter) GetStats(ctx context.Context, userID int) (models.UserStats, error) {
+	return database.GetUserStats(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) RecentPosts(ctx context.Context, userID, limit int) ([]models.Post, error) {
+	return database.GetRecentPostsByUserID(ctx, s.reader(), userID, limit)
+}
+
+func (s *SQLiteAdapter) RecentComments(ctx context.Context, userID, limit int) ([]models.Comment, error) {
+	return database.GetRecentCommentsByUserID(ctx, s.reader(), userID, limit)
+}
+
+// AccountRepo
+func (s *SQLiteAdapter) RequestDeletion(ctx context.Context, userID int, now time.Time) error {
+	return database.RequestAccountDeletion(ctx, s.DB, userID, now)
+}
+
+func (s *SQLiteAdapter) CancelDeletion(ctx context.Context, userID int) (bool, error) {
+	return database.CancelAccountDeletion(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) DeletionRequestedAt(ctx context.Context, userID int) (*time.Time, error) {
+	return database.GetDeletionRequestedAt(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error) {
+	return database.PurgeDeletedAccounts(ctx, s.DB, cutoff)
+}
+
+func (s *SQLiteAdapter) AccountInfo(ctx context.Context, userID int) (*time.Time, *time.Time, error) {
+	return database.GetAccountInfo(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) UserComments(ctx context.Context, userID int) ([]models.Comment, error) {
+	return database.GetCommentsByUserID(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) UserPostReactions(ctx context.Context, userID int) ([]models.Reaction, error) {
+	return database.GetUserReactions(ctx, s.reader(), "post_likes", userID)
+}
+
+func (s *SQLiteAdapter) UserCommentReactions(ctx context.Context, userID int) ([]models.Reaction, error) {
+	return database.GetUserReactions(ctx, s.reader(), "comment_likes", userID)
+}
+
+func (s *SQLiteAdapter) UserMessages(ctx context.Context, userID int) ([]models.Message, error) {
+	return database.GetUserMessages(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) UserIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	return database.GetUserIdentities(ctx, s.reader(), userID)
+}
+
+// AdminRepo
+func (s *SQLiteAdapter) ListUsers(ctx context.Context) ([]models.AccountSummary, error) {
+	return database.ListUsers(ctx, s.reader())
+}
+
+func (s *SQLiteAdapter) Ban(ctx context.Context, userID int, now time.Time) (bool, error) {
+	return database.BanUser(ctx, s.DB, userID, now)
+}
+
+func (s *SQLiteAdapter) Unban(ctx context.Context, userID int) (bool, error) {
+	return database.UnbanUser(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) IsBanned(ctx context.Context, userID int) (bool, error) {
+	return database.IsUserBanned(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	return database.IsUserAdmin(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) SetAdmin(ctx context.Context, userID int, admin bool) error {
+	return database.SetUserAdmin(ctx, s.DB, userID, admin)
+}
+
+func (s *SQLiteAdapter) Stats(ctx context.Context) (*models.ForumStats, error) {
+	return database.GetForumStats(ctx, s.reader())
+}
+
+// SessionRepo
+func (s *SQLiteAdapter) GetSession(ctx context.Context, id string) (int, time.Time, error) {
+	return database.GetSession(ctx, s.reader(), id)
+}
+
+func (s *SQLiteAdapter) ReplaceSessions(ctx context.Context, userID int, id string, expiresAt time.Time) error {
+	return database.ReplaceUserSessions(ctx, s.DB, userID, id, expiresAt)
+}
+
+func (s *SQLiteAdapter) DeleteSession(ctx context.Context, id string) (bool, error) {
+	return database.DeleteSession(ctx, s.DB, id)
+}
+
+func (s *SQLiteAdapter) DeleteUserSessions(ctx context.Context, userID int) (int64, error) {
+	return database.DeleteSessionsByUserID(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) DeleteOtherSessions(ctx context.Context, userID int, keepID string) error {
+	return database.TerminateAllOtherSessions(ctx, s.DB, keepID, userID)
+}
+
+func (s *SQLiteAdapter) ListSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	rows, err := database.GetUserSessions(ctx, s.reader(), userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]models.Session, 0, len(rows))
+	for _, r := range rows {
+		sessions = append(sessions, models.Session{ID: r.ID, UserID: userID, ExpiresAt: r.ExpiresAt, CreatedAt: r.CreatedAt})
+	}
+	return sessions, nil
+}
+
+func (s *SQLiteAdapter) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	return database.DeleteExpiredSessions(ctx, s.DB)
+}
+
+// TokenRepo
+func (s *SQLiteAdapter) CreateToken(ctx context.Context, userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	return database.CreateUserToken(ctx, s.DB, userID, purpose, tokenHash, expiresAt)
+}
+
+func (s *SQLiteAdapter) ConsumeToken(ctx context.Context, purpose, tokenHash string) (int, error) {
+	return database.ConsumeUserToken(ctx, s.DB, purpose, tokenHash)
+}
+
+func (s *SQLiteAdapter) PeekToken(ctx context.Context, purpose, tokenHash string) (int, error) {
+	return database.PeekUserToken(ctx, s.reader(), purpose, tokenHash)
+}
+
+func (s *SQLiteAdapter) InvalidateTokens(ctx context.Context, userID int, purpose string) error {
+	return database.InvalidateUserTokens(ctx, s.DB, userID, purpose)
+}
+
+func (s *SQLiteAdapter) LatestTokenAt(ctx context.Context, userID int, purpose string) (time.Time, error) {
+	return database.LatestUserTokenAt(ctx, s.reader(), userID, purpose)
+}
+
+// TwoFactorRepo
+func (s *SQLiteAdapter) GetTOTPState(ctx context.Context, userID int) (*database.TOTPState, error) {
+	return database.GetTOTPState(ctx, s.reader(), userID)
 }
 
-func (s *SQLiteAdapter) GetByUsername(username string) (*models.User, error) {
-	return database.GetUserByUsername(s.DB, username)
+func (s *SQLiteAdapter) SetPendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return database.SetPendingTOTPSecret(ctx, s.DB, userID, secret)
+}
+
+func (s *SQLiteAdapter) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	return database.EnableTOTP(ctx, s.DB, userID, step, codeHashes)
+}
+
+func (s *SQLiteAdapter) DisableTOTP(ctx context.Context, userID int) error {
+	return database.DisableTOTP(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) AdvanceTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	return database.AdvanceTOTPStep(ctx, s.DB, userID, step)
+}
+
+func (s *SQLiteAdapter) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	return database.UseRecoveryCode(ctx, s.DB, userID, codeHash)
+}
+
+func (s *SQLiteAdapter) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	return database.CountRecoveryCodes(ctx, s.reader(), userID)
+}
+
+// APITokenRepo
+func (s *SQLiteAdapter) CreateAPIToken(ctx context.Context, userID int, name, tokenHash string, scopes []string, expiresAt time.Time) (int, error) {
+	return database.CreateAPIToken(ctx, s.DB, userID, name, tokenHash, scopes, expiresAt)
+}
+
+func (s *SQLiteAdapter) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	return database.GetAPITokenByHash(ctx, s.reader(), tokenHash)
+}
+
+func (s *SQLiteAdapter) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	return database.ListAPITokens(ctx, s.reader(), userID)
+}
+
+func (s *SQLiteAdapter) DeleteAPIToken(ctx context.Context, userID, tokenID int) (bool, error) {
+	return database.DeleteAPIToken(ctx, s.DB, userID, tokenID)
+}
+
+func (s *SQLiteAdapter) DeleteUserAPITokens(ctx context.Context, userID int) (int64, error) {
+	return database.DeleteAPITokensByUserID(ctx, s.DB, userID)
+}
+
+func (s *SQLiteAdapter) TouchAPIToken(ctx context.Context, tokenID int, now time.Time, resolution time.Duration) error {
+	return database.TouchAPIToken(ctx, s.DB, tokenID, now, resolution)
+}
+
+// LoginAttemptRepo
+func (s *SQLiteAdapter) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	return database.GetLoginAttempt(ctx, s.reader(), key)
+}
+
+func (s *SQLiteAdapter) RecordLoginFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	return database.RecordLoginFailure(ctx, s.DB, key, now, since)
+}
+
+func (s *SQLiteAdapter) ExtendLoginLockout(ctx context.Context, key string, until time.Time) error {
+	return database.ExtendLoginLockout(ctx, s.DB, key, until)
+}
+
+func (s *SQLiteAdapter) ClearLoginAttempts(ctx context.Context, keys ...string) (int64, error) {
+	return database.ClearLoginAttempts(ctx, s.DB, keys...)
+}
+
+// IdentityRepo
+func (s *SQLiteAdapter) SaveOIDCState(ctx context.Context, st database.OIDCState) error {
+	return database.SaveOIDCState(ctx, s.DB, st)
+}
+
+func (s *SQLiteAdapter) ConsumeOIDCState(ctx context.Context, stateHash string) (*database.OIDCState, error) {
+	return database.ConsumeOIDCState(ctx, s.DB, stateHash)
+}
+
+func (s *SQLiteAdapter) UserIDByIdentity(ctx context.Context, provider, subject string) (int, error) {
+	return database.GetUserIDByIdentity(ctx, s.reader(), provider, subject)
+}
+
+func (s *SQLiteAdapter) TouchIdentity(ctx context.Context, provider, subject, email string) error {
+	return database.TouchIdentity(ctx, s.DB, provider, subject, email)
+}
+
+func (s *SQLiteAdapter) LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error {
+	return database.LinkIdentity(ctx, s.DB, userID, provider, subject, email)
+}
+
+func (s *SQLiteAdapter) CreateExternalUser(ctx context.Context, u database.ExternalUser) (int, error) {
+	return database.CreateExternalUser(ctx, s.DB, u)
+}
+
+// CategoryRepo
+func (s *SQLiteAdapter) ListCategories(ctx context.Context, includeArchived bool) ([]models.Category, error) {
+	return database.GetAllCategories(ctx, s.reader(), includeArchived)
+}
+
+func (s *SQLiteAdapter) GetCategory(ctx context.Context, id int) (*models.Category, error) {
+	return database.GetCategoryByID(ctx, s.reader(), id)
+}
+
+func (s *SQLiteAdapter) CreateCategory(ctx context.Context, c *models.Category) (int, error) {
+	return database.CreateCategory(ctx, s.DB, c)
+}
+
+func (s *SQLiteAdapter) UpdateCategory(ctx context.Context, c *models.Category) error {
+	return database.UpdateCategory(ctx, s.DB, c)
+}
+
+func (s *SQLiteAdapter) DeleteCategory(ctx context.Context, id int) error {
+	return database.DeleteCategory(ctx, s.DB, id)
+}
+
+func (s *SQLiteAdapter) CountCategoryUsage(ctx context.Context, id int) (int, int, error) {
+	return database.CountCategoryUsage(ctx, s.reader(), id)
+}
+
+// PostRepo
+func (s *SQLiteAdapter) CreatePost(ctx context.Context, userID int, title, content string, categoryIDs []int, tags []string) (int, error) {
+	var postID int
+	err := database.WithTx(ctx, s.DB, func(tx *sql.Tx) error {
+		// категории проверяем внутри транзакции: админ мог архивировать её только что
+		for _, id := range categoryIDs {
+			ok, err := database.CategoryAcceptsPosts(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrInvalidCategory
+			}
+		}
+
+		var err error
+		if postID, err = database.CreatePost(ctx, tx, userID, title, content); err != nil {
+			return err
+		}
+		for _, id := range categoryIDs {
+			if err := database.AddCategoryToPost(ctx, tx, postID, id); err != nil {
+				return err
+			}
+		}
+		return database.AddTagsToPost(ctx, tx, postID, tags)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return postID, nil
+}
+
+func (s *SQLiteAdapter) GetPost(ctx context.Context, viewerID, id int) (*models.Post, error) {
+	return database.GetPostByID(ctx, s.reader(), id, viewerID)
+}
+
+func (s *SQLiteAdapter) ListPosts(ctx context.Context, f PostFilter) ([]models.Post, error) {
+	switch {
+	case f.AuthorID > 0 && len(f.CategoryIDs) > 0:
+		return database.GetPostsByUserIDAndCategories(ctx, s.reader(), f.AuthorID, f.CategoryIDs, f.ViewerID)
+	case f.AuthorID > 0:
+		return database.GetPostsByUserID(ctx, s.reader(), f.AuthorID, f.ViewerID)
+	case f.LikedBy > 0 && len(f.CategoryIDs) > 0:
+		return database.GetLikedPostsByCategories(ctx, s.reader(), f.LikedBy, f.CategoryIDs, f.ViewerID)
+	case f.LikedBy > 0:
+		return database.GetLikedPosts(ctx, s.reader(), f.LikedBy, f.ViewerID)
+	case len(f.CategoryIDs) > 0:
+		return database.GetPostsByCategories(ctx, s.reader(), f.CategoryIDs, f.ViewerID)
+	default:
+		return database.GetAllPosts(ctx, s.reader(), f.ViewerID)
+	}
+}
+
+func (s *SQLiteAdapter) PostIDsByTags(ctx context.Context, tags []string) (map[int]bool, error) {
+	return database.GetPostIDsByTags(ctx, s.reader(), tags)
+}
+
+func (s *SQLiteAdapter) PostIDsByCategories(ctx context.Context, categoryIDs []int) (map[int]bool, error) {
+	return database.GetPostIDsByCategories(ctx, s.reader(), categoryIDs)
+}
+
+func (s *SQLiteAdapter) SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	return database.SearchTags(ctx, s.reader(), prefix, limit)
+}
+
+// CommentRepo
+func (s *SQLiteAdapter) CreateComment(ctx context.Context, postID, userID int, content string) (int, error) {
+	return database.CreateComment(ctx, s.DB, postID, userID, content)
+}
+
+func (s *SQLiteAdapter) GetComment(ctx context.Context, id int) (*models.Comment, error) {
+	return database.GetCommentByID(ctx, s.reader(), id)
+}
+
+func (s *SQLiteAdapter) ListComments(ctx context.Context, viewerID, postID int) ([]models.Comment, error) {
+	return database.GetCommentsByPostID(ctx, s.reader(), postID, viewerID)
+}
+
+func (s *SQLiteAdapter) CountComments(ctx context.Context, postID int) (int, error) {
+	return database.GetCommentCount(ctx, s.reader(), postID)
+}
+
+// ReactionRepo
+func (s *SQLiteAdapter) TogglePostReaction(ctx context.Context, userID, postID int, reaction string) (map[string]int, string, error) {
+	return utils.TogglePostReaction(ctx, s.DB, userID, postID, reaction)
+}
+
+func (s *SQLiteAdapter) ToggleCommentReaction(ctx context.Context, userID, commentID int, reaction string) (map[string]int, string, error) {
+	return utils.ToggleCommentReaction(ctx, s.DB, userID, commentID, reaction)
+}
+
+// BlockRepo
+func (s *SQLiteAdapter) IsBlocked(ctx context.Context, blocker, blocked int) (bool, error) {
+	return database.IsBlocked(ctx, s.reader(), blocker, blocked)
+}
+
+func (s *SQLiteAdapter) BlockedIDs(ctx context.Context, blocker int) (map[int]bool, error) {
+	return database.GetBlockedIDs(ctx, s.reader(), blocker)
+}
+
+func (s *SQLiteAdapter) Block(ctx context.Context, blocker, blocked int) error {
+	return database.BlockUser(ctx, s.DB, blocker, blocked)
+}
+
+func (s *SQLiteAdapter) Unblock(ctx context.Context, blocker, blocked int) (bool, error) {
+	return database.UnblockUser(ctx, s.DB, blocker, blocked)
+}
+
+func (s *SQLiteAdapter) ListBlocked(ctx context.Context, blocker int) ([]models.BlockedUser, error) {
+	return database.ListBlockedUsers(ctx, s.reader(), blocker)
 }
 
 // MessageRepo
-func (s *SQLiteAdapter) Insert(from int, to int, content string) (int64, string, error) {
-	return database.InsertMessage(s.DB, from, to, content)
+func (s *SQLiteAdapter) Insert(ctx context.Context, from int, to int, content string) (int64, string, error) {
+	return database.InsertMessage(ctx, s.DB, from, to, content)
+}
+
+func (s *SQLiteAdapter) GetBetween(ctx context.Context, a int, b int, offset int, limit int) ([]models.Comment, error) {
+	return database.GetMessagesBetween(ctx, s.reader(), a, b, offset, limit)
+}
+
+func (s *SQLiteAdapter) CountBetween(ctx context.Context, a int, b int) (int, error) {
+	return database.CountMessagesBetween(ctx, s.reader(), a, b)
 }
 
-func (s *SQLiteAdapter) GetBetween(a int, b int, offset int, limit int) ([]models.Comment, error) {
-	return database.GetMessagesBetween(s.DB, a, b, offset, limit)
+func (s *SQLiteAdapter) CanSendMessage(ctx context.Context, from, to int) (bool, error) {
+	return database.CanSendMessage(ctx, s.reader(), from, to)
 }
 
-func (s *SQLiteAdapter) CountBetween(a int, b int) (int, error) {
-	return database.CountMessagesBetween(s.DB, a, b)
+func (s *SQLiteAdapter) ChatUsers(ctx context.Context, userID int) ([]models.ChatUser, error) {
+	return database.ListChatUsers(ctx, s.reader(), userID)
 }
 
 // PresenceService
-func (s *SQLiteAdapter) SetOnline(userID int, nickname string) error {
-	_, err := s.DB.Exec("INSERT OR REPLACE INTO presence (user_id, status, nickname, updated_at) VALUES (?, 'online', ?, datetime('now'))", userID, nickname)
+func (s *SQLiteAdapter) SetOnline(ctx context.Context, userID int, nickname string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, "INSERT OR REPLACE INTO presence (user_id, status, nickname, updated_at) VALUES (?, 'online', ?, datetime('now'))", userID, nickname)
 	return err
 }
 
-func (s *SQLiteAdapter) SetOffline(userID int) error {
-	_, err := s.DB.Exec("UPDATE presence SET status='offline', updated_at = datetime('now') WHERE user_id = ?", userID)
+func (s *SQLiteAdapter) SetOffline(ctx context.Context, userID int) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, "UPDATE presence SET status='offline', updated_at = datetime('now') WHERE user_id = ?", userID)
 	return err
 }
 
-func (s *SQLiteAdapter) ListOnline() ([]map[string]interface{}, error) {
-	rows, err := s.DB.Query("SELECT user_id, nickname FROM presence WHERE status = 'online'")
+func (s *SQLiteAdapter) ListOnline(ctx context.Context) ([]map[string]interface{}, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.reader().QueryContext(ctx, "SELECT user_id, nickname FROM presence WHERE status = 'online'")
 	if err != nil {
 		return nil, err
 	}
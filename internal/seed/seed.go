@@ -0,0 +1,342 @@
+// Package seed fills a forum database with generated demo data: users,
+// posts in the existing categories, comment threads, reactions and private
+// conversations. The same Options produce the same rows on the same
+// starting database, so benchmarks and UI demos are reproducible. Only the
+// bcrypt salt differs between runs.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/utils"
+)
+
+// Options controls the amount of generated data
+type Options struct {
+	Seed     int64
+	Users    int
+	Posts    int
+	Comments int // spread over the posts, popular posts get more
+	Messages int // spread over conversations of about 20 messages
+	// Password of every generated user
+	Password string
+	// Reactions the forum accepts (config.Config.ReactionNames)
+	Reactions []string
+}
+
+// Result counts the rows Generate inserted
+type Result struct {
+	Users     int `json:"users"`
+	Posts     int `json:"posts"`
+	Comments  int `json:"comments"`
+	Reactions int `json:"reactions"`
+	Messages  int `json:"messages"`
+}
+
+// epoch is the earliest generated timestamp; everything happens within a year of it
+var epoch = time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+type user struct {
+	id   int
+	name string
+}
+
+type post struct {
+	id        int
+	createdAt time.Time
+	// commenters so far, replies mention one of them
+	commenters []string
+}
+
+// generator carries the state of one Generate call
+type generator struct {
+	ctx context.Context
+	tx  *sql.Tx
+	rnd *rand.Rand
+	res Result
+
+	users []user
+	posts []post
+	// reaction names repeated by weight, see reactionWeights
+	reactions []string
+}
+
+// Generate inserts the data described by opts in a single transaction
+func Generate(ctx context.Context, db *sql.DB, opts Options) (*Result, error) {
+	if opts.Users < 2 {
+		return nil, errors.New("at least 2 users are needed")
+	}
+	if err := utils.ValidatePasswordStrength(opts.Password); err != nil {
+		return nil, err
+	}
+	if len(opts.Reactions) == 0 {
+		return nil, errors.New("no reactions to pick from")
+	}
+	// один хеш на всех: bcrypt намеренно медленный
+	hash, err := utils.HashPassword(opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := activeCategoryIDs(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 && opts.Posts > 0 {
+		return nil, errors.New("no categories to post in")
+	}
+
+	g := &generator{ctx: ctx, rnd: rand.New(rand.NewSource(opts.Seed)), reactions: weighReactions(opts.Reactions)}
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		g.tx = tx
+		if err := g.genUsers(opts.Users, hash); err != nil {
+			return err
+		}
+		if err := g.genPosts(opts.Posts, categories); err != nil {
+			return err
+		}
+		if err := g.genComments(opts.Comments); err != nil {
+			return err
+		}
+		if err := g.genReactions(); err != nil {
+			return err
+		}
+		return g.genMessages(opts.Messages)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g.res, nil
+}
+
+func weighReactions(names []string) []string {
+	var out []string
+	for _, name := range names {
+		n := reactionWeights[name]
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func activeCategoryIDs(ctx context.Context, db *sql.DB) ([]int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM categories WHERE archived = 0 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// подкатегории архивного раздела тоже не принимают посты
+	active := ids[:0]
+	for _, id := range ids {
+		ok, err := database.CategoryAcceptsPosts(ctx, db, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			active = append(active, id)
+		}
+	}
+	return active, nil
+}
+
+// genUsers numbers the usernames after the last existing user, so seeding
+// again adds new accounts instead of colliding with the previous run
+func (g *generator) genUsers(n int, hash string) error {
+	var last int
+	if err := g.tx.QueryRowContext(g.ctx, "SELECT COALESCE(MAX(id), 0) FROM users").Scan(&last); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		first := pick(g.rnd, firstNames)
+		surname := pick(g.rnd, lastNames)
+		name := fmt.Sprintf("%s%d", strings.ToLower(first), last+i+1)
+		joined := g.at(epoch, 30*24*time.Hour)
+
+		res, err := g.tx.ExecContext(g.ctx, `
+			INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name, created_at, email_verified_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			name+"@example.com", name, hash, 18+g.rnd.Intn(45), pick(g.rnd, genders), first, surname, stamp(joined), stamp(joined),
+		)
+		if err != nil {
+			return fmt.Errorf("create user %s: %v", name, err)
+		}
+		id, _ := res.LastInsertId()
+		g.users = append(g.users, user{id: int(id), name: name})
+		g.res.Users++
+	}
+	return nil
+}
+
+func (g *generator) genPosts(n int, categories []int) error {
+	for i := 0; i < n; i++ {
+		author := pick(g.rnd, g.users)
+		title := fmt.Sprintf(pick(g.rnd, titleTemplates), pick(g.rnd, roles), pick(g.rnd, companies), pick(g.rnd, cities))
+		createdAt := g.at(epoch.Add(30*24*time.Hour), 300*24*time.Hour)
+
+		res, err := g.tx.ExecContext(g.ctx,
+			"INSERT INTO posts (user_id, title, content, created_at) VALUES (?, ?, ?, ?)",
+			author.id, title, g.paragraph(2, 5), stamp(createdAt),
+		)
+		if err != nil {
+			return err
+		}
+		id64, _ := res.LastInsertId()
+		id := int(id64)
+
+		// 1–2 категории и до трёх тегов
+		for _, idx := range g.rnd.Perm(len(categories))[:1+g.rnd.Intn(min(2, len(categories)))] {
+			if _, err := g.tx.ExecContext(g.ctx, "INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)", id, categories[idx]); err != nil {
+				return err
+			}
+		}
+		var tags []string
+		for _, idx := range g.rnd.Perm(len(tagNames))[:g.rnd.Intn(4)] {
+			tags = append(tags, tagNames[idx])
+		}
+		if err := database.AddTagsToPost(g.ctx, g.tx, id, tags); err != nil {
+			return err
+		}
+
+		g.posts = append(g.posts, post{id: id, createdAt: createdAt})
+		g.res.Posts++
+	}
+	return nil
+}
+
+// genComments spreads n comments over the posts, skewed towards the first
+// ones. Comments are flat, so a thread is a run of replies that address
+// earlier commenters by @name.
+func (g *generator) genComments(n int) error {
+	if len(g.posts) == 0 {
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		r := g.rnd.Float64()
+		p := &g.posts[int(r*r*float64(len(g.posts)))]
+		author := pick(g.rnd, g.users)
+
+		content := pick(g.rnd, replies)
+		if len(p.commenters) > 0 && g.rnd.Intn(10) < 4 {
+			content = "@" + pick(g.rnd, p.commenters) + " " + content
+		} else if g.rnd.Intn(3) == 0 {
+			content = g.paragraph(1, 2)
+		}
+		createdAt := g.at(p.createdAt, 14*24*time.Hour)
+
+		res, err := g.tx.ExecContext(g.ctx,
+			"INSERT INTO comments (post_id, user_id, content, created_at) VALUES (?, ?, ?, ?)",
+			p.id, author.id, content, stamp(createdAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, _ := res.LastInsertId()
+		p.commenters = append(p.commenters, author.name)
+		g.res.Comments++
+
+		if err := g.react("comment_likes", "comment_id", int(id), 5); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) genReactions() error {
+	for _, p := range g.posts {
+		if err := g.react("post_likes", "post_id", p.id, 15); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// react adds up to limit reactions from distinct users, most of them likes
+func (g *generator) react(table, column string, id, limit int) error {
+	k := g.rnd.Intn(min(limit, len(g.users)) + 1)
+	for _, idx := range g.rnd.Perm(len(g.users))[:k] {
+		_, err := g.tx.ExecContext(g.ctx,
+			"INSERT INTO "+table+" ("+column+", user_id, reaction) VALUES (?, ?, ?)",
+			id, g.users[idx].id, pick(g.rnd, g.reactions),
+		)
+		if err != nil {
+			return err
+		}
+		g.res.Reactions++
+	}
+	return nil
+}
+
+// genMessages writes n messages as conversations between random pairs
+func (g *generator) genMessages(n int) error {
+	for n > 0 {
+		size := min(n, 10+g.rnd.Intn(21))
+		perm := g.rnd.Perm(len(g.users))
+		a, b := g.users[perm[0]], g.users[perm[1]]
+		t := g.at(epoch.Add(30*24*time.Hour), 300*24*time.Hour)
+
+		for i := 0; i < size; i++ {
+			from, to := a, b
+			if g.rnd.Intn(2) == 0 {
+				from, to = b, a
+			}
+			t = t.Add(time.Duration(1+g.rnd.Intn(180)) * time.Minute)
+			if _, err := g.tx.ExecContext(g.ctx,
+				"INSERT INTO messages (from_user, to_user, content, created_at) VALUES (?, ?, ?, ?)",
+				from.id, to.id, pick(g.rnd, chatLines), stamp(t),
+			); err != nil {
+				return err
+			}
+			g.res.Messages++
+		}
+		n -= size
+	}
+	return nil
+}
+
+// paragraph joins between lo and hi distinct sentences
+func (g *generator) paragraph(lo, hi int) string {
+	n := lo + g.rnd.Intn(hi-lo+1)
+	parts := make([]string, 0, n)
+	for _, idx := range g.rnd.Perm(len(sentences))[:n] {
+		parts = append(parts, sentences[idx])
+	}
+	return strings.Join(parts, " ")
+}
+
+// at returns a moment within span after from, rounded to the second
+func (g *generator) at(from time.Time, span time.Duration) time.Time {
+	return from.Add(time.Duration(g.rnd.Int63n(int64(span/time.Second))) * time.Second)
+}
+
+func pick[T any](rnd *rand.Rand, items []T) T {
+	return items[rnd.Intn(len(items))]
+}
+
+// stamp formats t the way SQLite's CURRENT_TIMESTAMP does
+func stamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,98 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/utils"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	pools, err := database.InitDB(filepath.Join(t.TempDir(), "forum.db"), database.Options{BusyTimeout: time.Second, MaxWriteConns: 2, MaxReadConns: 1})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { pools.Close() })
+	if err := database.RunMigrations(pools.Write); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return pools.Write
+}
+
+// dump renders the generated rows in a stable order
+func dump(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	var b strings.Builder
+	for _, q := range []string{
+		"SELECT username || '|' || email || '|' || first_name || '|' || age || '|' || created_at FROM users ORDER BY id",
+		"SELECT user_id || '|' || title || '|' || content || '|' || created_at FROM posts ORDER BY id",
+		"SELECT post_id || '|' || category_id FROM post_categories ORDER BY post_id, category_id",
+		"SELECT pt.post_id || '|' || t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id ORDER BY pt.post_id, t.name",
+		"SELECT post_id || '|' || user_id || '|' || content || '|' || created_at FROM comments ORDER BY id",
+		"SELECT post_id || '|' || user_id || '|' || reaction FROM post_likes ORDER BY id",
+		"SELECT comment_id || '|' || user_id || '|' || reaction FROM comment_likes ORDER BY id",
+		"SELECT from_user || '|' || to_user || '|' || content || '|' || created_at FROM messages ORDER BY id",
+	} {
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		for rows.Next() {
+			var line string
+			rows.Scan(&line)
+			b.WriteString(line + "\n")
+		}
+		rows.Close()
+	}
+	return b.String()
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{Seed: 42, Users: 12, Posts: 20, Comments: 60, Messages: 45, Password: "password123", Reactions: []string{"like", "dislike", "love"}}
+
+	first, second, other := openDB(t), openDB(t), openDB(t)
+	res, err := Generate(ctx, first, opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if res.Users != 12 || res.Posts != 20 || res.Comments != 60 || res.Messages != 45 || res.Reactions == 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if _, err := Generate(ctx, second, opts); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	opts.Seed = 43
+	if _, err := Generate(ctx, other, opts); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if dump(t, first) != dump(t, second) {
+		t.Error("the same seed produced different data")
+	}
+	if dump(t, first) == dump(t, other) {
+		t.Error("different seeds produced the same data")
+	}
+
+	var hash string
+	first.QueryRow("SELECT password_hash FROM users LIMIT 1").Scan(&hash)
+	if err := utils.VerifyPassword(hash, "password123"); err != nil {
+		t.Errorf("generated users cannot sign in: %v", err)
+	}
+
+	// seeding again adds users instead of failing on taken usernames
+	if _, err := Generate(ctx, first, opts); err != nil {
+		t.Fatalf("second run on the same database: %v", err)
+	}
+	var users int
+	first.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	if users != 24 {
+		t.Errorf("users after two runs = %d, want 24", users)
+	}
+}
@@ -0,0 +1,91 @@
+package seed
+
+// Word lists the generator picks from. Appending is fine; reordering or
+// editing entries changes what every seed produces.
+
+var firstNames = []string{
+	"Anna", "Boris", "Chloe", "Daniel", "Elena", "Farid", "Greta", "Hugo",
+	"Irina", "Jonas", "Kira", "Liam", "Maria", "Nikolai", "Olga", "Pavel",
+	"Quinn", "Rosa", "Sergei", "Tara", "Umar", "Vera", "Wei", "Yana", "Zoe",
+}
+
+var lastNames = []string{
+	"Smith", "Ivanova", "Kowalski", "Novak", "Garcia", "Petrov", "Lee",
+	"Schmidt", "Rossi", "Haddad", "Tanaka", "Larsen", "Silva", "Moreau",
+}
+
+var genders = []string{"female", "male", "other", "prefer_not_to_say", ""}
+
+var roles = []string{
+	"backend developer", "frontend developer", "data analyst", "UX designer",
+	"product manager", "QA engineer", "DevOps engineer", "sales manager",
+	"technical writer", "support specialist",
+}
+
+var companies = []string{
+	"Northwind", "Globex", "Initech", "Umbrella Labs", "Hooli",
+	"Stark Logistics", "Acme Retail", "Blue Harbor Bank",
+}
+
+var cities = []string{"Berlin", "Lisbon", "Warsaw", "Toronto", "Tallinn", "Madrid", "remote"}
+
+// titleTemplates take a role, a company and a city, in that order; the
+// explicit argument indexes let a template skip any of them
+var titleTemplates = []string{
+	"How do I prepare for a %[1]s interview at %[2]s?",
+	"Is %[2]s a good place to work as a %[1]s?",
+	"Looking for a %[1]s role in %[3]s",
+	"Salary expectations for a %[1]s in %[3]s",
+	"Hiring: %[1]s at %[2]s (%[3]s)",
+	"My internship at %[2]s, an honest review",
+	"Switching careers to %[1]s: where to start?",
+	"Relocating to %[3]s for a %[1]s job",
+}
+
+var sentences = []string{
+	"I have been applying for about three months now and would love some feedback.",
+	"The recruiter was friendly, but the process took almost six weeks.",
+	"They asked a lot about system design and a little about algorithms.",
+	"The team seems great, although the office is quite far from the city centre.",
+	"Has anyone here negotiated a remote contract with them?",
+	"I would definitely recommend preparing a short portfolio first.",
+	"The take-home task was reasonable and took me one evening.",
+	"Benefits include a learning budget and flexible hours.",
+	"I am not sure whether to mention my previous salary.",
+	"Onboarding was well organised and I had a mentor from day one.",
+	"Is it normal to have five interview rounds for a mid-level position?",
+	"My advice: ask about the team structure before accepting an offer.",
+}
+
+var replies = []string{
+	"Thanks, this is really helpful!",
+	"Same experience here, the second round was the hardest.",
+	"I disagree, in my case the process was quick.",
+	"Could you share which questions they asked?",
+	"Good luck, let us know how it goes!",
+	"They changed their policy last year, so it may be different now.",
+	"I applied there too and never heard back.",
+	"Totally worth it in my opinion.",
+}
+
+var chatLines = []string{
+	"Hi! Saw your post about the interview.",
+	"Hey, how did it go in the end?",
+	"Do you still have the contact of that recruiter?",
+	"I got the offer!",
+	"Congrats, well deserved.",
+	"Want to do a mock interview this week?",
+	"Sure, Thursday evening works for me.",
+	"Sending you my CV, could you take a look?",
+	"Looks good, I would shorten the summary a bit.",
+	"Thanks a lot!",
+}
+
+// reactionWeights makes likes more common than the rest of the configured
+// set (Options.Reactions); names missing here are picked with weight 1
+var reactionWeights = map[string]int{"like": 6, "love": 2, "dislike": 2}
+
+var tagNames = []string{
+	"remote", "junior", "senior", "salary", "interview", "relocation",
+	"internship", "startup", "visa", "portfolio",
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailExists    = errors.New("email exists")
+	ErrUsernameExists = errors.New("username exists")
+	// ErrInvalidCredentials covers both an unknown login and a wrong password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type authService struct {
+	users repos.UserRepo
+}
+
+func NewAuthService(users repos.UserRepo) AuthService {
+	return &authService{users: users}
+}
+
+func (s *authService) Register(ctx context.Context, r Registration) (*models.User, error) {
+	if !utils.IsValidEmail(r.Email) ||
+		!utils.IsValidUsername(r.Username) ||
+		r.Age < 13 {
+		return nil, invalid("invalid data")
+	}
+
+	if ok, _ := s.users.EmailExists(ctx, r.Email); ok {
+		return nil, ErrEmailExists
+	}
+	if ok, _ := s.users.UsernameExists(ctx, r.Username); ok {
+		return nil, ErrUsernameExists
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(r.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        r.Email,
+		Username:     r.Username,
+		PasswordHash: string(hash),
+		Age:          r.Age,
+		Gender:       r.Gender,
+		FirstName:    r.FirstName,
+		LastName:     r.LastName,
+	}
+	if user.ID, err = s.users.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *authService) FindAccount(ctx context.Context, identifier string) (*models.User, error) {
+	user, err := s.users.GetByLogin(ctx, identifier)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (s *authService) CheckPassword(u *models.User, password string) error {
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthService(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	s := NewAuthService(store)
+
+	reg := Registration{Email: "alice@example.com", Username: "alice", Password: "secret123", Age: 30}
+	user, err := s.Register(ctx, reg)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.ID == 0 || user.PasswordHash == reg.Password {
+		t.Fatalf("registered user %+v: want an ID and a hashed password", user)
+	}
+
+	var verr *ValidationError
+	young := reg
+	young.Email, young.Username, young.Age = "kid@example.com", "kid", 12
+	if _, err := s.Register(ctx, young); !errors.As(err, &verr) {
+		t.Errorf("age 12: err = %v, want a ValidationError", err)
+	}
+	dupEmail := reg
+	dupEmail.Username = "alice2"
+	if _, err := s.Register(ctx, dupEmail); err != ErrEmailExists {
+		t.Errorf("duplicate email: err = %v, want ErrEmailExists", err)
+	}
+	dupName := reg
+	dupName.Email = "other@example.com"
+	dupName.Username = "ALICE"
+	if _, err := s.Register(ctx, dupName); err != ErrUsernameExists {
+		t.Errorf("duplicate username: err = %v, want ErrUsernameExists", err)
+	}
+
+	found, err := s.FindAccount(ctx, "Alice@Example.com")
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("FindAccount by email = %+v, %v", found, err)
+	}
+	if _, err := s.FindAccount(ctx, "nobody"); err != ErrInvalidCredentials {
+		t.Errorf("unknown login: err = %v, want ErrInvalidCredentials", err)
+	}
+	if err := s.CheckPassword(found, "secret123"); err != nil {
+		t.Errorf("right password rejected: %v", err)
+	}
+	if err := s.CheckPassword(found, "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+)
+
+var (
+	// ErrBlockedByRecipient: the recipient blocked the sender. The sender
+	// must not learn about it, so callers drop the message silently.
+	ErrBlockedByRecipient = errors.New("blocked by recipient")
+	ErrRecipientBlocked   = errors.New("you have blocked this user")
+	ErrMessagesNotAllowed = errors.New("recipient does not accept messages from you")
+)
+
+type chatService struct {
+	messages repos.MessageRepo
+	users    repos.UserRepo
+	blocks   repos.BlockRepo
+}
+
+func NewChatService(messages repos.MessageRepo, users repos.UserRepo, blocks repos.BlockRepo) ChatService {
+	return &chatService{messages: messages, users: users, blocks: blocks}
+}
+
+func (s *chatService) Send(ctx context.Context, from, to int, content string) (int64, string, error) {
+	if content == "" {
+		return 0, "", invalid("empty message")
+	}
+	if _, err := s.users.GetByID(ctx, to); err != nil {
+		return 0, "", notFound(err)
+	}
+
+	if blocked, err := s.blocks.IsBlocked(ctx, to, from); err != nil {
+		return 0, "", err
+	} else if blocked {
+		return 0, "", ErrBlockedByRecipient
+	}
+	if blocked, err := s.blocks.IsBlocked(ctx, from, to); err != nil {
+		return 0, "", err
+	} else if blocked {
+		return 0, "", ErrRecipientBlocked
+	}
+
+	// получатель мог закрыть личку для незнакомых
+	if allowed, err := s.messages.CanSendMessage(ctx, from, to); err != nil {
+		return 0, "", err
+	} else if !allowed {
+		return 0, "", ErrMessagesNotAllowed
+	}
+
+	return s.messages.Insert(ctx, from, to, content)
+}
+
+func (s *chatService) History(ctx context.Context, userID, otherID, offset, limit int) ([]models.Comment, bool, error) {
+	if _, err := s.users.GetByID(ctx, otherID); err != nil {
+		return nil, false, notFound(err)
+	}
+
+	msgs, err := s.messages.GetBetween(ctx, userID, otherID, offset, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	total, err := s.messages.CountBetween(ctx, userID, otherID)
+	if err != nil {
+		return nil, false, err
+	}
+	return msgs, offset+limit < total, nil
+}
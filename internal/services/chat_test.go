@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"real-time-forum/internal/models"
+)
+
+func TestChatServiceSend(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	bob := store.addUser("bob", true)
+	carol := store.addUser("carol", true)
+	s := NewChatService(store, store, store)
+
+	if _, _, err := s.Send(ctx, alice, bob, "hi bob"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, _, err := s.Send(ctx, alice, 999, "hi"); err != ErrNotFound {
+		t.Errorf("unknown recipient: err = %v, want ErrNotFound", err)
+	}
+
+	// bob accepts only people he has written to
+	p := store.privacy[bob]
+	p.DMPolicy = models.DMContacts
+	store.privacy[bob] = p
+	if _, _, err := s.Send(ctx, carol, bob, "hi"); err != ErrMessagesNotAllowed {
+		t.Errorf("stranger under contacts policy: err = %v, want ErrMessagesNotAllowed", err)
+	}
+
+	// being blocked wins over everything else, so the sender learns nothing more
+	store.blocks[[2]int{bob, carol}] = true
+	store.blocks[[2]int{carol, bob}] = true
+	if _, _, err := s.Send(ctx, carol, bob, "hi"); err != ErrBlockedByRecipient {
+		t.Errorf("blocked by recipient: err = %v, want ErrBlockedByRecipient", err)
+	}
+	if _, _, err := s.Send(ctx, bob, carol, "hi"); err != ErrBlockedByRecipient {
+		t.Errorf("mutual block: err = %v, want ErrBlockedByRecipient", err)
+	}
+	delete(store.blocks, [2]int{carol, bob})
+	if _, _, err := s.Send(ctx, bob, carol, "hi"); err != ErrRecipientBlocked {
+		t.Errorf("recipient blocked by sender: err = %v, want ErrRecipientBlocked", err)
+	}
+
+	if len(store.messages) != 1 {
+		t.Fatalf("%d messages stored, want only the first one", len(store.messages))
+	}
+}
+
+func TestChatServiceHistory(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	bob := store.addUser("bob", true)
+	s := NewChatService(store, store, store)
+
+	for i := 0; i < 3; i++ {
+		s.Send(ctx, alice, bob, "hi")
+	}
+	msgs, more, err := s.History(ctx, bob, alice, 0, 2)
+	if err != nil || len(msgs) != 2 || !more {
+		t.Fatalf("first page: %d messages, more=%v, %v", len(msgs), more, err)
+	}
+	if msgs, more, _ = s.History(ctx, bob, alice, 2, 2); len(msgs) != 1 || more {
+		t.Fatalf("last page: %d messages, more=%v", len(msgs), more)
+	}
+	if _, _, err := s.History(ctx, bob, 999, 0, 2); err != ErrNotFound {
+		t.Fatalf("unknown user: err = %v, want ErrNotFound", err)
+	}
+}
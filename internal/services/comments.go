@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/utils"
+)
+
+type commentService struct {
+	comments repos.CommentRepo
+	posts    repos.PostRepo
+	users    repos.UserRepo
+	blocks   repos.BlockRepo
+}
+
+func NewCommentService(comments repos.CommentRepo, posts repos.PostRepo, users repos.UserRepo, blocks repos.BlockRepo) CommentService {
+	return &commentService{comments: comments, posts: posts, users: users, blocks: blocks}
+}
+
+func (s *commentService) Create(ctx context.Context, userID, postID int, content string) (*models.Comment, int, error) {
+	if ok, msg := utils.ValidateCommentData(content); !ok {
+		return nil, 0, invalid(msg)
+	}
+	if _, err := s.posts.GetPost(ctx, 0, postID); err != nil {
+		return nil, 0, notFound(err)
+	}
+
+	id, err := s.comments.CreateComment(ctx, postID, userID, content)
+	if err != nil {
+		return nil, 0, err
+	}
+	comment, err := s.comments.GetComment(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.comments.CountComments(ctx, postID)
+	return comment, count, err
+}
+
+func (s *commentService) Get(ctx context.Context, id int) (*models.Comment, error) {
+	comment, err := s.comments.GetComment(ctx, id)
+	return comment, notFound(err)
+}
+
+func (s *commentService) List(ctx context.Context, viewerID, postID int) ([]models.Comment, error) {
+	comments, err := s.comments.ListComments(ctx, viewerID, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hidden := hiddenAuthors(ctx, s.users, s.blocks, viewerID); len(hidden) > 0 {
+		visible := comments[:0]
+		for _, c := range comments {
+			if !hidden[c.UserID] {
+				visible = append(visible, c)
+			}
+		}
+		comments = visible
+	}
+	return comments, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCommentService(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	troll := store.addUser("troll", false)
+	post, _ := store.CreatePost(ctx, alice, "Title", "", []int{1}, nil)
+	s := NewCommentService(store, store, store, store)
+
+	var verr *ValidationError
+	if _, _, err := s.Create(ctx, alice, post, "   "); !errors.As(err, &verr) {
+		t.Fatalf("empty comment: err = %v, want a ValidationError", err)
+	}
+	if _, _, err := s.Create(ctx, alice, 999, "hello"); err != ErrNotFound {
+		t.Fatalf("missing post: err = %v, want ErrNotFound", err)
+	}
+
+	comment, count, err := s.Create(ctx, alice, post, "hello")
+	if err != nil || comment.Content != "hello" || count != 1 {
+		t.Fatalf("Create = %+v, %d, %v", comment, count, err)
+	}
+	if _, count, _ = s.Create(ctx, troll, post, "spam"); count != 2 {
+		t.Fatalf("comment count %d, want 2", count)
+	}
+
+	store.blocks[[2]int{alice, troll}] = true
+	if list, _ := s.List(ctx, alice, post); len(list) != 1 || list[0].UserID != alice {
+		t.Fatalf("alice sees %+v, want only her comment", list)
+	}
+	if list, _ := s.List(ctx, 0, post); len(list) != 2 {
+		t.Fatalf("guest sees %d comments, want 2", len(list))
+	}
+}
+
+func TestReactionService(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	bob := store.addUser("bob", true)
+	post, _ := store.CreatePost(ctx, alice, "Title", "", []int{1}, nil)
+	comment, _ := store.CreateComment(ctx, post, alice, "hi")
+	s := NewReactionService(store, store, store, []string{"like", "dislike", "love"})
+
+	if _, _, err := s.ReactToPost(ctx, alice, 999, "like"); err != ErrNotFound {
+		t.Fatalf("missing post: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := s.ReactToComment(ctx, alice, 999, "like"); err != ErrNotFound {
+		t.Fatalf("missing comment: err = %v, want ErrNotFound", err)
+	}
+	var verr *ValidationError
+	if _, _, err := s.ReactToPost(ctx, alice, post, "angry"); !errors.As(err, &verr) {
+		t.Fatalf("reaction outside the set: err = %v, want ValidationError", err)
+	}
+
+	steps := []struct {
+		user     int
+		reaction string
+		mine     string
+		counts   string
+	}{
+		{alice, "like", "like", "map[like:1]"},
+		{alice, "dislike", "dislike", "map[dislike:1]"}, // switch
+		{bob, "love", "love", "map[dislike:1 love:1]"},
+		{alice, "dislike", "", "map[love:1]"}, // repeat takes it back
+	}
+	for i, st := range steps {
+		counts, mine, err := s.ReactToComment(ctx, st.user, comment, st.reaction)
+		if err != nil || mine != st.mine || fmt.Sprint(counts) != st.counts {
+			t.Fatalf("step %d: %v, %q, %v; want %s, %q", i, counts, mine, err, st.counts, st.mine)
+		}
+	}
+
+	comments := NewCommentService(store, store, store, store)
+	for viewer, want := range map[int]string{bob: "love", alice: "", 0: ""} {
+		list, err := comments.List(ctx, viewer, post)
+		if err != nil || len(list) != 1 || list[0].MyReaction != want {
+			t.Errorf("viewer %d: comments = %+v, %v; want my_reaction %q", viewer, list, err, want)
+		}
+	}
+}
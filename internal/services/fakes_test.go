@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+)
+
+// fakeStore is an in-memory stand-in for every repository the services use
+type fakeStore struct {
+	users    map[int]*models.User
+	privacy  map[int]models.PrivacySettings
+	posts    map[int]*models.Post
+	postCats map[int][]int // post ID -> category IDs
+	comments map[int]*models.Comment
+	blocks   map[[2]int]bool  // {blocker, blocked}
+	contacts map[[2]int]bool  // {from, to}: to accepts messages from from
+	messages []models.Comment // like the real repo; PostID holds the recipient
+	// reactions: {userID, targetID} -> reaction name
+	postReactions    map[[2]int]string
+	commentReactions map[[2]int]string
+	archived         map[int]bool // category IDs no longer accepting posts
+	nextID           int
+
+	// methods the services never call come from these nil interfaces and panic
+	repos.UserRepo
+	repos.PostRepo
+	repos.BlockRepo
+	repos.MessageRepo
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:            map[int]*models.User{},
+		privacy:          map[int]models.PrivacySettings{},
+		posts:            map[int]*models.Post{},
+		postCats:         map[int][]int{},
+		comments:         map[int]*models.Comment{},
+		blocks:           map[[2]int]bool{},
+		contacts:         map[[2]int]bool{},
+		postReactions:    map[[2]int]string{},
+		commentReactions: map[[2]int]string{},
+		archived:         map[int]bool{},
+	}
+}
+
+func (f *fakeStore) repos() *repos.Repos {
+	return &repos.Repos{Users: f, Posts: f, Comments: f, Reactions: f, Blocks: f, Messages: f}
+}
+
+func (f *fakeStore) id() int {
+	f.nextID++
+	return f.nextID
+}
+
+func (f *fakeStore) addUser(name string, hide bool) int {
+	id := f.id()
+	f.users[id] = &models.User{ID: id, Username: name, Email: name + "@example.com"}
+	f.privacy[id] = models.PrivacySettings{DMPolicy: models.DMEveryone, HideBlockedContent: hide}
+	return id
+}
+
+// UserRepo
+
+func (f *fakeStore) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetByUsername(ctx context.Context, name string) (*models.User, error) {
+	for _, u := range f.users {
+		if strings.EqualFold(u.Username, name) {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetByLogin(ctx context.Context, identifier string) (*models.User, error) {
+	for _, u := range f.users {
+		if strings.EqualFold(u.Username, identifier) || strings.EqualFold(u.Email, identifier) {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) EmailExists(ctx context.Context, email string) (bool, error) {
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) UsernameExists(ctx context.Context, name string) (bool, error) {
+	_, err := f.GetByUsername(ctx, name)
+	return err == nil, nil
+}
+
+func (f *fakeStore) CreateUser(ctx context.Context, u *models.User) (int, error) {
+	stored := *u
+	stored.ID = f.id()
+	f.users[stored.ID] = &stored
+	f.privacy[stored.ID] = models.PrivacySettings{DMPolicy: models.DMEveryone, HideBlockedContent: true}
+	return stored.ID, nil
+}
+
+func (f *fakeStore) GetPrivacySettings(ctx context.Context, userID int) (*models.PrivacySettings, error) {
+	p, ok := f.privacy[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+// PostRepo
+
+func (f *fakeStore) CreatePost(ctx context.Context, userID int, title, content string, categoryIDs []int, tags []string) (int, error) {
+	for _, c := range categoryIDs {
+		if f.archived[c] {
+			return 0, repos.ErrInvalidCategory
+		}
+	}
+	id := f.id()
+	f.posts[id] = &models.Post{ID: id, UserID: userID, Title: title, Content: content, Tags: tags}
+	f.postCats[id] = categoryIDs
+	return id, nil
+}
+
+func (f *fakeStore) GetPost(ctx context.Context, viewerID, id int) (*models.Post, error) {
+	if p, ok := f.posts[id]; ok {
+		post := *p
+		post.MyReaction = f.postReactions[[2]int{viewerID, id}]
+		return &post, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) ListPosts(ctx context.Context, filter repos.PostFilter) ([]models.Post, error) {
+	inCategories, _ := f.PostIDsByCategories(ctx, filter.CategoryIDs)
+	out := []models.Post{}
+	for id := 1; id <= f.nextID; id++ {
+		p, ok := f.posts[id]
+		if !ok ||
+			filter.AuthorID > 0 && p.UserID != filter.AuthorID ||
+			filter.LikedBy > 0 && f.postReactions[[2]int{filter.LikedBy, id}] != models.ReactionLike ||
+			len(filter.CategoryIDs) > 0 && !inCategories[id] {
+			continue
+		}
+		post := *p
+		post.MyReaction = f.postReactions[[2]int{filter.ViewerID, id}]
+		out = append(out, post)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) PostIDsByTags(ctx context.Context, tags []string) (map[int]bool, error) {
+	ids := map[int]bool{}
+	for id, p := range f.posts {
+		for _, have := range p.Tags {
+			for _, want := range tags {
+				if have == want {
+					ids[id] = true
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeStore) PostIDsByCategories(ctx context.Context, categoryIDs []int) (map[int]bool, error) {
+	ids := map[int]bool{}
+	for id, cats := range f.postCats {
+		for _, have := range cats {
+			for _, want := range categoryIDs {
+				if have == want {
+					ids[id] = true
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+// CommentRepo
+
+func (f *fakeStore) CreateComment(ctx context.Context, postID, userID int, content string) (int, error) {
+	id := f.id()
+	f.comments[id] = &models.Comment{ID: id, PostID: postID, UserID: userID, Content: content}
+	return id, nil
+}
+
+func (f *fakeStore) GetComment(ctx context.Context, id int) (*models.Comment, error) {
+	if c, ok := f.comments[id]; ok {
+		return c, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) ListComments(ctx context.Context, viewerID, postID int) ([]models.Comment, error) {
+	out := []models.Comment{}
+	for id := 1; id <= f.nextID; id++ {
+		if c, ok := f.comments[id]; ok && c.PostID == postID {
+			comment := *c
+			comment.MyReaction = f.commentReactions[[2]int{viewerID, id}]
+			out = append(out, comment)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) CountComments(ctx context.Context, postID int) (int, error) {
+	list, _ := f.ListComments(ctx, 0, postID)
+	return len(list), nil
+}
+
+// ReactionRepo
+
+func toggle(reactions map[[2]int]string, key [2]int, reaction string) (map[string]int, string) {
+	if reactions[key] == reaction {
+		delete(reactions, key)
+		reaction = ""
+	} else {
+		reactions[key] = reaction
+	}
+	counts := map[string]int{}
+	for k, name := range reactions {
+		if k[1] == key[1] {
+			counts[name]++
+		}
+	}
+	return counts, reaction
+}
+
+func (f *fakeStore) TogglePostReaction(ctx context.Context, userID, postID int, reaction string) (map[string]int, string, error) {
+	counts, mine := toggle(f.postReactions, [2]int{userID, postID}, reaction)
+	return counts, mine, nil
+}
+
+func (f *fakeStore) ToggleCommentReaction(ctx context.Context, userID, commentID int, reaction string) (map[string]int, string, error) {
+	counts, mine := toggle(f.commentReactions, [2]int{userID, commentID}, reaction)
+	return counts, mine, nil
+}
+
+// BlockRepo
+
+func (f *fakeStore) IsBlocked(ctx context.Context, blocker, blocked int) (bool, error) {
+	return f.blocks[[2]int{blocker, blocked}], nil
+}
+
+func (f *fakeStore) BlockedIDs(ctx context.Context, blocker int) (map[int]bool, error) {
+	ids := map[int]bool{}
+	for k := range f.blocks {
+		if k[0] == blocker {
+			ids[k[1]] = true
+		}
+	}
+	return ids, nil
+}
+
+// MessageRepo
+
+func (f *fakeStore) Insert(ctx context.Context, from, to int, content string) (int64, string, error) {
+	id := f.id()
+	f.messages = append(f.messages, models.Comment{ID: id, UserID: from, PostID: to, Content: content})
+	return int64(id), "2026-01-02 03:04:05", nil
+}
+
+func (f *fakeStore) GetBetween(ctx context.Context, a, b, offset, limit int) ([]models.Comment, error) {
+	var out []models.Comment
+	for i := len(f.messages) - 1; i >= 0; i-- {
+		m := f.messages[i]
+		if m.UserID == a && m.PostID == b || m.UserID == b && m.PostID == a {
+			out = append(out, m)
+		}
+	}
+	if offset > len(out) {
+		offset = len(out)
+	}
+	out = out[offset:]
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (f *fakeStore) CountBetween(ctx context.Context, a, b int) (int, error) {
+	all, _ := f.GetBetween(ctx, a, b, 0, len(f.messages))
+	return len(all), nil
+}
+
+func (f *fakeStore) CanSendMessage(ctx context.Context, from, to int) (bool, error) {
+	if f.privacy[to].DMPolicy != models.DMContacts {
+		return true, nil
+	}
+	return f.contacts[[2]int{from, to}], nil
+}
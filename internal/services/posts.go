@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+	"real-time-forum/internal/utils"
+)
+
+// MaxCategoriesPerPost limits how many categories a post may be filed under
+const MaxCategoriesPerPost = 5
+
+type postService struct {
+	posts  repos.PostRepo
+	users  repos.UserRepo
+	blocks repos.BlockRepo
+}
+
+func NewPostService(posts repos.PostRepo, users repos.UserRepo, blocks repos.BlockRepo) PostService {
+	return &postService{posts: posts, users: users, blocks: blocks}
+}
+
+func (s *postService) Create(ctx context.Context, userID int, p NewPost) (int, error) {
+	if ok, msg := utils.ValidatePostData(p.Title, p.Content); !ok {
+		return 0, invalid(msg)
+	}
+	if len(p.CategoryIDs) == 0 {
+		return 0, invalid("Please select at least one category for your post")
+	}
+	if len(p.CategoryIDs) > MaxCategoriesPerPost {
+		return 0, invalid("Cannot select more than 5 categories")
+	}
+
+	tags := utils.NormalizeTags(p.Tags)
+	if ok, msg := utils.ValidateTags(tags); !ok {
+		return 0, invalid(msg)
+	}
+
+	// существование и архивность категорий проверяет репозиторий внутри транзакции
+	return s.posts.CreatePost(ctx, userID, p.Title, p.Content, p.CategoryIDs, tags)
+}
+
+func (s *postService) Get(ctx context.Context, viewerID, id int) (*models.Post, error) {
+	post, err := s.posts.GetPost(ctx, viewerID, id)
+	return post, notFound(err)
+}
+
+func (s *postService) List(ctx context.Context, viewerID int, q PostQuery) ([]models.Post, error) {
+	// a guest has no posts of their own and no likes
+	if (q.Mine || q.Liked) && viewerID <= 0 {
+		return []models.Post{}, nil
+	}
+
+	filter := repos.PostFilter{CategoryIDs: q.CategoryIDs, ViewerID: viewerID}
+	if q.Mine {
+		filter.AuthorID = viewerID
+	} else if q.Liked {
+		filter.LikedBy = viewerID
+	}
+
+	tags := utils.NormalizeTags(q.Tags)
+	var (
+		posts []models.Post
+		err   error
+	)
+	if len(tags) > 0 {
+		posts, err = s.listByTags(ctx, filter, tags, q.MatchAny)
+	} else {
+		posts, err = s.posts.ListPosts(ctx, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hidden := hiddenAuthors(ctx, s.users, s.blocks, viewerID); len(hidden) > 0 {
+		visible := posts[:0]
+		for _, p := range posts {
+			if !hidden[p.UserID] {
+				visible = append(visible, p)
+			}
+		}
+		posts = visible
+	}
+	return posts, nil
+}
+
+// listByTags loads the listing without the category filter and keeps the
+// posts matching the tags and, if given, the categories (both or either one)
+func (s *postService) listByTags(ctx context.Context, filter repos.PostFilter, tags []string, matchAny bool) ([]models.Post, error) {
+	categoryIDs := filter.CategoryIDs
+	filter.CategoryIDs = nil
+	posts, err := s.posts.ListPosts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged, err := s.posts.PostIDsByTags(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+	var inCategories map[int]bool
+	if len(categoryIDs) > 0 {
+		if inCategories, err = s.posts.PostIDsByCategories(ctx, categoryIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := []models.Post{}
+	for _, p := range posts {
+		match := tagged[p.ID]
+		if inCategories != nil {
+			if matchAny {
+				match = match || inCategories[p.ID]
+			} else {
+				match = match && inCategories[p.ID]
+			}
+		}
+		if match {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
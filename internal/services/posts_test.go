@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"real-time-forum/internal/models"
+)
+
+func postIDs(posts []models.Post) string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = fmt.Sprint(p.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestPostServiceCreateValidates(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	store.archived[7] = true
+	s := NewPostService(store, store, store)
+
+	valid := NewPost{Title: "A valid title", Content: "Some content that is long enough", CategoryIDs: []int{1}}
+	tests := []struct {
+		name   string
+		modify func(p *NewPost)
+	}{
+		{"empty title", func(p *NewPost) { p.Title = "  " }},
+		{"no categories", func(p *NewPost) { p.CategoryIDs = nil }},
+		{"six categories", func(p *NewPost) { p.CategoryIDs = []int{1, 2, 3, 4, 5, 6} }},
+		{"bad tag", func(p *NewPost) { p.Tags = []string{"?!"} }},
+		{"six tags", func(p *NewPost) { p.Tags = []string{"a1", "a2", "a3", "a4", "a5", "a6"} }},
+	}
+	for _, tt := range tests {
+		p := valid
+		tt.modify(&p)
+		var verr *ValidationError
+		if _, err := s.Create(ctx, alice, p); !errors.As(err, &verr) {
+			t.Errorf("%s: err = %v, want a ValidationError", tt.name, err)
+		}
+	}
+
+	archived := valid
+	archived.CategoryIDs = []int{1, 7}
+	if _, err := s.Create(ctx, alice, archived); err != ErrInvalidCategory {
+		t.Errorf("archived category: err = %v, want ErrInvalidCategory", err)
+	}
+	if len(store.posts) != 0 {
+		t.Fatalf("%d posts stored by rejected creates", len(store.posts))
+	}
+
+	valid.Tags = []string{"#Go", "go", "Web Dev"}
+	id, err := s.Create(ctx, alice, valid)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := strings.Join(store.posts[id].Tags, ","); got != "go,web-dev" {
+		t.Fatalf("stored tags %q, want normalized go,web-dev", got)
+	}
+}
+
+func TestPostServiceList(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	alice := store.addUser("alice", true)
+	bob := store.addUser("bob", false)
+	troll := store.addUser("troll", false)
+	s := NewPostService(store, store, store)
+
+	goJobs, _ := store.CreatePost(ctx, alice, "Go jobs", "", []int{1}, []string{"go"})
+	goTalk, _ := store.CreatePost(ctx, bob, "Go talk", "", []int{2}, []string{"go"})
+	other, _ := store.CreatePost(ctx, bob, "Other", "", []int{1}, nil)
+	spam, _ := store.CreatePost(ctx, troll, "Spam", "", []int{2}, []string{"go"})
+	store.blocks[[2]int{alice, troll}] = true
+	store.blocks[[2]int{bob, troll}] = true
+	store.postReactions[[2]int{bob, goJobs}] = "like"
+
+	tests := []struct {
+		name   string
+		viewer int
+		q      PostQuery
+		want   []int
+	}{
+		{"guest sees all", 0, PostQuery{}, []int{goJobs, goTalk, other, spam}},
+		{"blocked authors hidden", alice, PostQuery{}, []int{goJobs, goTalk, other}},
+		{"hiding switched off", bob, PostQuery{}, []int{goJobs, goTalk, other, spam}},
+		{"guest has no own posts", 0, PostQuery{Mine: true}, nil},
+		{"mine", bob, PostQuery{Mine: true}, []int{goTalk, other}},
+		{"liked", bob, PostQuery{Liked: true}, []int{goJobs}},
+		{"tags are normalized", 0, PostQuery{Tags: []string{"#GO"}}, []int{goJobs, goTalk, spam}},
+		{"tags and categories", 0, PostQuery{Tags: []string{"go"}, CategoryIDs: []int{1}}, []int{goJobs}},
+		{"tags or categories", alice, PostQuery{Tags: []string{"go"}, CategoryIDs: []int{1}, MatchAny: true}, []int{goJobs, goTalk, other}},
+		{"mine with tags", bob, PostQuery{Mine: true, Tags: []string{"go"}}, []int{goTalk}},
+	}
+	for _, tt := range tests {
+		posts, err := s.List(ctx, tt.viewer, tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := make([]models.Post, len(tt.want))
+		for i, id := range tt.want {
+			want[i].ID = id
+		}
+		if got := postIDs(posts); got != postIDs(want) {
+			t.Errorf("%s: posts %s, want %s", tt.name, got, postIDs(want))
+		}
+	}
+
+	if _, err := s.Get(ctx, 0, 999); err != ErrNotFound {
+		t.Errorf("Get(missing) err = %v, want ErrNotFound", err)
+	}
+	for viewer, want := range map[int]string{bob: "like", alice: "", 0: ""} {
+		if post, err := s.Get(ctx, viewer, goJobs); err != nil || post.MyReaction != want {
+			t.Errorf("Get as %d: my_reaction = %q, %v; want %q", viewer, post.MyReaction, err, want)
+		}
+	}
+}
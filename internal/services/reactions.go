@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+
+	"real-time-forum/internal/repos"
+)
+
+type reactionService struct {
+	reactions repos.ReactionRepo
+	posts     repos.PostRepo
+	comments  repos.CommentRepo
+	allowed   map[string]bool
+}
+
+func NewReactionService(reactions repos.ReactionRepo, posts repos.PostRepo, comments repos.CommentRepo, allowed []string) ReactionService {
+	s := &reactionService{reactions: reactions, posts: posts, comments: comments, allowed: map[string]bool{}}
+	for _, name := range allowed {
+		s.allowed[name] = true
+	}
+	return s
+}
+
+func (s *reactionService) ReactToPost(ctx context.Context, userID, postID int, reaction string) (map[string]int, string, error) {
+	if !s.allowed[reaction] {
+		return nil, "", invalid("unknown reaction")
+	}
+	if _, err := s.posts.GetPost(ctx, 0, postID); err != nil {
+		return nil, "", notFound(err)
+	}
+	return s.reactions.TogglePostReaction(ctx, userID, postID, reaction)
+}
+
+func (s *reactionService) ReactToComment(ctx context.Context, userID, commentID int, reaction string) (map[string]int, string, error) {
+	if !s.allowed[reaction] {
+		return nil, "", invalid("unknown reaction")
+	}
+	if _, err := s.comments.GetComment(ctx, commentID); err != nil {
+		return nil, "", notFound(err)
+	}
+	return s.reactions.ToggleCommentReaction(ctx, userID, commentID, reaction)
+}
@@ -0,0 +1,154 @@
+// Package services holds the forum's business rules: validation, permission
+// checks and operations spanning several storage calls. Services work on the
+// repos interfaces, so handlers only deal with HTTP and the rules can be
+// tested with fakes.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"real-time-forum/internal/models"
+	"real-time-forum/internal/repos"
+)
+
+var (
+	// ErrNotFound is returned when the post, comment or user acted on does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidCategory is returned when a category does not exist or is archived
+	ErrInvalidCategory = repos.ErrInvalidCategory
+)
+
+// ValidationError reports invalid user input; Msg is meant for the user
+type ValidationError struct {
+	Msg string
+}
+
+func (e *ValidationError) Error() string { return e.Msg }
+
+func invalid(msg string) error { return &ValidationError{Msg: msg} }
+
+// PostQuery selects a post listing
+type PostQuery struct {
+	Mine        bool     // only the viewer's posts
+	Liked       bool     // only posts the viewer liked
+	CategoryIDs []int    // posts in any of these categories
+	Tags        []string // posts with any of these tags, as typed by the user
+	// with both categories and tags, a post must match both unless MatchAny is set
+	MatchAny bool
+}
+
+// NewPost is the input of PostService.Create
+type NewPost struct {
+	Title       string
+	Content     string
+	CategoryIDs []int
+	Tags        []string // as typed by the user; normalized by the service
+}
+
+// PostService creates and lists posts
+type PostService interface {
+	// Create stores the post with its categories and tags atomically and
+	// returns its ID; once it returns nil the post may be announced
+	Create(ctx context.Context, userID int, p NewPost) (int, error)
+	// Get returns a post with the viewer's own reaction (none for viewerID 0)
+	Get(ctx context.Context, viewerID, id int) (*models.Post, error)
+	// List returns the posts matching q, without those the viewer hides
+	List(ctx context.Context, viewerID int, q PostQuery) ([]models.Post, error)
+}
+
+// CommentService creates and lists comments
+type CommentService interface {
+	// Create adds a comment and returns it with the post's new comment count
+	Create(ctx context.Context, userID, postID int, content string) (*models.Comment, int, error)
+	Get(ctx context.Context, id int) (*models.Comment, error)
+	List(ctx context.Context, viewerID, postID int) ([]models.Comment, error)
+}
+
+// ReactionService toggles reactions from the configured set; repeating the
+// current reaction takes it back. Both return the target's counts per
+// reaction and the user's reaction after the toggle
+type ReactionService interface {
+	ReactToPost(ctx context.Context, userID, postID int, reaction string) (counts map[string]int, mine string, err error)
+	ReactToComment(ctx context.Context, userID, commentID int, reaction string) (counts map[string]int, mine string, err error)
+}
+
+// Registration is the input of AuthService.Register
+type Registration struct {
+	Email     string
+	Username  string
+	Password  string
+	Age       int
+	Gender    string
+	FirstName string
+	LastName  string
+}
+
+// AuthService registers users and checks their credentials
+type AuthService interface {
+	Register(ctx context.Context, r Registration) (*models.User, error)
+	// FindAccount looks a user up by e-mail or username, as typed on the login form
+	FindAccount(ctx context.Context, identifier string) (*models.User, error)
+	CheckPassword(u *models.User, password string) error
+}
+
+// ChatService sends and reads private messages
+type ChatService interface {
+	// Send stores a message after the block and DM policy checks and
+	// returns its ID and creation time
+	Send(ctx context.Context, from, to int, content string) (int64, string, error)
+	// History returns a page of messages between two users, newest first,
+	// and whether older ones remain
+	History(ctx context.Context, userID, otherID, offset, limit int) ([]models.Comment, bool, error)
+}
+
+// Services bundles the services used by the HTTP handlers
+type Services struct {
+	Posts     PostService
+	Comments  CommentService
+	Reactions ReactionService
+	Auth      AuthService
+	Chat      ChatService
+}
+
+// New wires all services to the given repositories; reactions is the set of
+// reaction names users may leave (config.ReactionNames)
+func New(r *repos.Repos, reactions []string) *Services {
+	return &Services{
+		Posts:     NewPostService(r.Posts, r.Users, r.Blocks),
+		Comments:  NewCommentService(r.Comments, r.Posts, r.Users, r.Blocks),
+		Reactions: NewReactionService(r.Reactions, r.Posts, r.Comments, reactions),
+		Auth:      NewAuthService(r.Users),
+		Chat:      NewChatService(r.Messages, r.Users, r.Blocks),
+	}
+}
+
+// hiddenAuthors returns the authors whose posts and comments the viewer
+// should not see in listings: the users they blocked, unless they turned
+// hide_blocked_content off. Guests see everything, and so does anyone whose
+// settings fail to load: a listing is not worth an error page.
+func hiddenAuthors(ctx context.Context, users repos.UserRepo, blocks repos.BlockRepo, viewerID int) map[int]bool {
+	if viewerID <= 0 {
+		return nil
+	}
+	settings, err := users.GetPrivacySettings(ctx, viewerID)
+	if err != nil || !settings.HideBlockedContent {
+		return nil
+	}
+	ids, err := blocks.BlockedIDs(ctx, viewerID)
+	if err != nil {
+		log.Printf("load blocked ids for %d: %v", viewerID, err)
+		return nil
+	}
+	return ids
+}
+
+// notFound maps a missing row to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
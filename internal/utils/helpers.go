@@ -1,13 +1,18 @@
 package utils
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"html/template"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
+
+	"real-time-forum/internal/database"
+	"real-time-forum/internal/models"
 )
 
 // TemplateFuncs returns a map of custom template functions
@@ -113,56 +118,103 @@ func ValidatePostData(title, content string) (bool, string) {
 	return true, ""
 }
 
-// ValidateCompletePostData validates all post data including categories
-func ValidateCompletePostData(title, content string, categories []string) (bool, string) {
-	// First validate title and content
-	if valid, err := ValidatePostData(title, content); !valid {
-		return false, err
+var slugRe = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Slugify turns a category name into a URL slug: "Job Search Tips" -> "job-search-tips".
+// Letters outside a-z are dropped, so the result may be empty.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
 	}
+	return b.String()
+}
 
-	// Validate categories
-	if len(categories) == 0 {
-		return false, "Please select at least one category for your post"
+// ValidateCategoryData validates a category's name, slug and description
+func ValidateCategoryData(name, slug, description string) (bool, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, "Category name cannot be empty"
 	}
-
-	// Check maximum number of categories (optional limit)
-	if len(categories) > 5 {
-		return false, "Cannot select more than 5 categories"
+	if utf8.RuneCountInString(name) > 50 {
+		return false, "Category name must be at most 50 characters"
 	}
-
-	// Validate each category ID
-	for _, cat := range categories {
-		if strings.TrimSpace(cat) == "" {
-			return false, "Invalid category selected"
-		}
-		// Additional validation: ensure it's a valid number
-		if !isValidCategoryID(cat) {
-			return false, "Invalid category ID format"
-		}
+	if slug == "" || len(slug) > 50 || !slugRe.MatchString(slug) {
+		return false, "Slug may contain only lowercase letters, digits and single dashes"
+	}
+	if utf8.RuneCountInString(description) > 200 {
+		return false, "Category description must be at most 200 characters"
 	}
-
 	return true, ""
 }
 
-// isValidCategoryID checks if category ID is a valid positive integer
-func isValidCategoryID(categoryID string) bool {
-	if categoryID == "" {
-		return false
+// MaxTagsPerPost limits how many tags a post may carry
+const MaxTagsPerPost = 5
+
+// NormalizeTag brings a user-supplied tag to its stored form: lowercase,
+// without a leading '#', words joined by single dashes and only letters,
+// digits and dashes kept. "#Go Lang" -> "go-lang".
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			dash = true
+		}
 	}
+	return b.String()
+}
 
-	// Check if it contains only digits
-	for _, char := range categoryID {
-		if char < '0' || char > '9' {
-			return false
+// NormalizeTags normalizes tags and drops blanks and duplicates, keeping the
+// order. A tag made only of dropped characters becomes "" and fails ValidateTags.
+func NormalizeTags(raw []string) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, t := range raw {
+		if strings.TrimSpace(t) == "" {
+			continue
 		}
+		n := NormalizeTag(t)
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
 	}
+	return out
+}
 
-	// Check it's not just "0" or starting with 0 (like "01", "02")
-	if categoryID == "0" || (len(categoryID) > 1 && categoryID[0] == '0') {
-		return false
+// ValidateTags checks normalized tags
+func ValidateTags(tags []string) (bool, string) {
+	if len(tags) > MaxTagsPerPost {
+		return false, fmt.Sprintf("Cannot add more than %d tags", MaxTagsPerPost)
 	}
-
-	return true
+	for _, t := range tags {
+		n := utf8.RuneCountInString(t)
+		if n < 2 || n > 30 {
+			return false, "Tags must be 2 to 30 letters or digits long"
+		}
+	}
+	return true, ""
 }
 
 // ValidateCommentData validates comment data for validity
@@ -223,6 +275,57 @@ func IsValidUsername(username string) bool {
 	return re.MatchString(username)
 }
 
+// profileGenders are the values offered by the registration form ("" = not set)
+var profileGenders = map[string]bool{"": true, "female": true, "male": true, "other": true, "prefer_not_to_say": true}
+
+// ValidateProfileData validates the editable profile fields.
+// Age 0 means "not set" (accounts created through single sign-on).
+func ValidateProfileData(firstName, lastName, gender, bio, avatarURL string, age int) (bool, string) {
+	for _, name := range []string{firstName, lastName} {
+		if utf8.RuneCountInString(name) > 50 {
+			return false, "Names cannot exceed 50 characters"
+		}
+		if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return false, "Names cannot contain control characters"
+		}
+	}
+
+	if age != 0 && (age < 13 || age > 120) {
+		return false, "Age must be between 13 and 120"
+	}
+
+	if !profileGenders[gender] {
+		return false, "Invalid gender"
+	}
+
+	if utf8.RuneCountInString(bio) > 500 {
+		return false, "Bio cannot exceed 500 characters"
+	}
+
+	if len(avatarURL) > 500 || !IsValidURL(avatarURL) || !IsValidImageURL(avatarURL) {
+		return false, "Avatar must be an http(s) link to an image"
+	}
+
+	return true, ""
+}
+
+// ValidatePrivacySettings checks visibility levels and the DM policy
+func ValidatePrivacySettings(p models.PrivacySettings) (bool, string) {
+	for _, v := range []string{p.NameVisibility, p.AgeVisibility, p.GenderVisibility} {
+		switch v {
+		case models.VisibilityPublic, models.VisibilityMembers, models.VisibilityNobody:
+		default:
+			return false, "Visibility must be public, members or nobody"
+		}
+	}
+
+	if p.DMPolicy != models.DMEveryone && p.DMPolicy != models.DMContacts {
+		return false, "DM policy must be everyone or contacts"
+	}
+
+	return true, ""
+}
+
 // FormatTimeAgo formats a time as "X ago" string
 func FormatTimeAgo(t interface{}) string {
 	var timeVal time.Time
@@ -243,109 +346,81 @@ func FormatTimeAgo(t interface{}) string {
 	return formatTime(timeVal)
 }
 
+// TogglePostReaction sets the user's reaction to a post: a new reaction is
+// added, a different one replaces theirs and the same one is taken back.
+// It returns the post's counts per reaction and the user's reaction after
+// the toggle ("" when taken back)
 func TogglePostReaction(
+	ctx context.Context,
 	db *sql.DB,
 	userID int,
 	postID int,
-	isLike bool,
-) (likes int, dislikes int, err error) {
-
-	var current sql.NullBool
-
-	err = db.QueryRow(`
-		SELECT is_like FROM post_likes
-		WHERE post_id = ? AND user_id = ?`,
-		postID, userID,
-	).Scan(&current)
-
-	if err == sql.ErrNoRows {
-		_, err = db.Exec(`
-			INSERT INTO post_likes (post_id, user_id, is_like)
-			VALUES (?, ?, ?)`,
-			postID, userID, isLike,
-		)
-	} else if err == nil {
-		if current.Valid && current.Bool == isLike {
-			_, err = db.Exec(`
-				DELETE FROM post_likes
-				WHERE post_id = ? AND user_id = ?`,
-				postID, userID,
-			)
-		} else {
-			_, err = db.Exec(`
-				UPDATE post_likes
-				SET is_like = ?
-				WHERE post_id = ? AND user_id = ?`,
-				isLike, postID, userID,
-			)
-		}
-	}
-
-	if err != nil {
-		return
-	}
-
-	db.QueryRow(`
-		SELECT
-			COALESCE(SUM(CASE WHEN is_like = 1 THEN 1 ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN is_like = 0 THEN 1 ELSE 0 END), 0)
-		FROM post_likes
-		WHERE post_id = ?`,
-		postID,
-	).Scan(&likes, &dislikes)
-
-	return
+	reaction string,
+) (map[string]int, string, error) {
+	return toggleReaction(ctx, db, "post_likes", "post_id", database.GetPostReactionCounts, userID, postID, reaction)
 }
 
+// ToggleCommentReaction is TogglePostReaction for comments
 func ToggleCommentReaction(
+	ctx context.Context,
 	db *sql.DB,
 	userID int,
 	commentID int,
-	isLike bool,
-) (likes int, dislikes int, err error) {
-
-	var current sql.NullBool
-
-	err = db.QueryRow(`
-		SELECT is_like FROM comment_likes
-		WHERE comment_id = ? AND user_id = ?`,
-		commentID, userID,
-	).Scan(&current)
-
-	if err == sql.ErrNoRows {
-		_, err = db.Exec(`
-			INSERT INTO comment_likes (comment_id, user_id, is_like)
-			VALUES (?, ?, ?)`,
-			commentID, userID, isLike,
+	reaction string,
+) (map[string]int, string, error) {
+	return toggleReaction(ctx, db, "comment_likes", "comment_id", database.GetCommentReactionCounts, userID, commentID, reaction)
+}
+
+// toggleReaction runs the toggle and reads the counters in one transaction,
+// so concurrent toggles (double clicks, several tabs) apply one after another
+// and every caller gets the counts right after its own change. The counters
+// themselves are updated by the triggers on the reaction tables.
+func toggleReaction(
+	ctx context.Context,
+	db *sql.DB,
+	table, column string,
+	counts func(context.Context, database.DBTX, int) (map[string]int, error),
+	userID, targetID int,
+	reaction string,
+) (map[string]int, string, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		mine   string
+		result map[string]int
+	)
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// the same reaction twice takes it back
+		res, err := tx.ExecContext(ctx,
+			"DELETE FROM "+table+" WHERE "+column+" = ? AND user_id = ? AND reaction = ?",
+			targetID, userID, reaction,
 		)
-	} else if err == nil {
-		if current.Valid && current.Bool == isLike {
-			_, err = db.Exec(`
-				DELETE FROM comment_likes
-				WHERE comment_id = ? AND user_id = ?`,
-				commentID, userID,
-			)
-		} else {
-			_, err = db.Exec(`
-				UPDATE comment_likes
-				SET is_like = ?
-				WHERE comment_id = ? AND user_id = ?`,
-				isLike, commentID, userID,
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// new reaction or a different one replacing the user's
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO "+table+" ("+column+", user_id, reaction) VALUES (?, ?, ?) "+
+					"ON CONFLICT ("+column+", user_id) DO UPDATE SET reaction = excluded.reaction",
+				targetID, userID, reaction,
 			)
+			if err != nil {
+				return err
+			}
+			mine = reaction
 		}
-	}
 
+		result, err = counts(ctx, tx, targetID)
+		return err
+	})
 	if err != nil {
-		return 0, 0, err
+		return nil, "", err
 	}
-
-	err = db.QueryRow(`
-		SELECT 
-            (SELECT COUNT(*) FROM comment_likes WHERE comment_id = ? AND is_like = 1),
-            (SELECT COUNT(*) FROM comment_likes WHERE comment_id = ? AND is_like = 0)
-    `, commentID, commentID).Scan(&likes, &dislikes)
-
-	// ЭТОТ ЛОГ ПОМОЖЕТ НАМ УВИДЕТЬ РЕЗУЛЬТАТ В ТЕРМИНАЛЕ
-	fmt.Printf("DEBUG: Comment %d updated. Now Likes: %d, Dislikes: %d\n", commentID, likes, dislikes)
-	return likes, dislikes, err
+	return result, mine, nil
 }
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateProfileData(t *testing.T) {
+	tests := []struct {
+		name                             string
+		first, last, gender, bio, avatar string
+		age                              int
+		valid                            bool
+	}{
+		{"empty profile", "", "", "", "", "", 0, true},
+		{"full profile", "Мария", "Иванова", "female", "Hello!", "https://example.com/a.webp", 25, true},
+		{"too young", "", "", "", "", "", 12, false},
+		{"too old", "", "", "", "", "", 121, false},
+		{"unknown gender", "", "", "robot", "", "", 0, false},
+		{"long name", strings.Repeat("я", 51), "", "", "", "", 0, false},
+		{"control char in name", "Al\nice", "", "", "", "", 0, false},
+		{"long bio", "", "", "", strings.Repeat("x", 501), "", 0, false},
+		{"avatar without scheme", "", "", "", "", "example.com/a.png", 0, false},
+		{"avatar not an image", "", "", "", "", "https://example.com/a", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, msg := ValidateProfileData(tt.first, tt.last, tt.gender, tt.bio, tt.avatar, tt.age)
+			if ok != tt.valid {
+				t.Errorf("ValidateProfileData() = %v (%s), want %v", ok, msg, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Job Search Tips":   "job-search-tips",
+		"  C++ & Go!  ":     "c-go",
+		"Remote -- Jobs":    "remote-jobs",
+		"Вакансии":          "",
+		"Web3 Internships ": "web3-internships",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateCategoryData(t *testing.T) {
+	tests := []struct {
+		name, catName, slug, description string
+		valid                            bool
+	}{
+		{"valid", "Job Search", "job-search", "", true},
+		{"empty name", "  ", "x", "", false},
+		{"long name", strings.Repeat("a", 51), "a", "", false},
+		{"empty slug", "Name", "", "", false},
+		{"uppercase slug", "Name", "Name", "", false},
+		{"double dash", "Name", "a--b", "", false},
+		{"long description", "Name", "name", strings.Repeat("x", 201), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, msg := ValidateCategoryData(tt.catName, tt.slug, tt.description); ok != tt.valid {
+				t.Errorf("ValidateCategoryData() = %v (%s), want %v", ok, msg, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"#Go", " go ", "Web Dev", "web_dev", "", "  ", "!!!", "Remote--Jobs"})
+	want := []string{"go", "web-dev", "", "remote-jobs"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("NormalizeTags() = %q, want %q", got, want)
+	}
+
+	if ok, _ := ValidateTags([]string{"go", "web-dev"}); !ok {
+		t.Error("valid tags rejected")
+	}
+	if ok, _ := ValidateTags(got); ok {
+		t.Error("empty tag accepted")
+	}
+	if ok, _ := ValidateTags([]string{strings.Repeat("a", 31)}); ok {
+		t.Error("long tag accepted")
+	}
+	if ok, _ := ValidateTags([]string{"a1", "a2", "a3", "a4", "a5", "a6"}); ok {
+		t.Errorf("more than %d tags accepted", MaxTagsPerPost)
+	}
+}
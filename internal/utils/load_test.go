@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"real-time-forum/internal/database"
+)
+
+// TestConcurrentWritesDoNotLock runs chat inserts and reaction toggles side
+// by side on a database opened like in production: every call must wait for
+// the write lock instead of failing with "database is locked".
+func TestConcurrentWritesDoNotLock(t *testing.T) {
+	ctx := context.Background()
+	pools, err := database.InitDB(filepath.Join(t.TempDir(), "forum.db"), database.Options{
+		BusyTimeout:   5 * time.Second,
+		MaxWriteConns: 4,
+		MaxReadConns:  8,
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer pools.Close()
+	db := pools.Write
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	var mode string
+	if err := pools.Read.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q (%v), want wal", mode, err)
+	}
+
+	const (
+		workers = 32
+		posts   = 10
+		perUser = 200
+	)
+	users := make([]int, 2*workers)
+	for i := range users {
+		res, err := db.Exec(
+			"INSERT INTO users (email, username, password_hash, age, gender, first_name, last_name) VALUES (?, ?, 'x', 30, 'other', '', '')",
+			fmt.Sprintf("load%d@example.com", i), fmt.Sprintf("load%d", i),
+		)
+		if err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		users[i] = int(id)
+	}
+	postIDs := make([]int, posts)
+	for i := range postIDs {
+		id, err := database.CreatePost(ctx, db, users[0], fmt.Sprintf("Post %d", i), "body")
+		if err != nil {
+			t.Fatalf("create post: %v", err)
+		}
+		postIDs[i] = id
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers*perUser)
+	for w := 0; w < workers; w++ {
+		from, to := users[w], users[workers+w]
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perUser; i++ {
+				if _, _, err := database.InsertMessage(ctx, db, from, to, fmt.Sprintf("message %d", i)); err != nil {
+					errs <- fmt.Errorf("InsertMessage: %w", err)
+				}
+			}
+		}()
+		// каждый пользователь сначала лайкает все посты, затем меняет лайк на дизлайк
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2*posts; i++ {
+				reaction := "like"
+				if i >= posts {
+					reaction = "dislike"
+				}
+				if _, _, err := TogglePostReaction(ctx, db, to, postIDs[i%posts], reaction); err != nil {
+					errs <- fmt.Errorf("TogglePostReaction: %w", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var messages, dislikes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messages); err != nil {
+		t.Fatalf("count messages: %v", err)
+	}
+	if messages != workers*perUser {
+		t.Errorf("messages = %d, want %d", messages, workers*perUser)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM post_likes WHERE reaction = 'dislike'").Scan(&dislikes); err != nil {
+		t.Fatalf("count reactions: %v", err)
+	}
+	if dislikes != workers*posts {
+		t.Errorf("dislikes = %d, want %d", dislikes, workers*posts)
+	}
+}
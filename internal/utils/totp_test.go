@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors (SHA-1), truncated to 6 digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, v := range vectors {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", v.unix, err)
+		}
+		if got != v.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", v.unix, got, v.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+	code, _ := TOTPCode(secret, step)
+
+	if got, ok := ValidateTOTP(secret, code, now, 0); !ok || got != step {
+		t.Fatalf("ValidateTOTP current = %d, %v", got, ok)
+	}
+
+	// previous step accepted for clock drift, older ones not
+	prev, _ := TOTPCode(secret, step-1)
+	if _, ok := ValidateTOTP(secret, prev, now, 0); !ok {
+		t.Errorf("expected previous step to be accepted")
+	}
+	old, _ := TOTPCode(secret, step-3)
+	if _, ok := ValidateTOTP(secret, old, now, 0); ok {
+		t.Errorf("expected old step to be rejected")
+	}
+
+	// replay of an already used step is rejected
+	if _, ok := ValidateTOTP(secret, code, now, step); ok {
+		t.Errorf("expected replayed code to be rejected")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Errorf("expected short code to be rejected")
+	}
+}
+
+func TestTOTPURIAndRecoveryCodes(t *testing.T) {
+	uri := TOTPURI("Forum", "alice@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Forum:alice@example.com?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes = %v, %v", codes, err)
+	}
+	if NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) != codes[0] {
+		t.Errorf("NormalizeRecoveryCode did not round-trip %s", codes[0])
+	}
+}
@@ -2,6 +2,37 @@ const jsonHeaders = {
   "Content-Type": "application/json",
 }
 
+// CSRF token выдаётся сервером через /api/me (заголовок X-CSRF-Token)
+let csrfToken = null
+
+async function ensureCsrfToken() {
+  if (!csrfToken) {
+    const res = await fetch("/api/me", { credentials: "include" })
+    csrfToken = res.headers.get("X-CSRF-Token")
+  }
+  return csrfToken
+}
+
+// mutate — fetch для не-GET запросов: добавляет CSRF заголовок
+// и один раз повторяет запрос, если токен устарел
+async function mutate(url, options = {}) {
+  const send = async () => fetch(url, {
+    ...options,
+    credentials: "include",
+    headers: { ...(options.headers || {}), "X-CSRF-Token": await ensureCsrfToken() },
+  })
+
+  let res = await send()
+  if (res.status === 403) {
+    const text = await res.clone().text()
+    if (text.includes("invalid csrf token")) {
+      csrfToken = null
+      res = await send()
+    }
+  }
+  return res
+}
+
 // function handleJSON(res) {
 //   if (!res.ok) {
 //     return res.text().then(text => {
@@ -78,7 +109,11 @@ window.handleApiError = function(error, context = 'action') {
       // Можно добавить перенаправление на логин
     }
   } else if (status === 403) {
-    window.showError('You don\'t have permission to perform this action')
+    if (message.includes('email not verified')) {
+      window.showUnverifiedEmail()
+    } else {
+      window.showError('You don\'t have permission to perform this action')
+    }
   } else if (status === 404) {
     window.showError('The requested resource was not found')
   } else if (status === 409) {
@@ -115,6 +150,7 @@ window.api = {
     const res = await fetch("/api/me", {
       credentials: "include",
     })
+    csrfToken = res.headers.get("X-CSRF-Token") || csrfToken
     if (!res.ok) {
       const errorText = await res.text()
       const error = new Error(errorText || "unauthorized")
@@ -125,7 +161,7 @@ window.api = {
   },
 
   async login(identifier, password) {
-    const res = await fetch("/api/login", {
+    const res = await mutate("/api/login", {
       method: "POST",
       headers: jsonHeaders,
       credentials: "include",
@@ -138,13 +174,39 @@ window.api = {
       throw error
     }
 
+    // { status: "ok" } или { status: "2fa_required", challenge }
+    const result = await res.json().catch(() => ({ status: "ok" }))
+    if (result.status === "ok" && window.websocket) {
+      window.websocket.init({ forceReconnect: true });
+    }
+    return result
+  },
+
+  async loginSecondFactor(challenge, code) {
+    // код из приложения (6 цифр) или одноразовый код восстановления
+    const body = /^\d{6}$/.test(code.trim())
+      ? { challenge, code: code.trim() }
+      : { challenge, recovery_code: code.trim() }
+
+    const res = await mutate("/api/login/2fa", {
+      method: "POST",
+      headers: jsonHeaders,
+      body: JSON.stringify(body),
+    })
+    if (!res.ok) {
+      const errorText = await res.text()
+      const error = new Error(errorText || "login failed")
+      error.status = res.status
+      throw error
+    }
+
     if (window.websocket) {
       window.websocket.init({ forceReconnect: true });
     }
   },
 
   async register(data) {
-    const res = await fetch("/api/register", {
+    const res = await mutate("/api/register", {
       method: "POST",
       headers: jsonHeaders,
       body: JSON.stringify(data),
@@ -171,7 +233,7 @@ window.api = {
       }
 
       // 2. Выполняем стандартный запрос на сервер для очистки сессии
-      const res = await fetch("/api/logout", {
+      const res = await mutate("/api/logout", {
         method: "POST",
         credentials: "include",
       });
@@ -179,9 +241,120 @@ window.api = {
       return res;
     },
 
+  // always 202, whether or not the address is registered
+  async forgotPassword(email) {
+    const res = await mutate("/api/password/forgot", {
+      method: "POST",
+      headers: jsonHeaders,
+      body: JSON.stringify({ email }),
+    })
+    if (!res.ok) {
+      const errorText = await res.text()
+      const error = new Error(errorText || "request failed")
+      error.status = res.status
+      throw error
+    }
+  },
+
+  async resetPassword(token, newPassword) {
+    const res = await mutate("/api/password/reset", {
+      method: "POST",
+      headers: jsonHeaders,
+      body: JSON.stringify({ token, new_password: newPassword }),
+    })
+    if (!res.ok) {
+      const errorText = await res.text()
+      const error = new Error(errorText || "password reset failed")
+      error.status = res.status
+      throw error
+    }
+  },
+
+  async resendVerification() {
+    const res = await mutate("/api/verify/resend", { method: "POST" })
+    if (!res.ok) {
+      const errorText = await res.text()
+      const error = new Error(errorText || "resend failed")
+      error.status = res.status
+      throw error
+    }
+  },
+
+  // ================= PROFILES =================
+
+  async getProfile(userId) {
+    const res = await fetch(`/api/users/${userId}/profile`, { credentials: "include" })
+    return handleJSON(res)
+  },
+
+  // PATCH /api/me — only the passed fields change
+  async updateProfile(fields) {
+    const res = await mutate("/api/me", {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(fields),
+    })
+    return handleJSON(res)
+  },
+
+  async getPrivacy() {
+    const res = await fetch("/api/me/privacy", { credentials: "include" })
+    return handleJSON(res)
+  },
+
+  async updatePrivacy(fields) {
+    const res = await mutate("/api/me/privacy", {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(fields),
+    })
+    return handleJSON(res)
+  },
+
+  // DELETE /api/me — schedules the deletion; signing in again before the purge cancels it
+  async deleteAccount({ password, confirm }) {
+    const res = await mutate("/api/me", {
+      method: "DELETE",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ password, confirm }),
+    })
+    return handleJSON(res)
+  },
+
+  // the export itself is a plain download of /api/me/export
+
+  // ================= BLOCKS =================
+
+  async getBlocks() {
+    const res = await fetch("/api/blocks", { credentials: "include" })
+    return handleJSON(res)
+  },
+
+  async blockUser(userId) {
+    const res = await mutate("/api/blocks", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ user_id: userId }),
+    })
+    if (!res.ok) throw new Error((await res.text()) || "Failed to block user")
+  },
+
+  async unblockUser(userId) {
+    const res = await mutate(`/api/blocks/${userId}`, { method: "DELETE" })
+    if (!res.ok) throw new Error((await res.text()) || "Failed to unblock user")
+  },
+
+  // Single sign-on: the login itself is a full-page redirect to /api/auth/oidc/login
+  async ssoStatus() {
+    const res = await fetch("/api/auth/oidc")
+    if (!res.ok) return { enabled: false }
+    return res.json()
+  },
+
   // ================= POSTS =================
 
-  async getPosts(filter = "all", categories = []) {
+  // tags: any of them; mode "and" (default) or "or" combines tags with categories
+  async getPosts(filter = "all", categories = [], tags = [], mode = "and") {
     let url = "/api/posts"
     const params = []
 
@@ -190,6 +363,10 @@ window.api = {
     if (categories.length) {
       params.push(`categories=${categories.join(",")}`)
     }
+    if (tags.length) {
+      params.push(`tags=${encodeURIComponent(tags.join(","))}`)
+      if (mode === "or") params.push("mode=or")
+    }
 
     if (params.length) {
       url += "?" + params.join("&")
@@ -208,12 +385,12 @@ window.api = {
   },
 
   // POST /api/posts/create
-  async createPost({ title, content, categories }) {
-    const res = await fetch("/api/posts/create", {
+  async createPost({ title, content, categories, tags = [] }) {
+    const res = await mutate("/api/posts/create", {
       method: "POST",
       headers: jsonHeaders,
       credentials: "include",
-      body: JSON.stringify({ title, content, categories }),
+      body: JSON.stringify({ title, content, categories, tags }),
     })
 
     return handleJSON(res)
@@ -235,7 +412,7 @@ window.api = {
 
   // POST /api/comments
   async createComment(postId, content) {
-    const res = await fetch("/api/comments", {
+    const res = await mutate("/api/comments", {
       method: "POST",
       headers: jsonHeaders,
       credentials: "include",
@@ -252,42 +429,29 @@ window.api = {
 
   // ================= REACTIONS =================
 
-  async likePost(postId) {
-    const res = await fetch("/api/posts/like", {
-      method: "POST",
-      headers: jsonHeaders,
-      credentials: "include",
-      body: JSON.stringify({ post_id: postId }),
-    })
-    return handleJSON(res)
-  },
-
-  async dislikePost(postId) {
-    const res = await fetch("/api/posts/dislike", {
-      method: "POST",
-      headers: jsonHeaders,
-      credentials: "include",
-      body: JSON.stringify({ post_id: postId }),
-    })
+  // GET /api/reactions — the configured set: [{ name, emoji }]
+  async getReactions() {
+    const res = await fetch("/api/reactions")
     return handleJSON(res)
   },
 
-  async likeComment(commentId) {
-    const res = await fetch("/api/comments/like", {
+  // Toggles a reaction; answers { likes, dislikes, reactions, my_reaction }
+  async reactToPost(postId, reaction) {
+    const res = await mutate("/api/posts/react", {
       method: "POST",
       headers: jsonHeaders,
       credentials: "include",
-      body: JSON.stringify({ comment_id: commentId }),
+      body: JSON.stringify({ post_id: postId, reaction }),
     })
     return handleJSON(res)
   },
 
-  async dislikeComment(commentId) {
-    const res = await fetch("/api/comments/dislike", {
+  async reactToComment(commentId, reaction) {
+    const res = await mutate("/api/comments/react", {
       method: "POST",
       headers: jsonHeaders,
       credentials: "include",
-      body: JSON.stringify({ comment_id: commentId }),
+      body: JSON.stringify({ comment_id: commentId, reaction }),
     })
     return handleJSON(res)
   },
@@ -299,6 +463,12 @@ window.api = {
     return handleJSON(res)
   },
 
+  // GET /api/tags?prefix= — most used tags first
+  async searchTags(prefix) {
+    const res = await fetch(`/api/tags?prefix=${encodeURIComponent(prefix)}`)
+    return handleJSON(res)
+  },
+
   // ================= CHAT =================
 
   async getChatUsers() {
@@ -39,7 +39,7 @@ window.renderHeader = function () {
             </div>
             <div class="nav-actions">
               <div class="user-info">
-                <span class="username">👤 ${escapeHtml(user.username)}</span>
+                <a href="/users/${user.id}" data-link class="username">👤 ${escapeHtml(user.username)}</a>
                 <button id="logoutBtn" class="btn btn-primary btn-sm">Logout</button>
               </div>
             </div>
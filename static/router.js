@@ -9,7 +9,10 @@ const routes = [
 
   { path: "/login", view: "renderLogin" },
   { path: "/register", view: "renderRegister" },
+  { path: "/forgot-password", view: "renderForgotPassword" },
+  { path: "/reset-password", view: "renderResetPassword" },
   { path: "/post/:id", view: "renderPost" },
+  { path: "/users/:id", view: "renderProfile" },
 ]
 
 function matchRoute(route, path) {
@@ -41,7 +44,7 @@ window.router = {
     const path = location.pathname;
     const { user } = window.state || {};
 
-    const publicRoutes = ["/login", "/register"];
+    const publicRoutes = ["/login", "/register", "/forgot-password", "/reset-password"];
     const isPublicRoute = publicRoutes.some(route => matchRoute(route, path) !== null);
 
     if (!user && !isPublicRoute) {
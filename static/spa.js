@@ -49,6 +49,9 @@ document.addEventListener("DOMContentLoaded", async () => {
     }
   }
 
+  // после перехода по ссылке из письма /api/verify возвращает на /?verified=1|0
+  window.showVerificationResult()
+
   router.resolve()
 
   if (window.state?.user && typeof window.initChatWidget === "function") {
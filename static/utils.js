@@ -6,3 +6,148 @@ window.escapeHtml = function (str = "") {
     .replaceAll('"', "&quot;")
     .replaceAll("'", "&#039;")
 }
+
+// flattenCategories turns the tree from /api/categories into a list in
+// display order; depth is the nesting level (0 = top level)
+window.flattenCategories = function (tree = [], depth = 0) {
+  return tree.flatMap(c => [
+    { ...c, depth },
+    ...flattenCategories(c.children || [], depth + 1),
+  ])
+}
+
+// parseTags splits "go, web dev" into ["go", "web dev"]; the server normalizes them
+window.parseTags = function (str = "") {
+  return str.split(",").map(t => t.trim()).filter(Boolean)
+}
+
+// bindTagAutocomplete fills the datalist of input with tags matching the last
+// comma-separated entry
+window.bindTagAutocomplete = function (input, datalist) {
+  let timer = null
+  input.addEventListener("input", () => {
+    clearTimeout(timer)
+    timer = setTimeout(async () => {
+      const parts = input.value.split(",")
+      const prefix = parts.pop().trim()
+      if (!prefix) return
+      try {
+        const head = parts.map(p => p.trim()).filter(Boolean)
+        const tags = await api.searchTags(prefix)
+        datalist.innerHTML = tags
+          .map(t => `<option value="${escapeHtml([...head, t.name].join(", "))}">${t.count}</option>`)
+          .join("")
+      } catch (err) {
+        console.error("Tag autocomplete failed", err)
+      }
+    }, 200)
+  })
+}
+
+// ================= EMAIL VERIFICATION =================
+
+// showUnverifiedEmail explains why the action was refused and offers to send
+// the confirmation link again (POST /api/verify/resend)
+window.showUnverifiedEmail = function (message = "Please confirm your e-mail address first. Check your inbox for the verification link.") {
+  const toast = window.notify.error(escapeHtml(message), 15000)
+  const button = document.createElement("button")
+  button.className = "notification-action"
+  button.textContent = "Resend verification e-mail"
+  button.onclick = async () => {
+    button.disabled = true
+    try {
+      await api.resendVerification()
+      window.notify.removeToast(toast)
+      window.showSuccess("Verification e-mail sent. Please check your inbox.")
+    } catch (err) {
+      if (err.status === 409) {
+        window.showInfo("Your e-mail address is already confirmed. Please reload the page.")
+      } else if (err.status === 429) {
+        window.showWarning("A verification e-mail was sent recently. Please wait a few minutes before asking again.")
+      } else {
+        window.handleApiError(err, "action")
+      }
+      button.disabled = false
+    }
+  }
+  toast.querySelector(".notification-content").appendChild(button)
+}
+
+// showVerificationResult reports /api/verify redirects (/?verified=1|0) and
+// drops the parameter from the address bar
+window.showVerificationResult = function () {
+  const params = new URLSearchParams(location.search)
+  const verified = params.get("verified")
+  if (verified === null) return
+
+  params.delete("verified")
+  const query = params.toString()
+  history.replaceState(null, "", location.pathname + (query ? `?${query}` : "") + location.hash)
+
+  if (verified === "1") {
+    window.showSuccess("Your e-mail address is confirmed. Welcome aboard!")
+  } else if (window.state?.user) {
+    window.showUnverifiedEmail("This verification link is invalid or has expired.")
+  } else {
+    window.showError("This verification link is invalid or has expired. Log in to get a new one.")
+  }
+}
+
+// ================= REACTIONS =================
+
+// loadReactionSet fetches the configured reactions once (GET /api/reactions);
+// if that fails the bar falls back to like/dislike
+window.loadReactionSet = async function () {
+  if (window.reactionSet) return window.reactionSet
+  try {
+    window.reactionSet = await api.getReactions()
+  } catch (err) {
+    console.error("Failed to load reactions", err)
+    return [{ name: "like", emoji: "👍" }, { name: "dislike", emoji: "👎" }]
+  }
+  return window.reactionSet
+}
+
+// renderReactionBar shows every reaction of the set with its count; the
+// viewer's own one is highlighted. Guests get plain labels instead of buttons
+window.renderReactionBar = function (reactions = {}, mine = "", interactive = false) {
+  const set = window.reactionSet || [{ name: "like", emoji: "👍" }, { name: "dislike", emoji: "👎" }]
+  const items = set.map(r => {
+    const count = (reactions || {})[r.name] || 0
+    const active = r.name === mine ? " active" : ""
+    const inner = `${escapeHtml(r.emoji)} <span class="reaction-count">${count}</span>`
+    return interactive
+      ? `<button class="reaction-btn btn btn-secondary${active}" data-reaction="${escapeHtml(r.name)}" title="${escapeHtml(r.name)}">${inner}</button>`
+      : `<span class="reaction-readonly${active}" data-reaction="${escapeHtml(r.name)}">${inner}</span>`
+  })
+  return `<div class="reaction-bar">${items.join("")}</div>`
+}
+
+// updateReactionBar sets the counts of a rendered bar; mine is left alone
+// when undefined (broadcasts carry it only in the reacting user's own copy)
+window.updateReactionBar = function (bar, reactions, mine) {
+  if (!bar || !reactions) return
+  bar.querySelectorAll("[data-reaction]").forEach(el => {
+    const count = el.querySelector(".reaction-count")
+    if (count) count.textContent = reactions[el.dataset.reaction] || 0
+    if (mine !== undefined) el.classList.toggle("active", el.dataset.reaction === mine)
+  })
+}
+
+// bindReactionBar calls toggle(name) on click; toggle returns the server's
+// answer ({ reactions, my_reaction })
+window.bindReactionBar = function (bar, toggle) {
+  if (!bar) return
+  bar.querySelectorAll(".reaction-btn").forEach(btn => {
+    btn.onclick = async e => {
+      e.preventDefault()
+      e.stopPropagation()
+      try {
+        const res = await toggle(btn.dataset.reaction)
+        updateReactionBar(bar, res.reactions, res.my_reaction)
+      } catch (err) {
+        window.handleApiError(err, "action")
+      }
+    }
+  })
+}
@@ -31,6 +31,19 @@ window.renderCreatePost = async function () {
                 ></textarea>
               </div>
 
+              <div class="form-group">
+                <label for="tags">Tags</label>
+                <input
+                  type="text"
+                  id="tags"
+                  name="tags"
+                  list="tagSuggestions"
+                  placeholder="go, remote-work (up to 5, comma separated)"
+                  autocomplete="off"
+                />
+                <datalist id="tagSuggestions"></datalist>
+              </div>
+
               <div class="categories">
                 <h4>Categories</h4>
                 <div id="categories" class="category-selection">Loading...</div>
@@ -50,10 +63,10 @@ window.renderCreatePost = async function () {
       const categories = await api.getCategories()
       const box = document.getElementById("categories")
 
-      box.innerHTML = categories
+      box.innerHTML = flattenCategories(categories)
         .map(
           c => `
-        <label class="category-item">
+        <label class="category-item" style="margin-left: ${c.depth * 16}px">
           <input type="checkbox" name="categories" value="${c.id}">
           ${escapeHtml(c.name)}
         </label>
@@ -88,6 +101,7 @@ window.renderCreatePost = async function () {
 
 function bindCreatePostForm() {
   const form = document.getElementById("createPostForm")
+  bindTagAutocomplete(document.getElementById("tags"), document.getElementById("tagSuggestions"))
 
   form.addEventListener("submit", async e => {
     e.preventDefault()
@@ -98,6 +112,7 @@ function bindCreatePostForm() {
     const categories = Array.from(
       document.querySelectorAll("#categories input:checked")
     ).map(cb => cb.value)
+    const tags = parseTags(document.getElementById("tags").value)
 
     // if (!title || !content || categories.length === 0) {
     //   window.showWarning("Fill all fields and select categories")
@@ -105,7 +120,7 @@ function bindCreatePostForm() {
     // }
 
     try {
-      await api.createPost({ title, content, categories })
+      await api.createPost({ title, content, categories, tags })
       window.showSuccess("Post created successfully!")
       router.navigate("/") // 👈 после создания возвращаемся к постам
     } catch (err) {
@@ -21,11 +21,29 @@ window.renderLogin = function () {
                 Login
               </button>
             </form>
+
+            <p class="auth-link">
+              <a href="/forgot-password" data-link>Forgot password?</a>
+            </p>
+
+            <a id="ssoLogin" href="/api/auth/oidc/login" class="btn button-full-width" style="display:none">
+              Log in with SSO
+            </a>
           </div>
         `
       }
     })
 
+    showSsoOption()
+
+    // SSO для аккаунта с 2FA возвращает на /login#2fa=<challenge>
+    const ssoChallenge = new URLSearchParams(location.hash.slice(1)).get("2fa")
+    if (ssoChallenge) {
+      history.replaceState(null, "", location.pathname + location.search)
+      renderSecondFactor(ssoChallenge)
+      return
+    }
+
     // Инициализируем валидацию для новой формы
     if (window.initFormValidation) {
       window.initFormValidation();
@@ -40,23 +58,14 @@ window.renderLogin = function () {
         const password = document.getElementById("password").value
 
         try {
-          await api.login(identifier, password)
-
-          const user = await api.me()
-          setState({ user })
-
-          // Инициализируем глобальный WebSocket после входа
-          if (window.websocket) {
-            console.log("Initializing WebSocket after login...")
-            window.websocket.init({ forceReconnect: true })
-            if (window.ensureChatMessageHandler) {
-              window.ensureChatMessageHandler()
-            }
-          } else {
-            console.warn("WebSocket module not loaded!")
+          const result = await api.login(identifier, password)
+
+          if (result?.status === "2fa_required") {
+            renderSecondFactor(result.challenge)
+            return
           }
 
-          router.navigate("/")
+          await completeLogin()
         } catch (err) {
           console.error("Login failed", err)
           // Используем специальный контекст 'login' для детальной обработки ошибок
@@ -69,3 +78,75 @@ window.renderLogin = function () {
     window.handleApiError(err, 'navigation')
   }
 }
+
+const ssoErrors = {
+  account_exists: "An account with this e-mail already exists. Log in with your password, then link SSO.",
+  email_required: "Your identity provider did not share an e-mail address.",
+  identity_in_use: "This SSO identity is already linked to another account.",
+  denied: "Single sign-on was cancelled.",
+  banned: "This account has been banned.",
+}
+
+// Кнопка SSO показывается, только если сервер настроен на OIDC
+async function showSsoOption() {
+  const error = new URLSearchParams(location.search).get("sso_error")
+  if (error) {
+    window.showError(ssoErrors[error] || "Single sign-on failed, please try again.")
+  }
+
+  const { enabled } = await api.ssoStatus()
+  const link = document.getElementById("ssoLogin")
+  if (enabled && link) link.style.display = ""
+}
+
+// Второй шаг входа: код из приложения-аутентификатора или код восстановления
+function renderSecondFactor(challenge) {
+  const container = document.querySelector(".form-container")
+  container.innerHTML = `
+    <h1>Two-factor authentication</h1>
+
+    <form id="twoFactorForm">
+      <div class="form-group">
+        <label>Code from your authenticator app or a recovery code</label>
+        <input id="twoFactorCode" name="code" autocomplete="one-time-code" required />
+      </div>
+
+      <button type="submit" class="btn btn-primary button-full-width">
+        Verify
+      </button>
+    </form>
+  `
+
+  document
+    .getElementById("twoFactorForm")
+    .addEventListener("submit", async (e) => {
+      e.preventDefault()
+
+      const code = document.getElementById("twoFactorCode").value
+      try {
+        await api.loginSecondFactor(challenge, code)
+        await completeLogin()
+      } catch (err) {
+        console.error("2FA failed", err)
+        window.handleApiError(err, 'login')
+      }
+    })
+}
+
+async function completeLogin() {
+  const user = await api.me()
+  setState({ user })
+
+  // Инициализируем глобальный WebSocket после входа
+  if (window.websocket) {
+    console.log("Initializing WebSocket after login...")
+    window.websocket.init({ forceReconnect: true })
+    if (window.ensureChatMessageHandler) {
+      window.ensureChatMessageHandler()
+    }
+  } else {
+    console.warn("WebSocket module not loaded!")
+  }
+
+  router.navigate("/")
+}
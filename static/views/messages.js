@@ -177,6 +177,22 @@ function handleSocketMessage(payload) {
   if (payload.type === "message") {
     handleIncomingMessage(payload);
   }
+
+  if (payload.type === "error" && payload.message === "email not verified") {
+    window.showUnverifiedEmail?.("Please confirm your e-mail address before sending messages.");
+  }
+
+  if (payload.type === "error" && payload.message === "recipient does not accept messages from you") {
+    window.showError?.("This user only accepts messages from people they have written to.");
+  }
+
+  if (payload.type === "error" && payload.message === "you have blocked this user") {
+    window.showError?.("You have blocked this user. Unblock them on their profile to send messages.");
+  }
+
+  if (payload.type === "rate_limited") {
+    window.showError?.("You are sending messages too fast. Please slow down.");
+  }
 }
 
 function handleNewUser(data) {
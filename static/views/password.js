@@ -0,0 +1,111 @@
+// views/password.js — восстановление пароля по ссылке из письма
+
+// /forgot-password: сервер всегда отвечает одинаково, чтобы не выдавать,
+// какие адреса зарегистрированы
+window.renderForgotPassword = function () {
+  setState({
+    ui: {
+      viewHtml: `
+        <div class="form-container">
+          <h1>Forgot password</h1>
+
+          <form id="forgotPasswordForm">
+            <div class="form-group">
+              <label>Email</label>
+              <input type="email" id="email" name="email" required />
+            </div>
+
+            <button type="submit" class="btn btn-primary button-full-width">
+              Send reset link
+            </button>
+          </form>
+
+          <p class="auth-link">
+            Remembered it?
+            <a href="/login" data-link><b><span style="color: blue;">Login</span></b></a>
+          </p>
+        </div>
+      `
+    }
+  })
+
+  document
+    .getElementById("forgotPasswordForm")
+    .addEventListener("submit", async e => {
+      e.preventDefault()
+
+      const email = document.getElementById("email").value.trim()
+      try {
+        await api.forgotPassword(email)
+        window.showSuccess("If this address is registered, a reset link is on its way. Check your e-mail.")
+        router.navigate("/login")
+      } catch (err) {
+        console.error("Forgot password failed", err)
+        window.handleApiError(err, "action")
+      }
+    })
+}
+
+// /reset-password?token=... — ссылка из письма
+window.renderResetPassword = function () {
+  const token = new URLSearchParams(location.search).get("token")
+  if (!token) {
+    window.renderError(400, "The reset link is incomplete")
+    return
+  }
+
+  setState({
+    ui: {
+      viewHtml: `
+        <div class="form-container">
+          <h1>Choose a new password</h1>
+
+          <form id="resetPasswordForm">
+            <div class="form-group">
+              <label>New password</label>
+              <input type="password" id="password" name="password" required minlength="8" autocomplete="new-password" />
+            </div>
+
+            <div class="form-group">
+              <label>Repeat new password</label>
+              <input type="password" id="passwordRepeat" required minlength="8" autocomplete="new-password" />
+            </div>
+
+            <button type="submit" class="btn btn-primary button-full-width">
+              Reset password
+            </button>
+          </form>
+        </div>
+      `
+    }
+  })
+
+  if (window.initFormValidation) {
+    window.initFormValidation()
+  }
+
+  document
+    .getElementById("resetPasswordForm")
+    .addEventListener("submit", async e => {
+      e.preventDefault()
+
+      const password = document.getElementById("password").value
+      if (password !== document.getElementById("passwordRepeat").value) {
+        window.showError("Passwords do not match")
+        return
+      }
+
+      try {
+        await api.resetPassword(token, password)
+        window.showSuccess("Your password has been changed. Please login.")
+        router.navigate("/login")
+      } catch (err) {
+        console.error("Password reset failed", err)
+        if (err.status === 400 && err.message.includes("token")) {
+          window.showError("This reset link is invalid or has expired. Please request a new one.")
+        } else {
+          window.showError(err.message || "Password reset failed. Please try again.")
+        }
+      }
+    })
+}
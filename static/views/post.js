@@ -28,6 +28,8 @@ window.renderPost = async function ({ id }) {
       throw err; // Перебрасываем ошибку выше
     }
     
+    await window.loadReactionSet()
+
     try {
       comments = await api.getComments(id)
     } catch (err) {
@@ -45,7 +47,7 @@ window.renderPost = async function ({ id }) {
             <div class="post-header">
               <h2>${escapeHtml(post.title)}</h2>
               <div class="post-info">
-                <span class="meta-item">👤 ${escapeHtml(post.username)}</span>
+                <a href="/users/${post.user_id}" data-link class="meta-item">👤 ${escapeHtml(post.username)}</a>
                 <span class="meta-item">🕒 ${new Date(post.created_at).toLocaleString()}</span>
               </div>
             </div>
@@ -56,20 +58,13 @@ window.renderPost = async function ({ id }) {
               ${(post.categories || [])
                 .map(c => `<span class="tag">${escapeHtml(c)}</span>`)
                 .join("")}
+              ${(post.tags || [])
+                .map(t => `<span class="tag tag-label">#${escapeHtml(t)}</span>`)
+                .join("")}
             </div>
 
             <div class="post-footer post-actions">
-              ${
-                user
-                  ? `
-                    <button id="postLikeBtn" class="btn btn-secondary">👍 <span class="post-like-count">${post.likes}</span></button>
-                    <button id="postDislikeBtn" class="btn btn-secondary">👎 <span class="post-dislike-count">${post.dislikes}</span></button>
-                  `
-                  : `
-                    <span class="post-like-readonly">👍 <span class="post-like-count">${post.likes}</span></span>
-                    <span class="post-dislike-readonly">👎 <span class="post-dislike-count">${post.dislikes}</span></span>
-                  `
-              }
+              ${renderReactionBar(post.reactions, post.my_reaction, !!user)}
               <span class="comment-count">💬 ${comments.length}</span>
             </div>
           </article>
@@ -120,8 +115,8 @@ window.renderPost = async function ({ id }) {
 
     if (user) {
       const rerender = () => window.renderPost({ id })
-      bindPostLikes(post.id, rerender)
-      bindCommentLikes(rerender)
+      bindPostReactions(post.id)
+      bindCommentReactions()
       bindCommentForm(post.id, rerender)
     }
 
@@ -148,101 +143,25 @@ function renderComment(comment, user) {
       <p>${escapeHtml(comment.content)}</p>
 
       <div class="comment-footer">
-        ${
-          user
-            ? `
-              <button class="comment-like btn btn-secondary">👍 <span class="comment-like-count">${comment.likes || 0}</span></button>
-              <button class="comment-dislike btn btn-secondary">👎 <span class="comment-dislike-count">${comment.dislikes || 0}</span></button>
-            `
-            : `
-              <span class="comment-like-readonly">👍 <span class="comment-like-count">${comment.likes || 0}</span></span>
-              <span class="comment-dislike-readonly">👎 <span class="comment-dislike-count">${comment.dislikes || 0}</span></span>
-            `
-        }
+        ${renderReactionBar(comment.reactions, comment.my_reaction, !!user)}
       </div>
     </div>
   `
 }
 
-// ================= POST LIKES =================
-
-function bindPostLikes(postId, rerender) {
-  const likeBtn = document.getElementById("postLikeBtn")
-  const dislikeBtn = document.getElementById("postDislikeBtn")
-
-  if (!likeBtn || !dislikeBtn) return
-
-// Общая функция для обновления цифр
-  const refreshMetrics = async () => {
-    try {
-      const updatedPost = await api.getPost(postId);
-      updatePostReactionCounters(updatedPost.likes, updatedPost.dislikes)
-    } catch (e) {
-      console.error("Failed to refresh metrics", e);
-    }
-  };
-
-  likeBtn.addEventListener("click", async () => {
-    try {
-      const res = await api.likePost(postId)
-      updatePostReactionCounters(res.likes, res.dislikes)
-    } catch (e) {
-      window.handleApiError(e, 'action')
-    }
-  })
+// ================= REACTIONS =================
 
-  dislikeBtn.addEventListener("click", async () => {
-    try {
-      const res = await api.dislikePost(postId)
-      updatePostReactionCounters(res.likes, res.dislikes)
-    } catch (e) {
-      window.handleApiError(e, 'action')
-    }
-  })
+function bindPostReactions(postId) {
+  const bar = document.querySelector(".post-detail .reaction-bar")
+  bindReactionBar(bar, reaction => api.reactToPost(postId, reaction))
 }
 
-// ================= COMMENT LIKES =================
-
-function bindCommentLikes(rerender) {
+function bindCommentReactions() {
   document.querySelectorAll(".comment").forEach(el => {
     const commentId = Number(el.dataset.id)
     if (!commentId) return
-
-    const likeBtn = el.querySelector(".comment-like")
-    const dislikeBtn = el.querySelector(".comment-dislike")
-
-    if (!likeBtn || !dislikeBtn) return;
-
-    const setCounts = (likes, dislikes) => {
-      const likeCount = likeBtn.querySelector(".comment-like-count")
-      const dislikeCount = dislikeBtn.querySelector(".comment-dislike-count")
-      if (likeCount) likeCount.textContent = likes || 0
-      if (dislikeCount) dislikeCount.textContent = dislikes || 0
-    }
-
-// Обработка ЛАЙКА
-    likeBtn.onclick = async (e) => {
-      e.preventDefault();
-        try {
-        // Получаем свежие цифры прямо из ответа бэкенда!
-        const data = await api.likeComment(commentId); 
-        setCounts(data.likes, data.dislikes)
-      } catch (err) {
-          window.handleApiError(err, 'action')
-        }
-      };
-// Обработка ДИСКЛАЙКА
-
-      dislikeBtn.onclick = async (e) => {
-        e.preventDefault();
-        try {
-        const data = await api.dislikeComment(commentId);
-        setCounts(data.likes, data.dislikes)
-      } catch (err) {
-          window.handleApiError(err, 'action')
-        }
-      };
-  });
+    bindReactionBar(el.querySelector(".reaction-bar"), reaction => api.reactToComment(commentId, reaction))
+  })
 }
 
 // ================= COMMENT FORM =================
@@ -288,13 +207,6 @@ function bindCommentForm(postId, rerender) {
 
 // ================= REALTIME UPDATES =================
 
-function updatePostReactionCounters(likes, dislikes) {
-  const likeCount = document.querySelector(".post-like-count")
-  const dislikeCount = document.querySelector(".post-dislike-count")
-  if (likeCount && typeof likes === "number") likeCount.textContent = likes
-  if (dislikeCount && typeof dislikes === "number") dislikeCount.textContent = dislikes
-}
-
 function updateCommentCountDisplay(count) {
   const commentsTotal = typeof count === "number" ? count : null
   const commentCountEl = document.querySelector(".comment-count")
@@ -312,10 +224,7 @@ function appendOrUpdateComment(comment, user) {
 
   const existing = commentsSection.querySelector(`.comment[data-id="${comment.id}"]`)
   if (existing) {
-    const likeCount = existing.querySelector(".comment-like-count")
-    const dislikeCount = existing.querySelector(".comment-dislike-count")
-    if (likeCount && typeof comment.likes === "number") likeCount.textContent = comment.likes
-    if (dislikeCount && typeof comment.dislikes === "number") dislikeCount.textContent = comment.dislikes
+    updateReactionBar(existing.querySelector(".reaction-bar"), comment.reactions)
     return
   }
 
@@ -328,8 +237,8 @@ function appendOrUpdateComment(comment, user) {
   if (newEl) {
     commentsSection.appendChild(newEl)
     if (user) {
-      // Подключаем обработчики лайков для новых комментариев
-      bindCommentLikes(() => {})
+      // Подключаем обработчики реакций для новых комментариев
+      bindCommentReactions()
     }
   }
 }
@@ -345,7 +254,8 @@ function attachPostRealtimeUpdates(postId, user) {
     if (!payload || !payload.type) return
 
     if (payload.type === "post_reaction" && Number(payload.post_id) === Number(postId)) {
-      updatePostReactionCounters(payload.likes, payload.dislikes)
+      // my_reaction приходит только в копии для своих вкладок
+      updateReactionBar(document.querySelector(".post-detail .reaction-bar"), payload.reactions, payload.my_reaction)
       return
     }
 
@@ -366,10 +276,7 @@ function attachPostRealtimeUpdates(postId, user) {
 
       const commentEl = document.querySelector(`.comment[data-id="${commentID}"]`)
       if (commentEl) {
-        const likeCount = commentEl.querySelector(".comment-like-count")
-        const dislikeCount = commentEl.querySelector(".comment-dislike-count")
-        if (likeCount && typeof payload.likes === "number") likeCount.textContent = payload.likes
-        if (dislikeCount && typeof payload.dislikes === "number") dislikeCount.textContent = payload.dislikes
+        updateReactionBar(commentEl.querySelector(".reaction-bar"), payload.reactions, payload.my_reaction)
       }
     }
   }
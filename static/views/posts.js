@@ -5,6 +5,8 @@ async function renderPostsPage({ title, filter }) {
   const { user } = window.state || {}
   let realtimeRefreshTimer = null
   let selectedCategories = []
+  let selectedTags = []
+  let tagMode = "and"
 
   if (window.websocket) {
     window.websocket.init()
@@ -16,6 +18,17 @@ async function renderPostsPage({ title, filter }) {
         <aside class="sidebar">
           <h3>Categories</h3>
           <div id="categories">Loading...</div>
+
+          <h3>Tags</h3>
+          <form id="tagFilter" class="tag-filter">
+            <input type="text" id="tagFilterInput" list="tagFilterSuggestions" placeholder="go, remote-work" autocomplete="off">
+            <datalist id="tagFilterSuggestions"></datalist>
+            <select id="tagMode" title="How tags combine with categories">
+              <option value="and">Tags and categories</option>
+              <option value="or">Tags or categories</option>
+            </select>
+            <button type="submit" class="btn btn-secondary">Filter</button>
+          </form>
         </aside>
 
         <section class="content posts-content">
@@ -32,10 +45,10 @@ async function renderPostsPage({ title, filter }) {
     try {
       const categories = await api.getCategories()
 
-      categoriesEl.innerHTML = categories
+      categoriesEl.innerHTML = flattenCategories(categories)
         .map(
           c => `
-        <label class="category-item">
+        <label class="category-item" style="margin-left: ${c.depth * 16}px">
           <input type="checkbox" value="${c.id}">
           ${escapeHtml(c.name)}
         </label>
@@ -63,6 +76,21 @@ async function renderPostsPage({ title, filter }) {
       categoriesEl.innerHTML = "<p class='error'>Failed to load categories</p>"
     }
 
+    // ================= TAGS =================
+
+    const tagInput = document.getElementById("tagFilterInput")
+    bindTagAutocomplete(tagInput, document.getElementById("tagFilterSuggestions"))
+
+    document.getElementById("tagFilter").addEventListener("submit", e => {
+      e.preventDefault()
+      selectedTags = parseTags(tagInput.value)
+      loadPosts()
+    })
+    document.getElementById("tagMode").addEventListener("change", e => {
+      tagMode = e.target.value
+      if (selectedTags.length) loadPosts()
+    })
+
     // ================= POSTS =================
 
     async function loadPosts() {
@@ -79,7 +107,8 @@ async function renderPostsPage({ title, filter }) {
 
       let posts
       try {
-        posts = await api.getPosts(filter, selectedCategories)
+        await window.loadReactionSet()
+        posts = await api.getPosts(filter, selectedCategories, selectedTags, tagMode)
       } catch (err) {
         console.error(err)
         // Для критических ошибок загрузки постов используем navigation context
@@ -115,18 +144,11 @@ async function renderPostsPage({ title, filter }) {
     }
 
     // локальное обновление карточек без полной перерисовки
-    const updateCardMetrics = (postId, likes, dislikes, commentCount) => {
+    const updateCardMetrics = (postId, reactions, commentCount, mine) => {
       const card = document.querySelector(`.post-card[data-id="${postId}"]`)
       if (!card) return false
-      const likeBtn = card.querySelector(".like-btn")
-      const dislikeBtn = card.querySelector(".dislike-btn")
-      const likeSpan = likeBtn || card.querySelector("span:nth-child(1)")
-      const dislikeSpan = dislikeBtn || card.querySelector("span:nth-child(2)")
-      if (likeBtn && typeof likes === "number") likeBtn.textContent = `👍 ${likes}`
-      if (dislikeBtn && typeof dislikes === "number") dislikeBtn.textContent = `👎 ${dislikes}`
-      if (!likeBtn && likeSpan && typeof likes === "number") likeSpan.textContent = `👍 ${likes}`
-      if (!dislikeBtn && dislikeSpan && typeof dislikes === "number") dislikeSpan.textContent = `👎 ${dislikes}`
-      const commentsEl = card.querySelector(".post-footer span:last-child")
+      updateReactionBar(card.querySelector(".reaction-bar"), reactions, mine)
+      const commentsEl = card.querySelector(".post-footer .comment-count")
       if (commentsEl && typeof commentCount === "number") commentsEl.textContent = `💬 ${commentCount}`
       return true
     }
@@ -167,19 +189,20 @@ async function renderPostsPage({ title, filter }) {
         if (!list) return
 
         if (payload.type === "post_reaction") {
-          updateCardMetrics(payload.post_id, payload.likes, payload.dislikes)
+          // my_reaction приходит только в копии для своих вкладок
+          updateCardMetrics(payload.post_id, payload.reactions, undefined, payload.my_reaction)
           return
         }
 
         if (payload.type === "comment_created") {
-          updateCardMetrics(payload.post_id, undefined, undefined, payload.comment_count)
+          updateCardMetrics(payload.post_id, undefined, payload.comment_count)
           return
         }
 
         if (payload.type === "post_created") {
           const post = payload.post
           // для фильтров кроме all/без категорий — оставляем прежнее поведение через reload
-          const isDefaultFeed = filter === "all" && selectedCategories.length === 0
+          const isDefaultFeed = filter === "all" && selectedCategories.length === 0 && selectedTags.length === 0
           if (isDefaultFeed && post) {
             insertNewPostCard(post)
             return
@@ -221,21 +244,14 @@ function renderPostCard(post, user) {
         ${(post.categories || [])
           .map(c => `<span class="tag">${escapeHtml(c)}</span>`)
           .join("")}
+        ${(post.tags || [])
+          .map(t => `<span class="tag tag-label">#${escapeHtml(t)}</span>`)
+          .join("")}
       </div>
 
       <div class="post-footer">
-        ${
-          user
-            ? `
-              <button class="like-btn">👍 ${post.likes}</button>
-              <button class="dislike-btn">👎 ${post.dislikes}</button>
-            `
-            : `
-              <span>👍 ${post.likes}</span>
-              <span>👎 ${post.dislikes}</span>
-            `
-        }
-        <span>💬 ${post.comment_count}</span>
+        ${renderReactionBar(post.reactions, post.my_reaction, !!user)}
+        <span class="comment-count">💬 ${post.comment_count}</span>
       </div>
     </article>
   `
@@ -249,52 +265,14 @@ function bindPostEvents(user) {
 
     // переход по карточке
     card.addEventListener("click", (e) => {
-      // Не переходим в пост, если нажали на кнопку лайка
+      // Не переходим в пост, если нажали на кнопку реакции
       if (e.target.closest('button')) return;
       router.navigate(`/post/${postId}`)
     })
 
     if (!user) return
 
-    const likeBtn = card.querySelector(".like-btn")
-    const dislikeBtn = card.querySelector(".dislike-btn")
-
-    // Вспомогательная функция для обновления цифр в конкретной карточке
-    const updateCardUI = async () => {
-        try {
-            // Получаем только этот пост с сервера (если API позволяет)
-            const updatedPost = await api.getPost(postId)
-            if (likeBtn) likeBtn.textContent = `👍 ${updatedPost.likes}`
-            if (dislikeBtn) dislikeBtn.textContent = `👎 ${updatedPost.dislikes}`
-        } catch (err) {
-            // Если точечно не вышло, обновляем список (но это вызовет прыжок)
-            // loadPosts() 
-        }
-    }
-
-    if (likeBtn) {
-      likeBtn.addEventListener("click", async e => {
-        e.stopPropagation()
-        try {
-          await api.likePost(postId)
-          await updateCardUI() // Обновляем только цифры в этой карточке
-        } catch (err) {
-          window.handleApiError(err, 'action')
-        }
-      })
-    }
-
-    if (dislikeBtn) {
-      dislikeBtn.addEventListener("click", async e => {
-        e.stopPropagation()
-        try {
-          await api.dislikePost(postId)
-          await updateCardUI() // Обновляем только цифры в этой карточке
-        } catch (err) {
-          window.handleApiError(err, 'action')
-        }
-      })
-    }
+    bindReactionBar(card.querySelector(".reaction-bar"), reaction => api.reactToPost(postId, reaction))
   })
 }
 
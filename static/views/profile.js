@@ -0,0 +1,270 @@
+// views/profile.js
+
+window.renderProfile = async function ({ id }) {
+  id = Number(id)
+  const app = document.getElementById("app")
+  const { user } = window.state || {}
+
+  if (!id || isNaN(id)) {
+    window.renderError(400, "Invalid user ID")
+    return
+  }
+
+  app.innerHTML = "<p>Loading profile...</p>"
+
+  let profile
+  try {
+    profile = await api.getProfile(id)
+  } catch (err) {
+    console.error("Error loading profile:", err)
+    window.handleApiError(err, 'navigation')
+    return
+  }
+
+  const isOwn = user && user.id === profile.id
+  const fullName = [profile.first_name, profile.last_name].filter(Boolean).join(" ")
+  const stats = profile.stats || {}
+
+  let isBlocked = false
+  if (user && !isOwn) {
+    try {
+      isBlocked = (await api.getBlocks()).some(b => b.id === profile.id)
+    } catch (err) {
+      console.error("Error loading blocks:", err)
+    }
+  }
+
+  app.innerHTML = `
+    <div class="page single-post">
+      <section class="content">
+        <article class="post-card profile-card">
+          <div class="post-header">
+            ${profile.avatar_url ? `<img class="profile-avatar" src="${escapeHtml(profile.avatar_url)}" alt="" width="96" height="96" referrerpolicy="no-referrer">` : ""}
+            <h2>${escapeHtml(profile.username)}</h2>
+            <div class="post-info">
+              ${fullName ? `<span class="meta-item">${escapeHtml(fullName)}</span>` : ""}
+              <span class="meta-item">🕒 Member since ${new Date(profile.created_at).toLocaleDateString()}</span>
+            </div>
+          </div>
+
+          ${profile.bio ? `<p class="post-body">${escapeHtml(profile.bio)}</p>` : ""}
+
+          <div class="post-tags">
+            <span class="tag">${stats.posts || 0} posts</span>
+            <span class="tag">${stats.comments || 0} comments</span>
+            <span class="tag">👍 ${stats.likes_given || 0}</span>
+            <span class="tag">👎 ${stats.dislikes_given || 0}</span>
+          </div>
+
+          ${user && !isOwn ? `<button id="blockBtn" class="btn btn-secondary">${isBlocked ? "Unblock" : "Block"}</button>` : ""}
+        </article>
+
+        <h3>Recent posts</h3>
+        ${(profile.recent_posts || []).length === 0 ? "<p>No posts yet.</p>" : profile.recent_posts.map(p => `
+          <div class="comment">
+            <a href="/post/${p.id}" data-link>${escapeHtml(p.title)}</a>
+            <span class="meta-item">🕒 ${new Date(p.created_at).toLocaleString()}</span>
+          </div>
+        `).join("")}
+
+        <h3>Recent comments</h3>
+        ${(profile.recent_comments || []).length === 0 ? "<p>No comments yet.</p>" : profile.recent_comments.map(c => `
+          <div class="comment">
+            <p>${escapeHtml(c.content)}</p>
+            <span class="meta-item">on <a href="/post/${c.post_id}" data-link>${escapeHtml(c.post_title)}</a></span>
+          </div>
+        `).join("")}
+
+        ${isOwn ? renderProfileForm(profile) + renderPrivacyForm(await api.getPrivacy()) + renderAccountSection() : ""}
+      </section>
+    </div>
+  `
+
+  const blockBtn = document.getElementById("blockBtn")
+  if (blockBtn) {
+    blockBtn.addEventListener("click", async () => {
+      try {
+        if (isBlocked) {
+          await api.unblockUser(profile.id)
+          window.showSuccess(`${profile.username} unblocked`)
+        } else {
+          await api.blockUser(profile.id)
+          window.showSuccess(`${profile.username} blocked`)
+        }
+        window.renderProfile({ id })
+      } catch (err) {
+        console.error("Block toggle failed", err)
+        window.showError(err.message)
+      }
+    })
+  }
+
+  if (isOwn) {
+    document.getElementById("profileForm").addEventListener("submit", async (e) => {
+      e.preventDefault()
+      const form = e.target
+      try {
+        await api.updateProfile({
+          first_name: form.first_name.value,
+          last_name: form.last_name.value,
+          age: Number(form.age.value) || 0,
+          gender: form.gender.value,
+          bio: form.bio.value,
+          avatar_url: form.avatar_url.value,
+        })
+        window.showSuccess("Profile updated")
+        window.renderProfile({ id })
+      } catch (err) {
+        console.error("Profile update failed", err)
+        window.showError(err.message || "Failed to update profile")
+      }
+    })
+
+    document.getElementById("deleteAccountForm").addEventListener("submit", async (e) => {
+      e.preventDefault()
+      const form = e.target
+      if (!confirm("Delete your account? Your posts and comments will stay, shown as [deleted].")) return
+      try {
+        const res = await api.deleteAccount({
+          password: form.password.value,
+          confirm: form.confirm_username.value,
+        })
+        if (window.cleanupMessages) window.cleanupMessages()
+        if (window.websocket) window.websocket.close()
+        setState({ user: null })
+        window.showSuccess(`Account scheduled for deletion on ${new Date(res.purge_at).toLocaleDateString()}. Log in before then to keep it.`)
+        router.navigate("/login")
+      } catch (err) {
+        console.error("Account deletion failed", err)
+        window.showError(err.message || "Failed to delete account")
+      }
+    })
+
+    document.getElementById("privacyForm").addEventListener("submit", async (e) => {
+      e.preventDefault()
+      const form = e.target
+      try {
+        await api.updatePrivacy({
+          name_visibility: form.name_visibility.value,
+          age_visibility: form.age_visibility.value,
+          gender_visibility: form.gender_visibility.value,
+          dm_policy: form.dm_policy.value,
+          hide_blocked_content: form.hide_blocked_content.checked,
+        })
+        window.showSuccess("Privacy settings saved")
+      } catch (err) {
+        console.error("Privacy update failed", err)
+        window.showError(err.message || "Failed to save privacy settings")
+      }
+    })
+  }
+}
+
+function renderPrivacyForm(privacy) {
+  const select = (name, options) => `
+    <select id="${name}" name="${name}">
+      ${options.map(([value, label]) => `<option value="${value}" ${privacy[name] === value ? "selected" : ""}>${label}</option>`).join("")}
+    </select>
+  `
+  const visibility = [["public", "Everyone"], ["members", "Logged-in members"], ["nobody", "Only me"]]
+
+  return `
+    <div class="form-container form-container-wide">
+      <h3>Privacy</h3>
+      <form id="privacyForm" class="post-form">
+        <div class="form-group">
+          <label for="name_visibility">Who can see my real name</label>
+          ${select("name_visibility", visibility)}
+        </div>
+        <div class="form-group">
+          <label for="age_visibility">Who can see my age</label>
+          ${select("age_visibility", visibility)}
+        </div>
+        <div class="form-group">
+          <label for="gender_visibility">Who can see my gender</label>
+          ${select("gender_visibility", visibility)}
+        </div>
+        <div class="form-group">
+          <label for="dm_policy">Who can message me</label>
+          ${select("dm_policy", [["everyone", "Everyone"], ["contacts", "Only people I've written to"]])}
+        </div>
+        <div class="form-group">
+          <label>
+            <input type="checkbox" name="hide_blocked_content" ${privacy.hide_blocked_content ? "checked" : ""}>
+            Hide posts and comments from users I blocked
+          </label>
+        </div>
+        <button type="submit" class="btn btn-primary">Save</button>
+      </form>
+    </div>
+  `
+}
+
+function renderProfileForm(profile) {
+  const genders = [
+    ["", "Not set"],
+    ["female", "Female"],
+    ["male", "Male"],
+    ["other", "Other"],
+    ["prefer_not_to_say", "Prefer not to say"],
+  ]
+
+  return `
+    <div class="form-container form-container-wide">
+      <h3>Edit profile</h3>
+      <form id="profileForm" class="post-form">
+        <div class="form-group">
+          <label for="first_name">First name</label>
+          <input id="first_name" name="first_name" maxlength="50" value="${escapeHtml(profile.first_name)}">
+        </div>
+        <div class="form-group">
+          <label for="last_name">Last name</label>
+          <input id="last_name" name="last_name" maxlength="50" value="${escapeHtml(profile.last_name)}">
+        </div>
+        <div class="form-group">
+          <label for="age">Age</label>
+          <input id="age" name="age" type="number" min="13" max="120" value="${profile.age || ""}">
+        </div>
+        <div class="form-group">
+          <label for="gender">Gender</label>
+          <select id="gender" name="gender">
+            ${genders.map(([value, label]) => `<option value="${value}" ${profile.gender === value ? "selected" : ""}>${label}</option>`).join("")}
+          </select>
+        </div>
+        <div class="form-group">
+          <label for="bio">Bio</label>
+          <textarea id="bio" name="bio" maxlength="500" rows="4">${escapeHtml(profile.bio)}</textarea>
+        </div>
+        <div class="form-group">
+          <label for="avatar_url">Avatar image URL</label>
+          <input id="avatar_url" name="avatar_url" type="url" placeholder="https://…/avatar.png" value="${escapeHtml(profile.avatar_url)}">
+        </div>
+        <button type="submit" class="btn btn-primary">Save</button>
+      </form>
+    </div>
+  `
+}
+
+function renderAccountSection() {
+  return `
+    <div class="form-container form-container-wide">
+      <h3>Your data</h3>
+      <p>Download everything the forum stores about you as a ZIP of JSON files.</p>
+      <a href="/api/me/export" class="btn btn-secondary" download>Download my data</a>
+
+      <h3>Delete account</h3>
+      <p>Your account is removed after a grace period; logging in before then cancels the deletion.</p>
+      <form id="deleteAccountForm" class="post-form">
+        <div class="form-group">
+          <label for="delete_password">Password</label>
+          <input type="password" id="delete_password" name="password" autocomplete="current-password">
+        </div>
+        <div class="form-group">
+          <label for="confirm_username">Signed in with single sign-on? Type your username instead</label>
+          <input type="text" id="confirm_username" name="confirm_username">
+        </div>
+        <button type="submit" class="btn btn-danger">Delete my account</button>
+      </form>
+    </div>
+  `
+}
@@ -97,7 +97,7 @@ window.renderRegister = function () {
 
     try {
       await api.register(formData)
-      window.showSuccess("Registration successful! Please login.");
+      window.showSuccess(`Registration successful! We sent a confirmation link to ${escapeHtml(formData.email)} — check your e-mail, then login.`);
       router.navigate("/login")
     } catch (err) {
       console.error("Registration failed", err)